@@ -18,11 +18,15 @@ package controller
 
 import (
 	"context"
+	"io"
+	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/publicsuffix"
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
@@ -72,6 +76,59 @@ var (
 			Help:      "Timestamp of last successful sync with the DNS provider",
 		},
 	)
+	plannedChangesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "planned_changes_total",
+			Help:      "Number of changes calculated by the plan, partitioned by action, record type and zone",
+		},
+		[]string{"action", "record_type", "zone"},
+	)
+	appliedChangesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "applied_changes_total",
+			Help:      "Number of changes successfully applied to the DNS provider, ownership records included, partitioned by action, record type and zone",
+		},
+		[]string{"action", "record_type", "zone"},
+	)
+	syncStageDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "sync_stage_duration_seconds",
+			Help:      "Duration of the stages of a sync, partitioned by stage",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 15),
+		},
+		[]string{"stage"},
+	)
+	registryOwnedRecords = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "registry",
+			Name:      "owned_records",
+			Help:      "Number of records in the registry owned by this instance",
+		},
+	)
+	syncRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "sync_retries_total",
+			Help:      "Number of retries scheduled after a failed sync, partitioned by error class",
+		},
+		[]string{"class"},
+	)
+	consecutiveSyncFailures = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "consecutive_sync_failures",
+			Help:      "Number of syncs failed since the last successful sync",
+		},
+	)
 	deprecatedRegistryErrors = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Subsystem: "registry",
@@ -94,6 +151,12 @@ func init() {
 	prometheus.MustRegister(sourceEndpointsTotal)
 	prometheus.MustRegister(registryEndpointsTotal)
 	prometheus.MustRegister(lastSyncTimestamp)
+	prometheus.MustRegister(plannedChangesTotal)
+	prometheus.MustRegister(appliedChangesTotal)
+	prometheus.MustRegister(syncStageDuration)
+	prometheus.MustRegister(registryOwnedRecords)
+	prometheus.MustRegister(syncRetriesTotal)
+	prometheus.MustRegister(consecutiveSyncFailures)
 	prometheus.MustRegister(deprecatedRegistryErrors)
 	prometheus.MustRegister(deprecatedSourceErrors)
 }
@@ -109,57 +172,211 @@ type Controller struct {
 	Registry registry.Registry
 	// The policy that defines which changes to DNS records are allowed
 	Policy plan.Policy
+	// The policy that refuses changes deleting too many records, nil disables it
+	DeletionGuard *plan.DeletionGuardPolicy
+	// The resolver that decides which resource acquires a DNS name requested by several resources
+	ConflictResolver plan.ConflictResolver
+	// The format in which the changes sent to the DNS provider are written to PlanOutput, empty disables the output
+	PlanOutputFormat string
+	// The writer which the changes sent to the DNS provider are written to
+	PlanOutput io.Writer
 	// The interval between individual synchronizations
 	Interval time.Duration
+	// The delay before retrying after a failed synchronization, doubled on every consecutive failure, zero disables retries
+	BackoffBase time.Duration
+	// The maximum delay before retrying after a failed synchronization, zero disables the exponential growth
+	BackoffMax time.Duration
 	// The DomainFilter defines which DNS records to keep or exclude
 	DomainFilter endpoint.DomainFilter
+	// The OwnerID of the records managed by this instance, empty considers every record owned
+	OwnerID string
+	// The SyncStatus records the outcome of every synchronization, nil disables it
+	SyncStatus *SyncStatus
+	// The EventEmitter records Kubernetes Events on the objects requesting changed records, nil disables it
+	EventEmitter *EventEmitter
 	// The nextRunAt used for throttling and batching reconciliation
 	nextRunAt time.Time
-	// The nextRunAtMux is for atomic updating of nextRunAt
+	// The backoffUntil prevents events from triggering a reconciliation while backing off from rate limiting
+	backoffUntil time.Time
+	// The failures counts the synchronizations failed since the last successful one
+	failures int
+	// The nextRunAtMux is for atomic updating of nextRunAt, backoffUntil and failures
 	nextRunAtMux sync.Mutex
 }
 
 // RunOnce runs a single iteration of a reconciliation loop.
 func (c *Controller) RunOnce(ctx context.Context) error {
+	stageStart := time.Now()
 	records, err := c.Registry.Records(ctx)
+	stageStart = observeStage(StageRegistryRecords, stageStart)
 	if err != nil {
 		registryErrorsTotal.Inc()
 		deprecatedRegistryErrors.Inc()
+		c.recordFailure(StageRegistryRecords, err)
 		return err
 	}
 	registryEndpointsTotal.Set(float64(len(records)))
+	registryOwnedRecords.Set(float64(c.countOwned(records)))
 
 	ctx = context.WithValue(ctx, provider.RecordsContextKey, records)
 
 	endpoints, err := c.Source.Endpoints(ctx)
+	stageStart = observeStage(StageSourceEndpoints, stageStart)
 	if err != nil {
 		sourceErrorsTotal.Inc()
 		deprecatedSourceErrors.Inc()
+		c.recordFailure(StageSourceEndpoints, err)
 		return err
 	}
 	sourceEndpointsTotal.Set(float64(len(endpoints)))
 
+	policies := []plan.Policy{c.Policy}
+	if c.DeletionGuard != nil {
+		guard := *c.DeletionGuard
+		guard.Current = records
+		policies = append(policies, &guard)
+	}
+
 	plan := &plan.Plan{
-		Policies:           []plan.Policy{c.Policy},
+		Policies:           policies,
 		Current:            records,
 		Desired:            endpoints,
 		DomainFilter:       c.DomainFilter,
 		PropertyComparator: c.Registry.PropertyValuesEqual,
+		ConflictResolver:   c.ConflictResolver,
+		OwnerID:            c.OwnerID,
 	}
 
 	plan = plan.Calculate()
+	stageStart = observeStage(StageCalculatePlan, stageStart)
+	c.countChanges(plannedChangesTotal, plan.Changes)
+	if c.EventEmitter != nil {
+		c.EventEmitter.Skipped(ctx, c.notOwned(plan.Changes.UpdateNew))
+	}
 
+	ctx, propagated := registry.WithPropagatedChanges(ctx)
 	err = c.Registry.ApplyChanges(ctx, plan.Changes)
+	observeStage(StageApplyChanges, stageStart)
+	if c.PlanOutputFormat != "" {
+		// the changes sent to the DNS provider are written, rather than the planned ones
+		if err := propagated.Export(c.PlanOutput, c.PlanOutputFormat); err != nil {
+			log.Errorf("Failed to write plan output: %v", err)
+		}
+	}
 	if err != nil {
 		registryErrorsTotal.Inc()
 		deprecatedRegistryErrors.Inc()
+		c.recordFailure(StageApplyChanges, err)
+		if c.EventEmitter != nil {
+			c.EventEmitter.Failed(ctx, c.ownedChanges(plan.Changes), err)
+		}
 		return err
 	}
 
+	c.countChanges(appliedChangesTotal, propagated)
+	if c.EventEmitter != nil {
+		c.EventEmitter.Applied(ctx, c.ownedChanges(plan.Changes))
+	}
 	lastSyncTimestamp.SetToCurrentTime()
+	if c.SyncStatus != nil {
+		c.SyncStatus.succeeded()
+	}
 	return nil
 }
 
+// observeStage records the duration of a stage started at start and returns the start of the next one.
+func observeStage(stage Stage, start time.Time) time.Time {
+	now := time.Now()
+	syncStageDuration.WithLabelValues(string(stage)).Observe(now.Sub(start).Seconds())
+	return now
+}
+
+// countChanges adds the changes to the given counter, partitioned by action, record type and zone.
+func (c *Controller) countChanges(counter *prometheus.CounterVec, changes *plan.Changes) {
+	for action, endpoints := range map[string][]*endpoint.Endpoint{
+		"create": changes.Create,
+		"update": changes.UpdateNew,
+		"delete": changes.Delete,
+	} {
+		for _, ep := range endpoints {
+			counter.WithLabelValues(action, ep.RecordType, c.zone(ep.DNSName)).Inc()
+		}
+	}
+}
+
+// zone returns the most specific domain filter matching the DNS name, or its registrable domain
+// if no domain filter is configured or matches. Controllers don't know about the zones of the
+// DNS provider, but the domain filters usually correspond to them.
+func (c *Controller) zone(dnsName string) string {
+	dnsName = strings.ToLower(strings.TrimSuffix(dnsName, "."))
+	zone := ""
+	for _, filter := range c.DomainFilter.Filters {
+		domain := strings.TrimPrefix(filter, ".")
+		if len(domain) > len(zone) && endpoint.NewDomainFilter([]string{filter}).Match(dnsName) {
+			zone = domain
+		}
+	}
+	if zone != "" {
+		return zone
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(strings.TrimPrefix(dnsName, "*.")); err == nil {
+		return domain
+	}
+	return dnsName
+}
+
+// ownedChanges returns the changes the registry applies, i.e. without the updates and deletes of records owned by other owners.
+func (c *Controller) ownedChanges(changes *plan.Changes) *plan.Changes {
+	if c.OwnerID == "" {
+		return changes
+	}
+	return &plan.Changes{
+		Create:    changes.Create,
+		UpdateOld: c.owned(changes.UpdateOld),
+		UpdateNew: c.owned(changes.UpdateNew),
+		Delete:    c.owned(changes.Delete),
+	}
+}
+
+// owned returns the records owned by this instance.
+func (c *Controller) owned(records []*endpoint.Endpoint) []*endpoint.Endpoint {
+	if c.OwnerID == "" {
+		return records
+	}
+	owned := []*endpoint.Endpoint{}
+	for _, record := range records {
+		if record.Labels[endpoint.OwnerLabelKey] == c.OwnerID {
+			owned = append(owned, record)
+		}
+	}
+	return owned
+}
+
+// notOwned returns the records not owned by this instance.
+func (c *Controller) notOwned(records []*endpoint.Endpoint) []*endpoint.Endpoint {
+	notOwned := []*endpoint.Endpoint{}
+	if c.OwnerID == "" {
+		return notOwned
+	}
+	for _, record := range records {
+		if record.Labels[endpoint.OwnerLabelKey] != c.OwnerID {
+			notOwned = append(notOwned, record)
+		}
+	}
+	return notOwned
+}
+
+// countOwned returns the number of records owned by this instance.
+func (c *Controller) countOwned(records []*endpoint.Endpoint) int {
+	return len(c.owned(records))
+}
+
+func (c *Controller) recordFailure(stage Stage, err error) {
+	if c.SyncStatus != nil {
+		c.SyncStatus.failed(stage, err)
+	}
+}
+
 // MinInterval is used as window for batching events
 const MinInterval = 5 * time.Second
 
@@ -168,6 +385,9 @@ func (c *Controller) ScheduleRunOnce(now time.Time) {
 	c.nextRunAtMux.Lock()
 	defer c.nextRunAtMux.Unlock()
 	c.nextRunAt = now.Add(MinInterval)
+	if c.nextRunAt.Before(c.backoffUntil) {
+		c.nextRunAt = c.backoffUntil
+	}
 }
 
 func (c *Controller) ShouldRunOnce(now time.Time) bool {
@@ -180,14 +400,94 @@ func (c *Controller) ShouldRunOnce(now time.Time) bool {
 	return true
 }
 
+// ScheduleRetry schedules the next synchronization after one failed with the given error.
+// Transient errors are retried with an exponential backoff starting at BackoffBase, but not later than
+// the regular interval. Errors caused by rate limiting back off exponentially starting at the interval,
+// and postpone reconciliations triggered by events too. Permanent errors are retried at the regular interval.
+// The delays are capped at BackoffMax and jittered to spread out the requests of several instances.
+func (c *Controller) ScheduleRetry(now time.Time, err error) {
+	c.nextRunAtMux.Lock()
+	defer c.nextRunAtMux.Unlock()
+
+	c.failures++
+	consecutiveSyncFailures.Set(float64(c.failures))
+
+	if c.BackoffBase <= 0 {
+		return
+	}
+
+	class := provider.ClassifyError(err)
+	switch class {
+	case provider.ErrorClassTransient:
+		delay := c.backoff(c.BackoffBase)
+		if delay >= c.Interval {
+			return
+		}
+		c.nextRunAt = now.Add(delay)
+	case provider.ErrorClassRateLimited:
+		delay := c.backoff(c.Interval)
+		if delay < c.Interval {
+			delay = c.Interval
+		}
+		if retryAfter := provider.RetryAfter(err); retryAfter > delay {
+			delay = retryAfter
+		}
+		c.nextRunAt = now.Add(delay)
+		c.backoffUntil = c.nextRunAt
+	default:
+		return
+	}
+	syncRetriesTotal.WithLabelValues(class.String()).Inc()
+	log.Infof("Sync failed with a %s error (%d consecutive failures), retrying at %s", class, c.failures, c.nextRunAt.Format(time.RFC3339))
+}
+
+// backoff returns the jittered delay after the current number of consecutive failures, doubling base for each of them.
+func (c *Controller) backoff(base time.Duration) time.Duration {
+	max := c.BackoffMax
+	if max <= 0 {
+		max = base
+	}
+	delay := base
+	for i := 1; i < c.failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	// jitter between half and the full delay
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// resetRetries clears the consecutive failures after a successful synchronization.
+func (c *Controller) resetRetries() {
+	c.nextRunAtMux.Lock()
+	defer c.nextRunAtMux.Unlock()
+	c.failures = 0
+	c.backoffUntil = time.Time{}
+	consecutiveSyncFailures.Set(0)
+}
+
 // Run runs RunOnce in a loop with a delay until context is canceled
 func (c *Controller) Run(ctx context.Context) {
+	if c.SyncStatus != nil {
+		c.SyncStatus.start()
+	}
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 	for {
 		if c.ShouldRunOnce(time.Now()) {
-			if err := c.RunOnce(ctx); err != nil {
+			if c.SyncStatus != nil {
+				c.SyncStatus.begin()
+			}
+			err := c.RunOnce(ctx)
+			if c.SyncStatus != nil {
+				c.SyncStatus.end()
+			}
+			if err != nil {
 				log.Error(err)
+				c.ScheduleRetry(time.Now(), err)
+			} else {
+				c.resetRetries()
 			}
 		}
 		select {
@@ -17,7 +17,9 @@ limitations under the License.
 package controller
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"reflect"
 	"testing"
@@ -27,8 +29,11 @@ import (
 	"sigs.k8s.io/external-dns/internal/testutils"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
 	"sigs.k8s.io/external-dns/registry"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -51,6 +56,10 @@ func (p *mockProvider) ApplyChanges(ctx context.Context, changes *plan.Changes)
 		return errors.New("number of created records is wrong")
 	}
 
+	if len(changes.Delete) != len(p.ExpectChanges.Delete) {
+		return errors.New("number of deleted records is wrong")
+	}
+
 	for i := range changes.Create {
 		if changes.Create[i].DNSName != p.ExpectChanges.Create[i].DNSName || !changes.Create[i].Targets.Same(p.ExpectChanges.Create[i].Targets) {
 			return errors.New("created record is wrong")
@@ -154,6 +163,310 @@ func TestRunOnce(t *testing.T) {
 	source.AssertExpectations(t)
 }
 
+// TestRunOnceDeletionGuard tests that RunOnce doesn't apply changes refused by the deletion guard.
+func TestRunOnceDeletionGuard(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		{
+			DNSName:    "create-record",
+			RecordType: endpoint.RecordTypeA,
+			Targets:    endpoint.Targets{"1.2.3.4"},
+		},
+	}, nil)
+
+	// Both existing records are to be deleted, which exceeds the guard's threshold.
+	provider := newMockProvider(
+		[]*endpoint.Endpoint{
+			{
+				DNSName:    "delete-record",
+				RecordType: endpoint.RecordTypeA,
+				Targets:    endpoint.Targets{"4.3.2.1"},
+			},
+			{
+				DNSName:    "another-delete-record",
+				RecordType: endpoint.RecordTypeA,
+				Targets:    endpoint.Targets{"8.8.8.8"},
+			},
+		},
+		&plan.Changes{},
+	)
+
+	r, err := registry.NewNoopRegistry(provider)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:        source,
+		Registry:      r,
+		Policy:        &plan.SyncPolicy{},
+		DeletionGuard: &plan.DeletionGuardPolicy{MaxDeletes: 1},
+	}
+
+	assert.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Nil(t, ctrl.DeletionGuard.Current, "should not modify the configured guard")
+
+	source.AssertExpectations(t)
+}
+
+// TestRunOnceWritesPlanOutput tests that RunOnce writes the calculated changes to the plan output.
+func TestRunOnceWritesPlanOutput(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		{
+			DNSName:    "create-record",
+			RecordType: endpoint.RecordTypeA,
+			Targets:    endpoint.Targets{"1.2.3.4"},
+		},
+	}, nil)
+
+	provider := newMockProvider(
+		[]*endpoint.Endpoint{},
+		&plan.Changes{
+			Create: []*endpoint.Endpoint{
+				{DNSName: "create-record", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+	)
+
+	r, err := registry.NewNoopRegistry(provider)
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	ctrl := &Controller{
+		Source:           source,
+		Registry:         r,
+		Policy:           &plan.SyncPolicy{},
+		PlanOutputFormat: plan.OutputFormatJSON,
+		PlanOutput:       &output,
+	}
+
+	assert.NoError(t, ctrl.RunOnce(context.Background()))
+
+	changes := &plan.Changes{}
+	require.NoError(t, json.Unmarshal(output.Bytes(), changes))
+	require.Len(t, changes.Create, 1)
+	assert.Equal(t, "create-record", changes.Create[0].DNSName)
+	assert.Empty(t, changes.Delete)
+}
+
+// TestRunOnceWritesPropagatedChanges tests that the plan output lists the changes sent to the DNS provider,
+// with the ownership records and without the records owned by other owners.
+func TestRunOnceWritesPropagatedChanges(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone("example.org"))
+
+	other, err := registry.NewTXTRegistry(p, registry.TXTRegistryConfig{OwnerID: "other-owner"})
+	require.NoError(t, err)
+	require.NoError(t, other.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("foreign.example.org", endpoint.RecordTypeA, "8.8.8.8")},
+	}))
+
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("create.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("foreign.example.org", endpoint.RecordTypeA, "8.8.4.4"),
+	}, nil)
+
+	r, err := registry.NewTXTRegistry(p, registry.TXTRegistryConfig{OwnerID: "owner"})
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	ctrl := &Controller{
+		Source:           source,
+		Registry:         r,
+		Policy:           &plan.SyncPolicy{},
+		OwnerID:          "owner",
+		PlanOutputFormat: plan.OutputFormatJSON,
+		PlanOutput:       &output,
+	}
+
+	assert.NoError(t, ctrl.RunOnce(ctx))
+
+	changes := &plan.Changes{}
+	require.NoError(t, json.Unmarshal(output.Bytes(), changes))
+	created := map[string]bool{}
+	for _, ep := range changes.Create {
+		if ep.RecordType == endpoint.RecordTypeA {
+			assert.Equal(t, "owner", ep.Labels[endpoint.OwnerLabelKey], "should list the record with its owner")
+		}
+		created[ep.RecordType] = true
+	}
+	assert.Equal(t, map[string]bool{endpoint.RecordTypeA: true, endpoint.RecordTypeTXT: true}, created)
+	assert.Empty(t, changes.UpdateNew, "should not list the update of the record owned by another owner")
+	assert.Empty(t, changes.Delete)
+
+	source.AssertExpectations(t)
+}
+
+// TestRunOnceRecordsSyncStatus tests that RunOnce records failing stages and successful synchronizations.
+func TestRunOnceRecordsSyncStatus(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return(nil, errors.New("source failure")).Once()
+	source.On("Endpoints").Return([]*endpoint.Endpoint{}, nil).Once()
+
+	r, err := registry.NewNoopRegistry(newMockProvider([]*endpoint.Endpoint{}, &plan.Changes{}))
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:     source,
+		Registry:   r,
+		Policy:     &plan.SyncPolicy{},
+		SyncStatus: NewSyncStatus(time.Minute),
+	}
+
+	assert.Error(t, ctrl.RunOnce(context.Background()))
+	report := ctrl.SyncStatus.Report()
+	assert.Nil(t, report.LastSyncTime)
+	assert.Equal(t, "source failure", report.Errors[StageSourceEndpoints].Error)
+	assert.Error(t, ctrl.SyncStatus.Ready())
+
+	assert.NoError(t, ctrl.RunOnce(context.Background()))
+	report = ctrl.SyncStatus.Report()
+	assert.NotNil(t, report.LastSyncTime)
+	assert.Contains(t, report.Errors, StageSourceEndpoints, "should keep the last error of the stage")
+	assert.NoError(t, ctrl.SyncStatus.Ready())
+
+	source.AssertExpectations(t)
+}
+
+// TestRunOnceCountsChanges tests that RunOnce counts the planned and applied changes by record type and zone.
+func TestRunOnceCountsChanges(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		{DNSName: "create-record.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+		{DNSName: "update-record.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"new.example.com"}},
+	}, nil)
+
+	provider := newMockProvider(
+		[]*endpoint.Endpoint{
+			{DNSName: "update-record.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"old.example.com"}},
+			{DNSName: "delete-record.sub.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"4.3.2.1"}},
+		},
+		&plan.Changes{
+			Create:    []*endpoint.Endpoint{{DNSName: "create-record.example.org", Targets: endpoint.Targets{"1.2.3.4"}}},
+			UpdateNew: []*endpoint.Endpoint{{DNSName: "update-record.example.org", Targets: endpoint.Targets{"new.example.com"}}},
+			UpdateOld: []*endpoint.Endpoint{{DNSName: "update-record.example.org", Targets: endpoint.Targets{"old.example.com"}}},
+			Delete:    []*endpoint.Endpoint{{DNSName: "delete-record.sub.example.org", Targets: endpoint.Targets{"4.3.2.1"}}},
+		},
+	)
+
+	r, err := registry.NewNoopRegistry(provider)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:       source,
+		Registry:     r,
+		Policy:       &plan.SyncPolicy{},
+		DomainFilter: endpoint.NewDomainFilter([]string{"example.org", "sub.example.org"}),
+	}
+
+	counters := []struct {
+		action, recordType, zone string
+	}{
+		{"create", endpoint.RecordTypeA, "example.org"},
+		{"update", endpoint.RecordTypeCNAME, "example.org"},
+		{"delete", endpoint.RecordTypeA, "sub.example.org"},
+	}
+	before := map[string]float64{}
+	for _, c := range counters {
+		before["planned"+c.action] = testutil.ToFloat64(plannedChangesTotal.WithLabelValues(c.action, c.recordType, c.zone))
+		before["applied"+c.action] = testutil.ToFloat64(appliedChangesTotal.WithLabelValues(c.action, c.recordType, c.zone))
+	}
+
+	assert.NoError(t, ctrl.RunOnce(context.Background()))
+
+	for _, c := range counters {
+		assert.Equal(t, before["planned"+c.action]+1, testutil.ToFloat64(plannedChangesTotal.WithLabelValues(c.action, c.recordType, c.zone)), c.action)
+		assert.Equal(t, before["applied"+c.action]+1, testutil.ToFloat64(appliedChangesTotal.WithLabelValues(c.action, c.recordType, c.zone)), c.action)
+	}
+	assert.Equal(t, float64(2), testutil.ToFloat64(registryOwnedRecords))
+
+	source.AssertExpectations(t)
+}
+
+// TestRunOnceCountsPropagatedChanges tests that RunOnce counts the changes sent to the DNS provider as applied,
+// with the ownership records and without the records owned by other owners.
+func TestRunOnceCountsPropagatedChanges(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	require.NoError(t, p.CreateZone("example.com"))
+
+	other, err := registry.NewTXTRegistry(p, registry.TXTRegistryConfig{OwnerID: "other-owner"})
+	require.NoError(t, err)
+	require.NoError(t, other.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("foreign.example.com", endpoint.RecordTypeAAAA, "2001:db8::1")},
+	}))
+
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("create.example.com", endpoint.RecordTypeAAAA, "2001:db8::2"),
+		endpoint.NewEndpoint("foreign.example.com", endpoint.RecordTypeAAAA, "2001:db8::3"),
+	}, nil)
+
+	r, err := registry.NewTXTRegistry(p, registry.TXTRegistryConfig{OwnerID: "owner"})
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:   source,
+		Registry: r,
+		Policy:   &plan.SyncPolicy{},
+		OwnerID:  "owner",
+	}
+
+	counters := []struct {
+		counter    *prometheus.CounterVec
+		action     string
+		recordType string
+		expected   float64
+	}{
+		{plannedChangesTotal, "update", endpoint.RecordTypeAAAA, 1},
+		{appliedChangesTotal, "update", endpoint.RecordTypeAAAA, 0},
+		{appliedChangesTotal, "create", endpoint.RecordTypeAAAA, 1},
+		{appliedChangesTotal, "create", endpoint.RecordTypeTXT, 1},
+	}
+	before := make([]float64, len(counters))
+	for i, c := range counters {
+		before[i] = testutil.ToFloat64(c.counter.WithLabelValues(c.action, c.recordType, "example.com"))
+	}
+
+	assert.NoError(t, ctrl.RunOnce(ctx))
+
+	for i, c := range counters {
+		assert.Equal(t, before[i]+c.expected, testutil.ToFloat64(c.counter.WithLabelValues(c.action, c.recordType, "example.com")), "%s %s", c.action, c.recordType)
+	}
+
+	source.AssertExpectations(t)
+}
+
+func TestControllerZone(t *testing.T) {
+	ctrl := &Controller{DomainFilter: endpoint.NewDomainFilter([]string{"example.org", ".sub.example.org", "Example.COM."})}
+
+	for dnsName, expected := range map[string]string{
+		"example.org":             "example.org",
+		"foo.example.org.":        "example.org",
+		"foo.sub.example.org":     "sub.example.org",
+		"FOO.example.com":         "example.com",
+		"foo.bar.example.co.uk":   "example.co.uk",
+		"*.foo.example.net":       "example.net",
+		"localhost":               "localhost",
+		"foo.notexample.org":      "notexample.org",
+		"foo.bar.sub.example.org": "sub.example.org",
+	} {
+		assert.Equal(t, expected, ctrl.zone(dnsName), dnsName)
+	}
+}
+
+func TestControllerCountOwned(t *testing.T) {
+	records := []*endpoint.Endpoint{
+		{DNSName: "owned", Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}},
+		{DNSName: "foreign", Labels: endpoint.Labels{endpoint.OwnerLabelKey: "other-owner"}},
+		{DNSName: "unowned", Labels: endpoint.Labels{}},
+	}
+
+	assert.Equal(t, 1, (&Controller{OwnerID: "owner"}).countOwned(records))
+	assert.Equal(t, 3, (&Controller{}).countOwned(records))
+}
+
 func TestShouldRunOnce(t *testing.T) {
 	ctrl := &Controller{Interval: 10 * time.Minute}
 
@@ -192,3 +505,136 @@ func TestShouldRunOnce(t *testing.T) {
 	// But not two times
 	assert.False(t, ctrl.ShouldRunOnce(now))
 }
+
+func TestScheduleRetryTransient(t *testing.T) {
+	ctrl := &Controller{Interval: 10 * time.Minute, BackoffBase: 10 * time.Second, BackoffMax: 5 * time.Minute}
+
+	now := time.Now()
+	for _, maxDelay := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second, 160 * time.Second, 5 * time.Minute, 5 * time.Minute} {
+		require.True(t, ctrl.ShouldRunOnce(now))
+		ctrl.ScheduleRetry(now, errors.New("connection refused"))
+
+		assert.False(t, ctrl.ShouldRunOnce(now.Add(maxDelay/2-time.Millisecond)), "should wait at least half of %s", maxDelay)
+		now = now.Add(maxDelay)
+	}
+	assert.Equal(t, 7, ctrl.failures)
+
+	// a successful sync resets the backoff
+	require.True(t, ctrl.ShouldRunOnce(now))
+	ctrl.resetRetries()
+	ctrl.ScheduleRetry(now, errors.New("connection refused"))
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(10*time.Second)))
+}
+
+func TestScheduleRetryTransientNotLaterThanInterval(t *testing.T) {
+	ctrl := &Controller{Interval: time.Minute, BackoffBase: 10 * time.Second, BackoffMax: time.Hour}
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		require.True(t, ctrl.ShouldRunOnce(now))
+		ctrl.ScheduleRetry(now, errors.New("connection refused"))
+		now = now.Add(time.Minute)
+	}
+}
+
+func TestScheduleRetryPermanent(t *testing.T) {
+	ctrl := &Controller{Interval: time.Minute, BackoffBase: 10 * time.Second, BackoffMax: time.Hour}
+
+	now := time.Now()
+	require.True(t, ctrl.ShouldRunOnce(now))
+	ctrl.ScheduleRetry(now, provider.NewPermanentError(errors.New("access denied")))
+
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(time.Minute-time.Second)), "should not retry before the interval")
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(time.Minute)))
+}
+
+func TestScheduleRetryRateLimited(t *testing.T) {
+	ctrl := &Controller{Interval: time.Minute, BackoffBase: 10 * time.Second, BackoffMax: 10 * time.Minute}
+
+	now := time.Now()
+	require.True(t, ctrl.ShouldRunOnce(now))
+	ctrl.ScheduleRetry(now, provider.NewRateLimitedError(errors.New("throttled"), 0))
+	require.True(t, ctrl.ShouldRunOnce(now.Add(time.Minute)), "should retry after the interval")
+
+	now = now.Add(time.Minute)
+	ctrl.ScheduleRetry(now, provider.NewRateLimitedError(errors.New("throttled"), 0))
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(time.Minute-time.Second)), "should back off beyond the interval")
+
+	// events don't trigger a reconciliation before the backoff elapsed
+	ctrl.ScheduleRunOnce(now)
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(MinInterval)))
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(2*time.Minute)))
+
+	// the delay requested by the provider takes precedence
+	now = now.Add(2 * time.Minute)
+	ctrl.ScheduleRetry(now, provider.NewRateLimitedError(errors.New("throttled"), time.Hour))
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(time.Hour-time.Second)))
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(time.Hour)))
+}
+
+func TestScheduleRetryDisabled(t *testing.T) {
+	ctrl := &Controller{Interval: time.Minute}
+
+	now := time.Now()
+	require.True(t, ctrl.ShouldRunOnce(now))
+	ctrl.ScheduleRetry(now, provider.NewRateLimitedError(errors.New("throttled"), time.Hour))
+
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(time.Minute-time.Second)))
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(time.Minute)))
+}
+
+func TestScheduleRetryCountsScheduledRetries(t *testing.T) {
+	retries := func() float64 {
+		total := 0.0
+		for _, class := range []provider.ErrorClass{provider.ErrorClassTransient, provider.ErrorClassRateLimited, provider.ErrorClassPermanent} {
+			total += testutil.ToFloat64(syncRetriesTotal.WithLabelValues(class.String()))
+		}
+		return total
+	}
+
+	for _, tc := range []struct {
+		title    string
+		ctrl     *Controller
+		err      error
+		expected float64
+	}{
+		{
+			title:    "transient error",
+			ctrl:     &Controller{Interval: time.Minute, BackoffBase: 10 * time.Second, BackoffMax: time.Hour},
+			err:      errors.New("connection refused"),
+			expected: 1,
+		},
+		{
+			title:    "transient error retried at the interval",
+			ctrl:     &Controller{Interval: time.Minute, BackoffBase: time.Hour, BackoffMax: time.Hour},
+			err:      errors.New("connection refused"),
+			expected: 0,
+		},
+		{
+			title:    "rate limited error",
+			ctrl:     &Controller{Interval: time.Minute, BackoffBase: 10 * time.Second, BackoffMax: time.Hour},
+			err:      provider.NewRateLimitedError(errors.New("throttled"), 0),
+			expected: 1,
+		},
+		{
+			title:    "permanent error",
+			ctrl:     &Controller{Interval: time.Minute, BackoffBase: 10 * time.Second, BackoffMax: time.Hour},
+			err:      provider.NewPermanentError(errors.New("access denied")),
+			expected: 0,
+		},
+		{
+			title:    "retries disabled",
+			ctrl:     &Controller{Interval: time.Minute},
+			err:      errors.New("connection refused"),
+			expected: 0,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			before := retries()
+			now := time.Now()
+			require.True(t, tc.ctrl.ShouldRunOnce(now))
+			tc.ctrl.ScheduleRetry(now, tc.err)
+			assert.Equal(t, before+tc.expected, retries())
+		})
+	}
+}
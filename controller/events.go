@@ -0,0 +1,206 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Reasons of the Events recorded on the source objects
+const (
+	EventReasonRecordCreated      = "RecordCreated"
+	EventReasonRecordUpdated      = "RecordUpdated"
+	EventReasonRecordDeleted      = "RecordDeleted"
+	EventReasonRecordSkipped      = "RecordSkipped"
+	EventReasonRecordChangeFailed = "RecordChangeFailed"
+)
+
+// resourceKind is the kind of object referred to by the prefix of an endpoint's resource label.
+type resourceKind struct {
+	gvr  schema.GroupVersionResource
+	kind string
+}
+
+// resourceKinds maps the prefixes of the resource labels set by the sources to the kinds of their objects.
+// The versions are the ones used when the version served by the cluster can't be discovered.
+var resourceKinds = map[string]resourceKind{
+	"service":        {schema.GroupVersionResource{Version: "v1", Resource: "services"}, "Service"},
+	"ingress":        {schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}, "Ingress"},
+	"crd":            {schema.GroupVersionResource{Group: "externaldns.k8s.io", Version: "v1alpha1", Resource: "dnsendpoints"}, "DNSEndpoint"},
+	"gateway":        {schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1alpha3", Resource: "gateways"}, "Gateway"},
+	"virtualservice": {schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1alpha3", Resource: "virtualservices"}, "VirtualService"},
+	"ingressroute":   {schema.GroupVersionResource{Group: "contour.heptio.com", Version: "v1beta1", Resource: "ingressroutes"}, "IngressRoute"},
+	"route":          {schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}, "Route"},
+	"routegroup":     {schema.GroupVersionResource{Group: "zalando.org", Version: "v1", Resource: "routegroups"}, "RouteGroup"},
+	"httproute":      {schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Resource: "httproutes"}, "HTTPRoute"},
+	"tlsroute":       {schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Resource: "tlsroutes"}, "TLSRoute"},
+	"tcproute":       {schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Resource: "tcproutes"}, "TCPRoute"},
+}
+
+// EventEmitter records Kubernetes Events on the objects which requested changed DNS records,
+// as identified by the endpoints' resource labels.
+type EventEmitter struct {
+	recorder      record.EventRecorder
+	dynamicClient dynamic.Interface
+	mapper        meta.RESTMapper
+	// skipped holds the records reported by the last call of Skipped
+	skipped map[string]bool
+}
+
+// NewEventEmitter returns an EventEmitter recording Events through the given client.
+// The dynamic client looks up the objects, so that the Events are shown by `kubectl describe`, in the
+// versions served by the cluster as discovered through the client.
+func NewEventEmitter(client kubernetes.Interface, dynamicClient dynamic.Interface) *EventEmitter {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+
+	return &EventEmitter{
+		recorder:      broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "external-dns"}),
+		dynamicClient: dynamicClient,
+		mapper:        restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(client.Discovery())),
+	}
+}
+
+// Applied records an Event for every applied change.
+func (e *EventEmitter) Applied(ctx context.Context, changes *plan.Changes) {
+	for _, ep := range changes.Create {
+		e.emit(ctx, ep, corev1.EventTypeNormal, EventReasonRecordCreated, "Created %s", describeRecord(ep))
+	}
+	for _, ep := range changes.UpdateNew {
+		e.emit(ctx, ep, corev1.EventTypeNormal, EventReasonRecordUpdated, "Updated %s", describeRecord(ep))
+	}
+	for _, ep := range changes.Delete {
+		e.emit(ctx, ep, corev1.EventTypeNormal, EventReasonRecordDeleted, "Deleted %s", describeRecord(ep))
+	}
+}
+
+// Failed records an Event for every change which failed to be applied.
+func (e *EventEmitter) Failed(ctx context.Context, changes *plan.Changes, err error) {
+	for action, endpoints := range map[string][]*endpoint.Endpoint{
+		"create": changes.Create,
+		"update": changes.UpdateNew,
+		"delete": changes.Delete,
+	} {
+		for _, ep := range endpoints {
+			e.emit(ctx, ep, corev1.EventTypeWarning, EventReasonRecordChangeFailed, "Failed to %s %s: %v", action, describeRecord(ep), err)
+		}
+	}
+}
+
+// Skipped records an Event for every record which isn't changed, because it's owned by another owner.
+// As the same records are skipped on every synchronization, a record is only reported again once it
+// stopped being skipped in between, or its desired targets or owner changed.
+func (e *EventEmitter) Skipped(ctx context.Context, endpoints []*endpoint.Endpoint) {
+	reported := make(map[string]bool, len(endpoints))
+	for _, ep := range endpoints {
+		owner := ep.Labels[endpoint.OwnerLabelKey]
+		key := ep.Labels[endpoint.ResourceLabelKey] + "::" + describeRecord(ep) + "::" + owner
+		reported[key] = true
+		if e.skipped[key] {
+			continue
+		}
+		if owner == "" {
+			e.emit(ctx, ep, corev1.EventTypeWarning, EventReasonRecordSkipped, "Record skipped: %s isn't owned by ExternalDNS", describeRecord(ep))
+			continue
+		}
+		e.emit(ctx, ep, corev1.EventTypeWarning, EventReasonRecordSkipped, "Record skipped: %s is owned by another owner (%s)", describeRecord(ep), owner)
+	}
+	e.skipped = reported
+}
+
+func (e *EventEmitter) emit(ctx context.Context, ep *endpoint.Endpoint, eventType, reason, messageFmt string, args ...interface{}) {
+	ref := e.reference(ctx, ep.Labels[endpoint.ResourceLabelKey])
+	if ref == nil {
+		return
+	}
+	e.recorder.Eventf(ref, eventType, reason, messageFmt, args...)
+}
+
+// reference returns a reference to the object identified by a resource label, e.g. ingress/default/foo,
+// or nil if the label is unknown or the object doesn't exist anymore.
+func (e *EventEmitter) reference(ctx context.Context, resource string) *corev1.ObjectReference {
+	parts := strings.SplitN(resource, "/", 3)
+	if len(parts) != 3 {
+		return nil
+	}
+	kind, ok := resourceKinds[parts[0]]
+	if !ok {
+		return nil
+	}
+
+	gvr := e.resource(kind)
+	ref := &corev1.ObjectReference{
+		Kind:       kind.kind,
+		APIVersion: gvr.GroupVersion().String(),
+		Namespace:  parts[1],
+		Name:       parts[2],
+	}
+	if e.dynamicClient == nil {
+		return ref
+	}
+
+	obj, err := e.dynamicClient.Resource(gvr).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		// still record the Event, it's only missing from `kubectl describe`
+		log.Debugf("Failed to look up %s: %v", resource, err)
+		return ref
+	}
+	ref.UID = obj.GetUID()
+	ref.ResourceVersion = obj.GetResourceVersion()
+	return ref
+}
+
+// describeRecord returns a human readable description of a record, e.g. "A record foo.example.com -> 1.2.3.4".
+func describeRecord(ep *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s record %s -> %s", ep.RecordType, ep.DNSName, strings.Join(ep.Targets, ", "))
+}
+
+// resource returns the resource of the kind in the preferred version of the cluster, e.g. networking.k8s.io/v1beta1
+// ingresses on clusters not serving networking.k8s.io/v1 yet, or in its default version if it can't be discovered.
+func (e *EventEmitter) resource(kind resourceKind) schema.GroupVersionResource {
+	if e.mapper == nil {
+		return kind.gvr
+	}
+	mapping, err := e.mapper.RESTMapping(schema.GroupKind{Group: kind.gvr.Group, Kind: kind.kind})
+	if err != nil {
+		log.Debugf("Failed to discover the version of %s: %v", kind.kind, err)
+		return kind.gvr
+	}
+	return mapping.Resource
+}
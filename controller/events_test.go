@@ -0,0 +1,273 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func newTestIngress(apiVersion, namespace, name string, uid types.UID) *unstructured.Unstructured {
+	ingress := &unstructured.Unstructured{}
+	ingress.SetAPIVersion(apiVersion)
+	ingress.SetKind("Ingress")
+	ingress.SetNamespace(namespace)
+	ingress.SetName(name)
+	ingress.SetUID(uid)
+	return ingress
+}
+
+// newTestEventClient returns a fake clientset storing the Events in their namespace like the API server.
+// The fake clientset of client-go v0.19 creates the Events recorded through a sink without namespace
+// at the cluster scope, where the tracker rejects them.
+func newTestEventClient() *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		event := action.(k8stesting.CreateAction).GetObject().(*corev1.Event)
+		return true, event, client.Tracker().Create(corev1.SchemeGroupVersion.WithResource("events"), event, event.Namespace)
+	})
+	return client
+}
+
+func newTestEndpoint(dnsName, recordType, resource string, targets ...string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint(dnsName, recordType, targets...)
+	ep.Labels[endpoint.ResourceLabelKey] = resource
+	return ep
+}
+
+func TestEventEmitterRecordsEventsOnObjects(t *testing.T) {
+	client := newTestEventClient()
+	// the cluster only serves the ingresses in networking.k8s.io/v1beta1
+	client.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "networking.k8s.io/v1beta1",
+			APIResources: []metav1.APIResource{{Name: "ingresses", Kind: "Ingress", Namespaced: true}},
+		},
+	}
+	dynamicClient := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme(), newTestIngress("networking.k8s.io/v1beta1", "default", "foo", "uid-foo"))
+	emitter := NewEventEmitter(client, dynamicClient)
+
+	emitter.Applied(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newTestEndpoint("foo.example.com", endpoint.RecordTypeA, "ingress/default/foo", "1.2.3.4"),
+			// the object doesn't exist anymore
+			newTestEndpoint("bar.example.com", endpoint.RecordTypeA, "ingress/default/bar", "1.2.3.4"),
+			// the record doesn't belong to any object
+			endpoint.NewEndpoint("baz.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	})
+
+	var events []corev1.Event
+	require.Eventually(t, func() bool {
+		list, err := client.CoreV1().Events("default").List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		events = list.Items
+		return len(events) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Len(t, events, 1)
+	event := events[0]
+	assert.Equal(t, corev1.EventTypeNormal, event.Type)
+	assert.Equal(t, EventReasonRecordCreated, event.Reason)
+	assert.Equal(t, "Created A record foo.example.com -> 1.2.3.4", event.Message)
+	assert.Equal(t, "external-dns", event.Source.Component)
+	assert.Equal(t, corev1.ObjectReference{
+		Kind:       "Ingress",
+		APIVersion: "networking.k8s.io/v1beta1",
+		Namespace:  "default",
+		Name:       "foo",
+		UID:        "uid-foo",
+	}, event.InvolvedObject)
+}
+
+func TestEventEmitterReference(t *testing.T) {
+	dynamicClient := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme(), newTestIngress("networking.k8s.io/v1", "default", "foo", "uid-foo"))
+
+	for _, tc := range []struct {
+		title    string
+		resource string
+		expected *corev1.ObjectReference
+	}{
+		{
+			title:    "existing object",
+			resource: "ingress/default/foo",
+			expected: &corev1.ObjectReference{Kind: "Ingress", APIVersion: "networking.k8s.io/v1", Namespace: "default", Name: "foo", UID: "uid-foo"},
+		},
+		{
+			title:    "deleted object",
+			resource: "ingress/default/bar",
+		},
+		{
+			title:    "unknown kind",
+			resource: "pod/default/foo",
+		},
+		{
+			title:    "invalid label",
+			resource: "ingress/foo",
+		},
+		{
+			title: "no label",
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			emitter := &EventEmitter{dynamicClient: dynamicClient}
+			assert.Equal(t, tc.expected, emitter.reference(context.Background(), tc.resource))
+		})
+	}
+
+	emitter := &EventEmitter{}
+	assert.Equal(t, &corev1.ObjectReference{Kind: "Service", APIVersion: "v1", Namespace: "kube-system", Name: "dns"},
+		emitter.reference(context.Background(), "service/kube-system/dns"), "should not look up the object without a dynamic client")
+}
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	events := []string{}
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// TestRunOnceEmitsEvents tests that RunOnce records Events for applied and skipped changes.
+func TestRunOnceEmitsEvents(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		newTestEndpoint("create-record", endpoint.RecordTypeA, "ingress/default/create", "1.2.3.4"),
+		newTestEndpoint("update-record", endpoint.RecordTypeA, "service/default/update", "8.8.4.4"),
+		newTestEndpoint("foreign-record", endpoint.RecordTypeA, "service/default/foreign", "8.8.4.4"),
+	}, nil)
+
+	provider := newMockProvider(
+		[]*endpoint.Endpoint{
+			{DNSName: "update-record", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"8.8.8.8"}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "service/default/update"}},
+			{DNSName: "foreign-record", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"8.8.8.8"}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "other-owner"}},
+			{DNSName: "delete-record", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"4.3.2.1"}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/delete"}},
+		},
+		&plan.Changes{
+			Create:    []*endpoint.Endpoint{{DNSName: "create-record", Targets: endpoint.Targets{"1.2.3.4"}}},
+			UpdateNew: []*endpoint.Endpoint{{DNSName: "foreign-record", Targets: endpoint.Targets{"8.8.4.4"}}, {DNSName: "update-record", Targets: endpoint.Targets{"8.8.4.4"}}},
+			UpdateOld: []*endpoint.Endpoint{{DNSName: "foreign-record", Targets: endpoint.Targets{"8.8.8.8"}}, {DNSName: "update-record", Targets: endpoint.Targets{"8.8.8.8"}}},
+			Delete:    []*endpoint.Endpoint{{DNSName: "delete-record", Targets: endpoint.Targets{"4.3.2.1"}}},
+		},
+	)
+
+	r, err := registry.NewNoopRegistry(&sortedUpdatesProvider{provider.(*mockProvider)})
+	require.NoError(t, err)
+
+	recorder := record.NewFakeRecorder(10)
+	ctrl := &Controller{
+		Source:       source,
+		Registry:     r,
+		Policy:       &plan.SyncPolicy{},
+		OwnerID:      "owner",
+		EventEmitter: &EventEmitter{recorder: recorder},
+	}
+
+	assert.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.ElementsMatch(t, []string{
+		"Warning RecordSkipped Record skipped: A record foreign-record -> 8.8.4.4 is owned by another owner (other-owner)",
+		"Normal RecordCreated Created A record create-record -> 1.2.3.4",
+		"Normal RecordUpdated Updated A record update-record -> 8.8.4.4",
+		"Normal RecordDeleted Deleted A record delete-record -> 4.3.2.1",
+	}, drainEvents(recorder))
+
+	source.AssertExpectations(t)
+}
+
+// sortedUpdatesProvider validates the updates sorted by name, as the order of the planned updates is random.
+// The updates are sorted in copies, so that the Events are emitted for the changes as they were planned.
+type sortedUpdatesProvider struct {
+	*mockProvider
+}
+
+func (p *sortedUpdatesProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	sorted := *changes
+	for _, updates := range []*[]*endpoint.Endpoint{&sorted.UpdateNew, &sorted.UpdateOld} {
+		*updates = append([]*endpoint.Endpoint{}, *updates...)
+		sort.Slice(*updates, func(i, j int) bool { return (*updates)[i].DNSName < (*updates)[j].DNSName })
+	}
+	return p.mockProvider.ApplyChanges(ctx, &sorted)
+}
+
+func TestEventEmitterSkippedOnlyOnChanges(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	emitter := &EventEmitter{recorder: recorder}
+	ctx := context.Background()
+
+	foo := newTestEndpoint("foo.example.com", endpoint.RecordTypeA, "crd/default/foo", "1.2.3.4")
+	foo.Labels[endpoint.OwnerLabelKey] = "other-owner"
+	bar := newTestEndpoint("bar.example.com", endpoint.RecordTypeA, "crd/default/bar", "1.2.3.5")
+
+	emitter.Skipped(ctx, []*endpoint.Endpoint{foo, bar})
+	assert.ElementsMatch(t, []string{
+		"Warning RecordSkipped Record skipped: A record foo.example.com -> 1.2.3.4 is owned by another owner (other-owner)",
+		"Warning RecordSkipped Record skipped: A record bar.example.com -> 1.2.3.5 isn't owned by ExternalDNS",
+	}, drainEvents(recorder))
+
+	// the same records aren't reported again
+	emitter.Skipped(ctx, []*endpoint.Endpoint{foo, bar})
+	assert.Empty(t, drainEvents(recorder))
+
+	// changed records and records skipped again are reported
+	changed := newTestEndpoint("foo.example.com", endpoint.RecordTypeA, "crd/default/foo", "1.2.3.6")
+	changed.Labels[endpoint.OwnerLabelKey] = "other-owner"
+	emitter.Skipped(ctx, []*endpoint.Endpoint{changed})
+	emitter.Skipped(ctx, []*endpoint.Endpoint{changed, bar})
+	assert.ElementsMatch(t, []string{
+		"Warning RecordSkipped Record skipped: A record foo.example.com -> 1.2.3.6 is owned by another owner (other-owner)",
+		"Warning RecordSkipped Record skipped: A record bar.example.com -> 1.2.3.5 isn't owned by ExternalDNS",
+	}, drainEvents(recorder))
+}
+
+func TestEventEmitterFailed(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	emitter := &EventEmitter{recorder: recorder}
+
+	emitter.Failed(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{newTestEndpoint("foo.example.com", endpoint.RecordTypeCNAME, "crd/default/foo", "bar.example.com")},
+		Delete: []*endpoint.Endpoint{newTestEndpoint("bar.example.com", endpoint.RecordTypeA, "crd/default/bar", "1.2.3.4", "5.6.7.8")},
+	}, errors.New("access denied"))
+
+	assert.ElementsMatch(t, []string{
+		"Warning RecordChangeFailed Failed to create CNAME record foo.example.com -> bar.example.com: access denied",
+		"Warning RecordChangeFailed Failed to delete A record bar.example.com -> 1.2.3.4, 5.6.7.8: access denied",
+	}, drainEvents(recorder))
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Stage is a step of a synchronization.
+type Stage string
+
+const (
+	// StageRegistryRecords is the listing of the current records from the registry
+	StageRegistryRecords Stage = "registry_records"
+	// StageSourceEndpoints is the listing of the desired endpoints from the sources
+	StageSourceEndpoints Stage = "source_endpoints"
+	// StageCalculatePlan is the calculation of the changes, it never fails
+	StageCalculatePlan Stage = "calculate_plan"
+	// StageApplyChanges is the application of the calculated changes through the registry
+	StageApplyChanges Stage = "apply_changes"
+)
+
+// StageError is the last error of a stage.
+type StageError struct {
+	Error string    `json:"error"`
+	Time  time.Time `json:"time"`
+}
+
+// SyncReport summarizes the outcome of the synchronizations, as served by the health endpoints.
+type SyncReport struct {
+	Status       string               `json:"status"`
+	Message      string               `json:"message,omitempty"`
+	Leader       *bool                `json:"leader,omitempty"`
+	LastSyncTime *time.Time           `json:"lastSyncTime,omitempty"`
+	Errors       map[Stage]StageError `json:"errors,omitempty"`
+}
+
+// SyncStatus keeps track of the successful synchronizations and the failing stages of a Controller.
+// It is safe for concurrent use.
+type SyncStatus struct {
+	// The duration without a successful synchronization after which the controller is considered not ready,
+	// and the duration of a running synchronization after which it is considered wedged, zero disables the checks
+	MaxSyncAge time.Duration
+
+	mu       sync.RWMutex
+	started  time.Time
+	syncing  time.Time
+	lastSync time.Time
+	errors   map[Stage]StageError
+	now      func() time.Time
+}
+
+// NewSyncStatus returns a SyncStatus that isn't ready when no synchronization succeeded within maxSyncAge.
+func NewSyncStatus(maxSyncAge time.Duration) *SyncStatus {
+	return &SyncStatus{
+		MaxSyncAge: maxSyncAge,
+		started:    time.Now(),
+		errors:     map[Stage]StageError{},
+		now:        time.Now,
+	}
+}
+
+// start resets the time from which the age of the last synchronization is measured,
+// e.g. when a replica takes over the leader election lease.
+func (s *SyncStatus) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = s.now()
+}
+
+// begin records the start of a synchronization, until it ends.
+func (s *SyncStatus) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncing = s.now()
+}
+
+// end records that the running synchronization returned, whether it succeeded or not.
+func (s *SyncStatus) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncing = time.Time{}
+}
+
+func (s *SyncStatus) succeeded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSync = s.now()
+}
+
+func (s *SyncStatus) failed(stage Stage, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[stage] = StageError{Error: err.Error(), Time: s.now()}
+}
+
+// Healthy returns an error if a synchronization has been running for longer than MaxSyncAge, i.e. the
+// controller loop is wedged. Failed synchronizations are retried and don't make the controller unhealthy.
+func (s *SyncStatus) Healthy() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.MaxSyncAge <= 0 || s.syncing.IsZero() {
+		return nil
+	}
+	if age := s.now().Sub(s.syncing); age > s.MaxSyncAge {
+		return fmt.Errorf("synchronization running for more than %s", s.MaxSyncAge)
+	}
+	return nil
+}
+
+// Ready returns an error if no synchronization succeeded yet, or none succeeded within MaxSyncAge,
+// neither since the start nor since the last successful synchronization.
+func (s *SyncStatus) Ready() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.lastSync.IsZero() {
+		return errors.New("no successful synchronization yet")
+	}
+	return s.checkSyncAge()
+}
+
+func (s *SyncStatus) checkSyncAge() error {
+	if s.MaxSyncAge <= 0 {
+		return nil
+	}
+	since := s.started
+	if s.lastSync.After(since) {
+		since = s.lastSync
+	}
+	if age := s.now().Sub(since); age > s.MaxSyncAge {
+		return fmt.Errorf("no successful synchronization within %s", s.MaxSyncAge)
+	}
+	return nil
+}
+
+// Report returns the time of the last successful synchronization and the last error of every failed stage.
+func (s *SyncStatus) Report() SyncReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	report := SyncReport{}
+	if !s.lastSync.IsZero() {
+		lastSync := s.lastSync
+		report.LastSyncTime = &lastSync
+	}
+	if len(s.errors) > 0 {
+		report.Errors = make(map[Stage]StageError, len(s.errors))
+		for stage, err := range s.errors {
+			report.Errors[stage] = err
+		}
+	}
+	return report
+}
+
+// HealthzHandler serves the liveness of the controller. It fails when a synchronization has been running for
+// longer than MaxSyncAge, or when the elector is the leader but failed to renew its lease. Failed synchronizations
+// don't fail it, so that a failing provider doesn't restart the controller. Standby replicas don't synchronize,
+// so the synchronizations are only checked on the leader. The elector is optional.
+func (s *SyncStatus) HealthzHandler(elector *LeaderElector) http.Handler {
+	return s.handler(elector, s.Healthy)
+}
+
+// ReadyzHandler serves the readiness of the controller. It fails until the first synchronization succeeded,
+// when no synchronization succeeded within MaxSyncAge, or when the elector is the leader but failed to renew its lease.
+func (s *SyncStatus) ReadyzHandler(elector *LeaderElector) http.Handler {
+	return s.handler(elector, s.Ready)
+}
+
+func (s *SyncStatus) handler(elector *LeaderElector, check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := s.Report()
+		err := func() error {
+			if elector == nil {
+				return check()
+			}
+			leader := elector.IsLeader()
+			report.Leader = &leader
+			if err := elector.Check(r); err != nil {
+				return err
+			}
+			if !leader {
+				return nil
+			}
+			return check()
+		}()
+
+		status := http.StatusOK
+		report.Status = "ok"
+		if err != nil {
+			status = http.StatusServiceUnavailable
+			report.Status = "failure"
+			report.Message = err.Error()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestSyncStatus returns a SyncStatus whose clock is advanced by the returned function.
+func newTestSyncStatus(maxSyncAge time.Duration) (*SyncStatus, func(time.Duration)) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewSyncStatus(maxSyncAge)
+	s.now = func() time.Time { return now }
+	s.start()
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestSyncStatusHealthy(t *testing.T) {
+	s, advance := newTestSyncStatus(3 * time.Minute)
+
+	advance(2 * time.Minute)
+	assert.NoError(t, s.Healthy())
+	assert.Error(t, s.Ready(), "should not be ready before the first synchronization")
+
+	s.succeeded()
+	advance(2 * time.Minute)
+	assert.NoError(t, s.Healthy())
+	assert.NoError(t, s.Ready())
+
+	s.failed(StageApplyChanges, errors.New("throttled"))
+	advance(2 * time.Minute)
+	assert.NoError(t, s.Healthy(), "failed synchronizations should not fail the liveness")
+	assert.Error(t, s.Ready(), "should not be ready when no synchronization succeeded within the maximum age")
+
+	s.start()
+	assert.NoError(t, s.Ready(), "should measure the age from the latest start")
+}
+
+func TestSyncStatusHealthyWedged(t *testing.T) {
+	s, advance := newTestSyncStatus(3 * time.Minute)
+
+	s.begin()
+	advance(2 * time.Minute)
+	assert.NoError(t, s.Healthy(), "should be healthy while a synchronization runs within the maximum age")
+
+	advance(2 * time.Minute)
+	assert.Error(t, s.Healthy(), "should fail when a synchronization runs for longer than the maximum age")
+
+	s.end()
+	assert.NoError(t, s.Healthy(), "should be healthy once the synchronization returned")
+}
+
+func TestSyncStatusHealthyDisabled(t *testing.T) {
+	s, advance := newTestSyncStatus(0)
+
+	s.begin()
+	advance(time.Hour)
+	assert.NoError(t, s.Healthy())
+	assert.Error(t, s.Ready())
+}
+
+func TestSyncStatusReport(t *testing.T) {
+	s, advance := newTestSyncStatus(time.Minute)
+
+	assert.Equal(t, SyncReport{}, s.Report())
+
+	s.failed(StageRegistryRecords, errors.New("registry failure"))
+	advance(time.Second)
+	s.failed(StageRegistryRecords, errors.New("another registry failure"))
+	s.failed(StageSourceEndpoints, errors.New("source failure"))
+	s.succeeded()
+
+	report := s.Report()
+	require.NotNil(t, report.LastSyncTime)
+	assert.Equal(t, s.now(), *report.LastSyncTime)
+	assert.Equal(t, map[Stage]StageError{
+		StageRegistryRecords: {Error: "another registry failure", Time: s.now()},
+		StageSourceEndpoints: {Error: "source failure", Time: s.now()},
+	}, report.Errors)
+}
+
+func TestSyncStatusHandlers(t *testing.T) {
+	for _, tc := range []struct {
+		title          string
+		synced         bool
+		syncing        bool
+		age            time.Duration
+		expectedHealth int
+		expectedReady  int
+	}{
+		{
+			title:          "not synchronized yet",
+			age:            time.Minute,
+			expectedHealth: http.StatusOK,
+			expectedReady:  http.StatusServiceUnavailable,
+		},
+		{
+			title:          "synchronized",
+			synced:         true,
+			age:            time.Minute,
+			expectedHealth: http.StatusOK,
+			expectedReady:  http.StatusOK,
+		},
+		{
+			title:          "last synchronization too old",
+			synced:         true,
+			age:            time.Hour,
+			expectedHealth: http.StatusOK,
+			expectedReady:  http.StatusServiceUnavailable,
+		},
+		{
+			title:          "synchronization wedged",
+			synced:         true,
+			syncing:        true,
+			age:            time.Hour,
+			expectedHealth: http.StatusServiceUnavailable,
+			expectedReady:  http.StatusServiceUnavailable,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			s, advance := newTestSyncStatus(3 * time.Minute)
+			if tc.synced {
+				s.succeeded()
+			}
+			if tc.syncing {
+				s.begin()
+			}
+			advance(tc.age)
+
+			for _, h := range []struct {
+				handler  http.Handler
+				expected int
+			}{
+				{s.HealthzHandler(nil), tc.expectedHealth},
+				{s.ReadyzHandler(nil), tc.expectedReady},
+			} {
+				w := httptest.NewRecorder()
+				h.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+				assert.Equal(t, h.expected, w.Code)
+
+				report := SyncReport{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+				assert.Equal(t, tc.synced, report.LastSyncTime != nil)
+				assert.Nil(t, report.Leader)
+				if h.expected == http.StatusOK {
+					assert.Equal(t, "ok", report.Status)
+				} else {
+					assert.Equal(t, "failure", report.Status)
+					assert.NotEmpty(t, report.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncStatusHandlersStandby(t *testing.T) {
+	elector, err := NewLeaderElector(fake.NewSimpleClientset(), testLeaderElectionConfig("replica-1"))
+	require.NoError(t, err)
+
+	s, advance := newTestSyncStatus(3 * time.Minute)
+	s.begin()
+	advance(time.Hour)
+
+	for _, handler := range []http.Handler{s.HealthzHandler(elector), s.ReadyzHandler(elector)} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code, "standby replicas don't synchronize")
+
+		report := SyncReport{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		require.NotNil(t, report.Leader)
+		assert.False(t, *report.Leader)
+	}
+}
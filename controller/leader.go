@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+var leaderGauge = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "leader",
+		Help:      "Whether this instance holds the leader election lease (1) or not (0)",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(leaderGauge)
+}
+
+// LeaderElectionConfig configures the lease based leader election between several replicas.
+type LeaderElectionConfig struct {
+	// Namespace and Name of the Lease object used as lock
+	Namespace string
+	Name      string
+	// Identity of this replica, must be unique among all replicas
+	Identity string
+	// The duration non-leader candidates wait before trying to acquire the lease
+	LeaseDuration time.Duration
+	// The duration the leader retries refreshing the lease before giving it up
+	RenewDeadline time.Duration
+	// The duration candidates wait between tries of acquiring or refreshing the lease
+	RetryPeriod time.Duration
+}
+
+// LeaderElector runs a function only while this replica is the leader.
+// Standby replicas keep running everything else, e.g. the informers of the sources,
+// so that they are ready to take over quickly.
+type LeaderElector struct {
+	config  leaderelection.LeaderElectionConfig
+	healthz *leaderelection.HealthzAdaptor
+	run     func(ctx context.Context)
+	leading int32
+}
+
+// NewLeaderElector creates a LeaderElector competing for the configured lease.
+func NewLeaderElector(client kubernetes.Interface, cfg LeaderElectionConfig) (*LeaderElector, error) {
+	le := &LeaderElector{
+		healthz: leaderelection.NewLeaderHealthzAdaptor(cfg.LeaseDuration),
+	}
+	le.config = leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Namespace: cfg.Namespace,
+				Name:      cfg.Name,
+			},
+			Client: client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: cfg.Identity,
+			},
+		},
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.RenewDeadline,
+		RetryPeriod:     cfg.RetryPeriod,
+		ReleaseOnCancel: true,
+		WatchDog:        le.healthz,
+		Name:            cfg.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: le.startedLeading,
+			OnStoppedLeading: le.stoppedLeading,
+			OnNewLeader: func(identity string) {
+				log.Infof("Current leader is %s", identity)
+			},
+		},
+	}
+
+	// validate the configuration up front rather than on every election
+	if _, err := leaderelection.NewLeaderElector(le.config); err != nil {
+		return nil, err
+	}
+
+	return le, nil
+}
+
+// Run takes part in the leader election until context is canceled and runs the given function while holding the lease.
+// The context passed to the function is canceled when the lease is lost, after which Run keeps competing for it.
+func (le *LeaderElector) Run(ctx context.Context, run func(ctx context.Context)) {
+	le.run = run
+	leaderGauge.Set(0)
+	for {
+		elector, err := leaderelection.NewLeaderElector(le.config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		le.healthz.SetLeaderElection(elector)
+		elector.Run(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Info("Terminating leader election")
+			return
+		default:
+		}
+	}
+}
+
+// IsLeader returns true if this replica currently holds the lease.
+func (le *LeaderElector) IsLeader() bool {
+	return atomic.LoadInt32(&le.leading) == 1
+}
+
+// Check returns an error if this replica is the leader, but failed to renew the lease in time.
+func (le *LeaderElector) Check(req *http.Request) error {
+	return le.healthz.Check(req)
+}
+
+func (le *LeaderElector) startedLeading(ctx context.Context) {
+	log.Info("Acquired the leader election lease")
+	atomic.StoreInt32(&le.leading, 1)
+	leaderGauge.Set(1)
+	le.run(ctx)
+}
+
+func (le *LeaderElector) stoppedLeading() {
+	log.Info("Stopped leading")
+	atomic.StoreInt32(&le.leading, 0)
+	leaderGauge.Set(0)
+}
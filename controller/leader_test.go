@@ -0,0 +1,93 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testLeaderElectionConfig(identity string) LeaderElectionConfig {
+	return LeaderElectionConfig{
+		Namespace:     "default",
+		Name:          "external-dns",
+		Identity:      identity,
+		LeaseDuration: 2 * time.Second,
+		RenewDeadline: time.Second,
+		RetryPeriod:   100 * time.Millisecond,
+	}
+}
+
+func TestNewLeaderElectorInvalidConfig(t *testing.T) {
+	cfg := testLeaderElectionConfig("replica-1")
+	cfg.RenewDeadline = cfg.LeaseDuration
+
+	_, err := NewLeaderElector(fake.NewSimpleClientset(), cfg)
+	assert.Error(t, err)
+}
+
+func TestLeaderElectorRunsOnlyWhileLeading(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	leader, err := NewLeaderElector(client, testLeaderElectionConfig("replica-1"))
+	require.NoError(t, err)
+	standby, err := NewLeaderElector(client, testLeaderElectionConfig("replica-2"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leading := make(chan struct{})
+	go leader.Run(ctx, func(ctx context.Context) {
+		close(leading)
+		<-ctx.Done()
+	})
+
+	select {
+	case <-leading:
+	case <-time.After(5 * time.Second):
+		t.Fatal("replica-1 did not acquire the lease")
+	}
+	assert.True(t, leader.IsLeader())
+	assert.NoError(t, leader.Check(nil))
+
+	standbyCtx, standbyCancel := context.WithCancel(context.Background())
+	standbyDone := make(chan struct{})
+	go func() {
+		standby.Run(standbyCtx, func(ctx context.Context) {
+			t.Error("replica-2 must not run while replica-1 holds the lease")
+		})
+		close(standbyDone)
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+	assert.False(t, standby.IsLeader())
+	assert.NoError(t, standby.Check(nil))
+
+	standbyCancel()
+	<-standbyDone
+
+	lease, err := client.CoordinationV1().Leases("default").Get(context.Background(), "external-dns", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "replica-1", *lease.Spec.HolderIdentity)
+}
@@ -34,6 +34,10 @@ const (
 	OwnerLabelKey = "owner"
 	// ResourceLabelKey is the name of the label that identifies k8s resource which wants to acquire the DNS name
 	ResourceLabelKey = "resource"
+	// PriorityLabelKey is the name of the label that ranks k8s resources which want to acquire the same DNS name
+	PriorityLabelKey = "priority"
+	// ClaimLabelKey is the name of the label that allows to take over the ownership of a matching record which isn't owned by anyone
+	ClaimLabelKey = "claim"
 
 	// AWSSDDescriptionLabel label responsible for storing raw owner/resource combination information in the Labels
 	// supposed to be inserted by AWS SD Provider, and parsed into OwnerLabelKey and ResourceLabelKey key by AWS SD Registry
@@ -41,6 +45,10 @@ const (
 
 	// DualstackLabelKey is the name of the label that identifies dualstack endpoints
 	DualstackLabelKey = "dualstack"
+
+	// OrphanedLabelKey is the name of the label that marks an ownership record whose owned record is gone,
+	// which is deleted like any other record which is no longer desired
+	OrphanedLabelKey = "orphaned"
 )
 
 // Labels store metadata related to the endpoint
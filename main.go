@@ -18,6 +18,9 @@ package main
 
 import (
 	"context"
+	"flag"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
@@ -26,6 +29,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/klog/v2"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"sigs.k8s.io/external-dns/controller"
@@ -89,39 +93,12 @@ func main() {
 		log.Fatalf("failed to parse log level: %v", err)
 	}
 	log.SetLevel(ll)
+	silenceKlog()
 
 	ctx, cancel := context.WithCancel(context.Background())
-
-	go serveMetrics(cfg.MetricsAddress)
 	go handleSigterm(cancel)
 
-	// Create a source.Config from the flags passed by the user.
-	sourceCfg := &source.Config{
-		Namespace:                      cfg.Namespace,
-		AnnotationFilter:               cfg.AnnotationFilter,
-		FQDNTemplate:                   cfg.FQDNTemplate,
-		CombineFQDNAndAnnotation:       cfg.CombineFQDNAndAnnotation,
-		IgnoreHostnameAnnotation:       cfg.IgnoreHostnameAnnotation,
-		Compatibility:                  cfg.Compatibility,
-		PublishInternal:                cfg.PublishInternal,
-		PublishHostIP:                  cfg.PublishHostIP,
-		AlwaysPublishNotReadyAddresses: cfg.AlwaysPublishNotReadyAddresses,
-		ConnectorServer:                cfg.ConnectorSourceServer,
-		CRDSourceAPIVersion:            cfg.CRDSourceAPIVersion,
-		CRDSourceKind:                  cfg.CRDSourceKind,
-		KubeConfig:                     cfg.KubeConfig,
-		APIServerURL:                   cfg.APIServerURL,
-		ServiceTypeFilter:              cfg.ServiceTypeFilter,
-		CFAPIEndpoint:                  cfg.CFAPIEndpoint,
-		CFUsername:                     cfg.CFUsername,
-		CFPassword:                     cfg.CFPassword,
-		ContourLoadBalancerService:     cfg.ContourLoadBalancerService,
-		SkipperRouteGroupVersion:       cfg.SkipperRouteGroupVersion,
-		RequestTimeout:                 cfg.RequestTimeout,
-	}
-
-	// Lookup all the selected sources by names and pass them the desired configuration.
-	sources, err := source.ByNames(&source.SingletonClientGenerator{
+	clientGenerator := &source.SingletonClientGenerator{
 		KubeConfig:   cfg.KubeConfig,
 		APIServerURL: cfg.APIServerURL,
 		// If update events are enabled, disable timeout.
@@ -131,14 +108,8 @@ func main() {
 			}
 			return cfg.RequestTimeout
 		}(),
-	}, cfg.Sources, sourceCfg)
-	if err != nil {
-		log.Fatal(err)
 	}
 
-	// Combine multiple sources into a single, deduplicated source.
-	endpointsSource := source.NewDedupSource(source.NewMultiSource(sources))
-
 	domainFilter := endpoint.NewDomainFilterWithExclusions(cfg.DomainFilter, cfg.ExcludeDomains)
 	zoneIDFilter := provider.NewZoneIDFilter(cfg.ZoneIDFilter)
 	zoneTypeFilter := provider.NewZoneTypeFilter(cfg.AWSZoneType)
@@ -301,9 +272,30 @@ func main() {
 	case "noop":
 		r, err = registry.NewNoopRegistry(p)
 	case "txt":
-		r, err = registry.NewTXTRegistry(p, cfg.TXTPrefix, cfg.TXTSuffix, cfg.TXTOwnerID, cfg.TXTCacheInterval)
+		var keyring *registry.TXTKeyring
+		if len(cfg.TXTKeyFiles) > 0 {
+			keyring, err = registry.LoadTXTKeyring(cfg.TXTKeyFiles, cfg.TXTEncrypt, cfg.TXTAllowUnprotected)
+			if err != nil {
+				log.Fatalf("failed to load the TXT registry keys: %v", err)
+			}
+		}
+		r, err = registry.NewTXTRegistry(p, registry.TXTRegistryConfig{
+			OwnerID:          cfg.TXTOwnerID,
+			Prefix:           cfg.TXTPrefix,
+			Suffix:           cfg.TXTSuffix,
+			NameTemplate:     cfg.TXTNameTemplate,
+			CacheInterval:    cfg.TXTCacheInterval,
+			Keyring:          keyring,
+			PreviousOwnerIDs: cfg.TXTPreviousOwnerIDs,
+			SharedRecords:    cfg.TXTSharedRecords,
+			Zones:            cfg.DomainFilter,
+		})
 	case "aws-sd":
 		r, err = registry.NewAWSSDRegistry(p.(*awssd.AWSSDProvider), cfg.TXTOwnerID)
+	case "crd":
+		r, err = newCRDRegistry(p, clientGenerator, cfg.TXTOwnerID)
+	case "file":
+		r, err = registry.NewFileRegistry(p, cfg.FileRegistryPath, cfg.TXTOwnerID)
 	default:
 		log.Fatalf("unknown registry: %s", cfg.Registry)
 	}
@@ -317,16 +309,135 @@ func main() {
 		log.Fatalf("unknown policy: %s", cfg.Policy)
 	}
 
+	conflictResolver, exists := plan.ConflictResolvers[cfg.ConflictResolver]
+	if !exists {
+		log.Fatalf("unknown conflict resolver: %s", cfg.ConflictResolver)
+	}
+
+	// the noop registry doesn't track ownership, every record is considered owned
+	ownerID := cfg.TXTOwnerID
+	if cfg.Registry == "noop" {
+		ownerID = ""
+	}
+
+	// the check only reads the registry: it neither needs the sources nor takes part in the leader election
+	if cfg.CheckRegistry {
+		report, err := registry.Check(ctx, r, ownerID)
+		if err != nil {
+			log.Fatalf("failed to check the registry: %v", err)
+		}
+		if err := report.Export(os.Stdout, cfg.CheckRegistryFormat); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	var elector *controller.LeaderElector
+	if cfg.LeaderElection && !cfg.Once {
+		elector, err = newLeaderElector(cfg, clientGenerator)
+		if err != nil {
+			log.Fatalf("failed to set up leader election: %v", err)
+		}
+	}
+
+	syncStatus := controller.NewSyncStatus(time.Duration(cfg.HealthMaxMissedIntervals) * cfg.Interval)
+
+	go serveMetrics(cfg.MetricsAddress, syncStatus, elector)
+
+	// Create a source.Config from the flags passed by the user.
+	sourceCfg := &source.Config{
+		Namespaces:                     cfg.Namespaces,
+		NamespaceSelector:              cfg.NamespaceSelector,
+		AnnotationFilter:               cfg.AnnotationFilter,
+		LabelFilter:                    cfg.LabelFilter,
+		IngressClassNames:              cfg.IngressClassNames,
+		FQDNTemplate:                   cfg.FQDNTemplate,
+		CombineFQDNAndAnnotation:       cfg.CombineFQDNAndAnnotation,
+		IgnoreHostnameAnnotation:       cfg.IgnoreHostnameAnnotation,
+		Compatibility:                  cfg.Compatibility,
+		PublishInternal:                cfg.PublishInternal,
+		PublishHostIP:                  cfg.PublishHostIP,
+		AlwaysPublishNotReadyAddresses: cfg.AlwaysPublishNotReadyAddresses,
+		ConnectorServer:                cfg.ConnectorSourceServer,
+		CRDSourceAPIVersion:            cfg.CRDSourceAPIVersion,
+		CRDSourceKind:                  cfg.CRDSourceKind,
+		KubeConfig:                     cfg.KubeConfig,
+		APIServerURL:                   cfg.APIServerURL,
+		ServiceTypeFilter:              cfg.ServiceTypeFilter,
+		CFAPIEndpoint:                  cfg.CFAPIEndpoint,
+		CFUsername:                     cfg.CFUsername,
+		CFPassword:                     cfg.CFPassword,
+		ContourLoadBalancerService:     cfg.ContourLoadBalancerService,
+		SkipperRouteGroupVersion:       cfg.SkipperRouteGroupVersion,
+		EventsPollInterval:             cfg.EventsPollInterval,
+		RequestTimeout:                 cfg.RequestTimeout,
+	}
+
+	// Lookup all the selected sources by names and pass them the desired configuration.
+	sources, err := source.ByNames(clientGenerator, cfg.Sources, sourceCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Combine multiple sources into a single, deduplicated source.
+	endpointsSource := source.NewDedupSource(source.NewMultiSource(sources))
+
+	var deletionGuard *plan.DeletionGuardPolicy
+	if !cfg.DeletionGuardOverride && (cfg.DeletionGuardMaxDeletes > 0 || cfg.DeletionGuardMaxPercentage > 0) {
+		deletionGuard = &plan.DeletionGuardPolicy{
+			MaxDeletes:          cfg.DeletionGuardMaxDeletes,
+			MaxDeletePercentage: cfg.DeletionGuardMaxPercentage,
+			OwnerID:             ownerID,
+		}
+	}
+
+	var planOutput io.Writer = os.Stdout
+	var planFile *os.File
+	if cfg.PlanOutputFormat != "" && cfg.PlanOutputFile != "" {
+		planFile, err = os.Create(cfg.PlanOutputFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer planFile.Close()
+		planOutput = planFile
+	}
+
+	var eventEmitter *controller.EventEmitter
+	if cfg.KubeEvents && !cfg.DryRun {
+		eventEmitter, err = newEventEmitter(clientGenerator)
+		if err != nil {
+			log.Fatalf("failed to set up Kubernetes Events: %v", err)
+		}
+	}
+
 	ctrl := controller.Controller{
-		Source:       endpointsSource,
-		Registry:     r,
-		Policy:       policy,
-		Interval:     cfg.Interval,
-		DomainFilter: domainFilter,
+		Source:           endpointsSource,
+		Registry:         r,
+		Policy:           policy,
+		ConflictResolver: conflictResolver,
+		DeletionGuard:    deletionGuard,
+		PlanOutputFormat: cfg.PlanOutputFormat,
+		PlanOutput:       planOutput,
+		Interval:         cfg.Interval,
+		BackoffBase:      cfg.RetryBackoffBase,
+		BackoffMax:       cfg.RetryBackoffMax,
+		DomainFilter:     domainFilter,
+		OwnerID:          ownerID,
+		SyncStatus:       syncStatus,
+		EventEmitter:     eventEmitter,
 	}
 
 	if cfg.Once {
 		err := ctrl.RunOnce(ctx)
+		// os.Exit skips the deferred calls, close the plan output file before exiting so its content isn't lost.
+		if planFile != nil {
+			if closeErr := planFile.Close(); closeErr != nil {
+				log.Errorf("failed to close the plan output file: %v", closeErr)
+				if err == nil {
+					err = closeErr
+				}
+			}
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -342,9 +453,55 @@ func main() {
 	}
 
 	ctrl.ScheduleRunOnce(time.Now())
+	if elector != nil {
+		elector.Run(ctx, ctrl.Run)
+		return
+	}
 	ctrl.Run(ctx)
 }
 
+func newLeaderElector(cfg *externaldns.Config, clientGenerator source.ClientGenerator) (*controller.LeaderElector, error) {
+	client, err := clientGenerator.KubeClient()
+	if err != nil {
+		return nil, err
+	}
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	return controller.NewLeaderElector(client, controller.LeaderElectionConfig{
+		Namespace:     cfg.LeaderElectionNamespace,
+		Name:          cfg.LeaderElectionLeaseName,
+		Identity:      identity,
+		LeaseDuration: cfg.LeaderElectionLeaseDuration,
+		RenewDeadline: cfg.LeaderElectionRenewDeadline,
+		RetryPeriod:   cfg.LeaderElectionRetryPeriod,
+	})
+}
+
+func newEventEmitter(clientGenerator source.ClientGenerator) (*controller.EventEmitter, error) {
+	client, err := clientGenerator.KubeClient()
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := clientGenerator.DynamicKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return controller.NewEventEmitter(client, dynamicClient), nil
+}
+
+func newCRDRegistry(p provider.Provider, clientGenerator source.ClientGenerator, ownerID string) (registry.Registry, error) {
+	dynamicClient, err := clientGenerator.DynamicKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return registry.NewCRDRegistry(p, dynamicClient, ownerID)
+}
+
 func handleSigterm(cancel func()) {
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGTERM)
@@ -353,13 +510,21 @@ func handleSigterm(cancel func()) {
 	cancel()
 }
 
-func serveMetrics(address string) {
-	http.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+func serveMetrics(address string, syncStatus *controller.SyncStatus, elector *controller.LeaderElector) {
+	http.Handle("/healthz", syncStatus.HealthzHandler(elector))
+	http.Handle("/readyz", syncStatus.ReadyzHandler(elector))
 
 	http.Handle("/metrics", promhttp.Handler())
 
 	log.Fatal(http.ListenAndServe(address, nil))
 }
+
+// silenceKlog discards the logs of klog/v2, used by the Kubernetes clients since v0.19, like those of
+// k8s.io/klog which is replaced by a no-op implementation in go.mod.
+func silenceKlog() {
+	flags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	_ = flags.Set("logtostderr", "false")
+	_ = flags.Set("stderrthreshold", "FATAL")
+	klog.SetOutput(ioutil.Discard)
+}
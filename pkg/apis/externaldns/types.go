@@ -45,8 +45,11 @@ type Config struct {
 	ContourLoadBalancerService        string
 	SkipperRouteGroupVersion          string
 	Sources                           []string
-	Namespace                         string
+	Namespaces                        []string
+	NamespaceSelector                 string
 	AnnotationFilter                  string
+	LabelFilter                       string
+	IngressClassNames                 []string
 	FQDNTemplate                      string
 	CombineFQDNAndAnnotation          bool
 	IgnoreHostnameAnnotation          bool
@@ -107,18 +110,44 @@ type Config struct {
 	TLSClientCert                     string
 	TLSClientCertKey                  string
 	Policy                            string
+	ConflictResolver                  string
+	DeletionGuardMaxDeletes           int
+	DeletionGuardMaxPercentage        int
+	DeletionGuardOverride             bool
 	Registry                          string
 	TXTOwnerID                        string
+	TXTPreviousOwnerIDs               []string
 	TXTPrefix                         string
 	TXTSuffix                         string
+	TXTNameTemplate                   string
 	Interval                          time.Duration
+	RetryBackoffBase                  time.Duration
+	RetryBackoffMax                   time.Duration
 	Once                              bool
 	DryRun                            bool
+	PlanOutputFormat                  string
+	PlanOutputFile                    string
+	CheckRegistry                     bool
+	CheckRegistryFormat               string
 	UpdateEvents                      bool
+	EventsPollInterval                time.Duration
+	KubeEvents                        bool
+	HealthMaxMissedIntervals          int
+	LeaderElection                    bool
+	LeaderElectionNamespace           string
+	LeaderElectionLeaseName           string
+	LeaderElectionLeaseDuration       time.Duration
+	LeaderElectionRenewDeadline       time.Duration
+	LeaderElectionRetryPeriod         time.Duration
 	LogFormat                         string
 	MetricsAddress                    string
 	LogLevel                          string
 	TXTCacheInterval                  time.Duration
+	TXTKeyFiles                       []string
+	TXTEncrypt                        bool
+	TXTAllowUnprotected               bool
+	TXTSharedRecords                  bool
+	FileRegistryPath                  string
 	ExoscaleEndpoint                  string
 	ExoscaleAPIKey                    string `secure:"yes"`
 	ExoscaleAPISecret                 string `secure:"yes"`
@@ -151,8 +180,11 @@ var defaultConfig = &Config{
 	ContourLoadBalancerService:  "heptio-contour/contour",
 	SkipperRouteGroupVersion:    "zalando.org/v1",
 	Sources:                     nil,
-	Namespace:                   "",
+	Namespaces:                  nil,
+	NamespaceSelector:           "",
 	AnnotationFilter:            "",
+	LabelFilter:                 "",
+	IngressClassNames:           nil,
 	FQDNTemplate:                "",
 	CombineFQDNAndAnnotation:    false,
 	IgnoreHostnameAnnotation:    false,
@@ -205,15 +237,41 @@ var defaultConfig = &Config{
 	TLSClientCert:               "",
 	TLSClientCertKey:            "",
 	Policy:                      "sync",
+	ConflictResolver:            "per-resource",
+	DeletionGuardMaxDeletes:     0,
+	DeletionGuardMaxPercentage:  0,
+	DeletionGuardOverride:       false,
 	Registry:                    "txt",
 	TXTOwnerID:                  "default",
+	TXTPreviousOwnerIDs:         []string{},
 	TXTPrefix:                   "",
 	TXTSuffix:                   "",
+	TXTNameTemplate:             "",
 	TXTCacheInterval:            0,
+	TXTKeyFiles:                 []string{},
+	TXTEncrypt:                  false,
+	TXTAllowUnprotected:         false,
+	TXTSharedRecords:            false,
+	FileRegistryPath:            "",
 	Interval:                    time.Minute,
+	RetryBackoffBase:            0,
+	RetryBackoffMax:             10 * time.Minute,
 	Once:                        false,
 	DryRun:                      false,
+	PlanOutputFormat:            "",
+	PlanOutputFile:              "",
+	CheckRegistry:               false,
+	CheckRegistryFormat:         "table",
 	UpdateEvents:                false,
+	EventsPollInterval:          15 * time.Second,
+	KubeEvents:                  false,
+	HealthMaxMissedIntervals:    3,
+	LeaderElection:              false,
+	LeaderElectionNamespace:     "default",
+	LeaderElectionLeaseName:     "external-dns",
+	LeaderElectionLeaseDuration: 15 * time.Second,
+	LeaderElectionRenewDeadline: 10 * time.Second,
+	LeaderElectionRetryPeriod:   2 * time.Second,
 	LogFormat:                   "text",
 	MetricsAddress:              ":7979",
 	LogLevel:                    logrus.InfoLevel.String(),
@@ -300,10 +358,13 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("skipper-routegroup-groupversion", "The resource version for skipper routegroup").Default(source.DefaultRoutegroupVersion).StringVar(&cfg.SkipperRouteGroupVersion)
 
 	// Flags related to processing sources
-	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required, options: service, ingress, node, fake, connector, istio-gateway, istio-virtualservice, cloudfoundry, contour-ingressroute, crd, empty, skipper-routegroup,openshift-route)").Required().PlaceHolder("source").EnumsVar(&cfg.Sources, "service", "ingress", "node", "istio-gateway", "istio-virtualservice", "cloudfoundry", "contour-ingressroute", "fake", "connector", "crd", "empty", "skipper-routegroup", "openshift-route")
+	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required, options: service, ingress, node, fake, connector, istio-gateway, istio-virtualservice, cloudfoundry, contour-ingressroute, crd, empty, skipper-routegroup,openshift-route, gateway-httproute, gateway-tlsroute, gateway-tcproute)").Required().PlaceHolder("source").EnumsVar(&cfg.Sources, "service", "ingress", "node", "istio-gateway", "istio-virtualservice", "cloudfoundry", "contour-ingressroute", "fake", "connector", "crd", "empty", "skipper-routegroup", "openshift-route", "gateway-httproute", "gateway-tlsroute", "gateway-tcproute")
 
-	app.Flag("namespace", "Limit sources of endpoints to a specific namespace (default: all namespaces)").Default(defaultConfig.Namespace).StringVar(&cfg.Namespace)
+	app.Flag("namespace", "Limit sources of endpoints to a specific namespace; specify multiple times for multiple namespaces (default: all namespaces)").StringsVar(&cfg.Namespaces)
+	app.Flag("namespace-selector", "Limit sources of endpoints to the namespaces matching this label selector, along with --namespace if given (default: all namespaces)").Default(defaultConfig.NamespaceSelector).StringVar(&cfg.NamespaceSelector)
 	app.Flag("annotation-filter", "Filter sources managed by external-dns via annotation using label selector semantics (default: all sources)").Default(defaultConfig.AnnotationFilter).StringVar(&cfg.AnnotationFilter)
+	app.Flag("label-filter", "Filter sources managed by external-dns via label selector, applied by the Kubernetes API server when listing and watching the resources (default: all sources)").Default(defaultConfig.LabelFilter).StringVar(&cfg.LabelFilter)
+	app.Flag("ingress-class", "Require an ingress to have this class, given by its spec.ingressClassName, its kubernetes.io/ingress.class annotation or the default IngressClass; specify multiple times for multiple classes (optional, default: all ingresses)").StringsVar(&cfg.IngressClassNames)
 	app.Flag("fqdn-template", "A templated string that's used to generate DNS names from sources that don't define a hostname themselves, or to add a hostname suffix when paired with the fake source (optional). Accepts comma separated list for multiple global FQDN.").Default(defaultConfig.FQDNTemplate).StringVar(&cfg.FQDNTemplate)
 	app.Flag("combine-fqdn-annotation", "Combine FQDN template and Annotations instead of overwriting").BoolVar(&cfg.CombineFQDNAndAnnotation)
 	app.Flag("ignore-hostname-annotation", "Ignore hostname annotation when generating DNS names, valid only when using fqdn-template is set (optional, default: false)").BoolVar(&cfg.IgnoreHostnameAnnotation)
@@ -395,19 +456,47 @@ func (cfg *Config) ParseFlags(args []string) error {
 
 	// Flags related to policies
 	app.Flag("policy", "Modify how DNS records are synchronized between sources and providers (default: sync, options: sync, upsert-only, create-only)").Default(defaultConfig.Policy).EnumVar(&cfg.Policy, "sync", "upsert-only", "create-only")
+	app.Flag("conflict-resolver", "Modify how a DNS name requested by several resources is resolved (default: per-resource, options: per-resource, merge-targets, priority)").Default(defaultConfig.ConflictResolver).EnumVar(&cfg.ConflictResolver, "per-resource", "merge-targets", "priority")
+	app.Flag("deletion-guard-max-deletes", "Refuse to apply changes deleting more than this number of records at once (default: 0, disabled)").Default(strconv.Itoa(defaultConfig.DeletionGuardMaxDeletes)).IntVar(&cfg.DeletionGuardMaxDeletes)
+	app.Flag("deletion-guard-max-percentage", "Refuse to apply changes deleting more than this percentage of the owned records at once (default: 0, disabled)").Default(strconv.Itoa(defaultConfig.DeletionGuardMaxPercentage)).IntVar(&cfg.DeletionGuardMaxPercentage)
+	app.Flag("deletion-guard-override", "When enabled, applies changes regardless of the deletion guard thresholds (default: disabled)").BoolVar(&cfg.DeletionGuardOverride)
 
 	// Flags related to the registry
-	app.Flag("registry", "The registry implementation to use to keep track of DNS record ownership (default: txt, options: txt, noop, aws-sd)").Default(defaultConfig.Registry).EnumVar(&cfg.Registry, "txt", "noop", "aws-sd")
-	app.Flag("txt-owner-id", "When using the TXT registry, a name that identifies this instance of ExternalDNS (default: default)").Default(defaultConfig.TXTOwnerID).StringVar(&cfg.TXTOwnerID)
+	app.Flag("registry", "The registry implementation to use to keep track of DNS record ownership (default: txt, options: txt, noop, aws-sd, crd, file)").Default(defaultConfig.Registry).EnumVar(&cfg.Registry, "txt", "noop", "aws-sd", "crd", "file")
+	app.Flag("txt-owner-id", "When using the TXT or CRD registry, a name that identifies this instance of ExternalDNS (default: default)").Default(defaultConfig.TXTOwnerID).StringVar(&cfg.TXTOwnerID)
+	app.Flag("txt-previous-owner-id", "When using the TXT registry, a name that identified a previous instance of ExternalDNS whose records are adopted by this one, e.g. when replacing a cluster; specify multiple times for multiple previous owners (optional)").StringsVar(&cfg.TXTPreviousOwnerIDs)
 	app.Flag("txt-prefix", "When using the TXT registry, a custom string that's prefixed to each ownership DNS record (optional). Mutual exclusive with txt-suffix!").Default(defaultConfig.TXTPrefix).StringVar(&cfg.TXTPrefix)
 	app.Flag("txt-suffix", "When using the TXT registry, a custom string that's suffixed to the host portion of each ownership DNS record (optional). Mutual exclusive with txt-prefix!").Default(defaultConfig.TXTSuffix).StringVar(&cfg.TXTSuffix)
+	app.Flag("txt-name-template", "When using the TXT registry, a template for the names of the ownership DNS records with the fields .RecordType, .Label and .Zone, e.g. {{.RecordType}}-{{.Label}}.{{.Zone}}; the apex and wildcard labels are replaced by _apex and _wildcard (optional). Mutual exclusive with txt-prefix and txt-suffix!").Default(defaultConfig.TXTNameTemplate).StringVar(&cfg.TXTNameTemplate)
 
 	// Flags related to the main control loop
 	app.Flag("txt-cache-interval", "The interval between cache synchronizations in duration format (default: disabled)").Default(defaultConfig.TXTCacheInterval.String()).DurationVar(&cfg.TXTCacheInterval)
+	app.Flag("txt-key-file", "When using the TXT registry, a file containing a base64 encoded 32 bytes key signing or encrypting the ownership records; specify multiple times to rotate keys, the first key protects the records and all of them are accepted (optional)").StringsVar(&cfg.TXTKeyFiles)
+	app.Flag("txt-encrypt", "When using the TXT registry with txt-key-file, encrypt the labels of the ownership records instead of only signing them (default: disabled)").BoolVar(&cfg.TXTEncrypt)
+	app.Flag("txt-allow-unprotected", "When using the TXT registry with txt-key-file, accept the ownership records which are neither signed nor encrypted and protect them, required to migrate existing records (default: disabled)").BoolVar(&cfg.TXTAllowUnprotected)
+	app.Flag("txt-shared-records", "When using the TXT registry, share the A and AAAA records with the other owners sharing them, each of them contributing its own targets; a shared record is deleted once its last owner leaves (default: disabled)").BoolVar(&cfg.TXTSharedRecords)
+	app.Flag("file-registry-path", "When using the file registry, the path of the local file storing the ownership records; it's created if needed and may be shared by several instances on the same host (required when --registry=file)").Default(defaultConfig.FileRegistryPath).StringVar(&cfg.FileRegistryPath)
 	app.Flag("interval", "The interval between two consecutive synchronizations in duration format (default: 1m)").Default(defaultConfig.Interval.String()).DurationVar(&cfg.Interval)
+	app.Flag("retry-backoff-base", "The delay before retrying a failed synchronization, doubled on every consecutive failure; transient errors are retried no later than the interval, rate limiting errors back off starting at the interval; 0 disables retries (default: disabled)").Default(defaultConfig.RetryBackoffBase.String()).DurationVar(&cfg.RetryBackoffBase)
+	app.Flag("retry-backoff-max", "The maximum delay before retrying a failed synchronization (default: 10m)").Default(defaultConfig.RetryBackoffMax.String()).DurationVar(&cfg.RetryBackoffMax)
 	app.Flag("once", "When enabled, exits the synchronization loop after the first iteration (default: disabled)").BoolVar(&cfg.Once)
 	app.Flag("dry-run", "When enabled, prints DNS record changes rather than actually performing them (default: disabled)").BoolVar(&cfg.DryRun)
+	app.Flag("plan-output-format", "When set, writes the changes sent to the DNS provider in every synchronization in this format (optional, options: json, yaml)").Default(defaultConfig.PlanOutputFormat).EnumVar(&cfg.PlanOutputFormat, "", "json", "yaml")
+	app.Flag("plan-output-file", "When using --plan-output-format, the file to write the changes to (default: stdout)").Default(defaultConfig.PlanOutputFile).StringVar(&cfg.PlanOutputFile)
+	app.Flag("check-registry", "When enabled, classifies every record of the provider as owned, foreign, unowned, orphaned or inconsistent according to the registry, prints a report to stdout and exits without applying any change (default: disabled)").BoolVar(&cfg.CheckRegistry)
+	app.Flag("check-registry-format", "When using --check-registry, the format of the report (default: table, options: table, json)").Default(defaultConfig.CheckRegistryFormat).EnumVar(&cfg.CheckRegistryFormat, "table", "json")
 	app.Flag("events", "When enabled, in addition to running every interval, the reconciliation loop will get triggered when supported sources change (default: disabled)").BoolVar(&cfg.UpdateEvents)
+	app.Flag("events-poll-interval", "With --events, the interval at which the sources which can't watch their objects, i.e. Cloud Foundry and Skipper route groups, poll them for changes (default: 15s)").Default(defaultConfig.EventsPollInterval.String()).DurationVar(&cfg.EventsPollInterval)
+	app.Flag("kube-events", "When enabled, records Kubernetes Events on the source objects when their DNS records change, ignored with --dry-run (default: disabled)").BoolVar(&cfg.KubeEvents)
+	app.Flag("health-max-missed-intervals", "The number of intervals without a successful synchronization after which /readyz reports a failure, and of a running synchronization after which /healthz reports a failure, 0 disables the checks (default: 3)").Default(strconv.Itoa(defaultConfig.HealthMaxMissedIntervals)).IntVar(&cfg.HealthMaxMissedIntervals)
+
+	// Flags related to leader election
+	app.Flag("leader-election", "When enabled, only the replica holding the leader election lease synchronizes DNS records, ignored with --once (default: disabled)").BoolVar(&cfg.LeaderElection)
+	app.Flag("leader-election-namespace", "The namespace of the leader election lease (default: default)").Default(defaultConfig.LeaderElectionNamespace).StringVar(&cfg.LeaderElectionNamespace)
+	app.Flag("leader-election-lease-name", "The name of the leader election lease (default: external-dns)").Default(defaultConfig.LeaderElectionLeaseName).StringVar(&cfg.LeaderElectionLeaseName)
+	app.Flag("leader-election-lease-duration", "The duration standby replicas wait before trying to acquire the leader election lease (default: 15s)").Default(defaultConfig.LeaderElectionLeaseDuration.String()).DurationVar(&cfg.LeaderElectionLeaseDuration)
+	app.Flag("leader-election-renew-deadline", "The duration the leader retries renewing the leader election lease before giving it up (default: 10s)").Default(defaultConfig.LeaderElectionRenewDeadline.String()).DurationVar(&cfg.LeaderElectionRenewDeadline)
+	app.Flag("leader-election-retry-period", "The duration between tries of acquiring or renewing the leader election lease (default: 2s)").Default(defaultConfig.LeaderElectionRetryPeriod.String()).DurationVar(&cfg.LeaderElectionRetryPeriod)
 
 	// Miscellaneous flags
 	app.Flag("log-format", "The format in which log messages are printed (default: text, options: text, json)").Default(defaultConfig.LogFormat).EnumVar(&cfg.LogFormat, "text", "json")
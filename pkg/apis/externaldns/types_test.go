@@ -35,7 +35,7 @@ var (
 		ContourLoadBalancerService: "heptio-contour/contour",
 		SkipperRouteGroupVersion:   "zalando.org/v1",
 		Sources:                    []string{"service"},
-		Namespace:                  "",
+		Namespaces:                 nil,
 		FQDNTemplate:               "",
 		Compatibility:              "",
 		Provider:                   "google",
@@ -79,14 +79,38 @@ var (
 		PDNSServer:                  "http://localhost:8081",
 		PDNSAPIKey:                  "",
 		Policy:                      "sync",
+		ConflictResolver:            "per-resource",
+		DeletionGuardMaxDeletes:     0,
+		DeletionGuardMaxPercentage:  0,
+		DeletionGuardOverride:       false,
 		Registry:                    "txt",
 		TXTOwnerID:                  "default",
 		TXTPrefix:                   "",
+		TXTNameTemplate:             "",
 		TXTCacheInterval:            0,
+		TXTEncrypt:                  false,
+		TXTAllowUnprotected:         false,
+		TXTSharedRecords:            false,
+		FileRegistryPath:            "",
 		Interval:                    time.Minute,
+		RetryBackoffBase:            0,
+		RetryBackoffMax:             10 * time.Minute,
 		Once:                        false,
 		DryRun:                      false,
+		PlanOutputFormat:            "",
+		PlanOutputFile:              "",
+		CheckRegistry:               false,
+		CheckRegistryFormat:         "table",
 		UpdateEvents:                false,
+		EventsPollInterval:          15 * time.Second,
+		KubeEvents:                  false,
+		HealthMaxMissedIntervals:    3,
+		LeaderElection:              false,
+		LeaderElectionNamespace:     "default",
+		LeaderElectionLeaseName:     "external-dns",
+		LeaderElectionLeaseDuration: 15 * time.Second,
+		LeaderElectionRenewDeadline: 10 * time.Second,
+		LeaderElectionRetryPeriod:   2 * time.Second,
 		LogFormat:                   "text",
 		MetricsAddress:              ":7979",
 		LogLevel:                    logrus.InfoLevel.String(),
@@ -109,8 +133,11 @@ var (
 		ContourLoadBalancerService: "heptio-contour-other/contour-other",
 		SkipperRouteGroupVersion:   "zalando.org/v2",
 		Sources:                    []string{"service", "ingress", "connector"},
-		Namespace:                  "namespace",
+		Namespaces:                 []string{"namespace", "other"},
+		NamespaceSelector:          "team=dns",
 		IgnoreHostnameAnnotation:   true,
+		LabelFilter:                "app=external-dns",
+		IngressClassNames:          []string{"nginx", "internal"},
 		FQDNTemplate:               "{{.Name}}.service.example.com",
 		Compatibility:              "mate",
 		Provider:                   "google",
@@ -158,14 +185,40 @@ var (
 		TLSClientCert:               "/path/to/cert.pem",
 		TLSClientCertKey:            "/path/to/key.pem",
 		Policy:                      "upsert-only",
+		ConflictResolver:            "priority",
+		DeletionGuardMaxDeletes:     100,
+		DeletionGuardMaxPercentage:  20,
+		DeletionGuardOverride:       true,
 		Registry:                    "noop",
 		TXTOwnerID:                  "owner-1",
+		TXTPreviousOwnerIDs:         []string{"owner-0", "owner-blue"},
 		TXTPrefix:                   "associated-txt-record",
+		TXTNameTemplate:             "{{.RecordType}}-{{.Label}}.{{.Zone}}",
 		TXTCacheInterval:            12 * time.Hour,
+		TXTKeyFiles:                 []string{"/keys/current", "/keys/previous"},
+		TXTEncrypt:                  true,
+		TXTAllowUnprotected:         true,
+		TXTSharedRecords:            true,
+		FileRegistryPath:            "/var/lib/external-dns/ownership.json",
 		Interval:                    10 * time.Minute,
+		RetryBackoffBase:            30 * time.Second,
+		RetryBackoffMax:             time.Hour,
 		Once:                        true,
 		DryRun:                      true,
+		PlanOutputFormat:            "json",
+		PlanOutputFile:              "/tmp/plan.json",
+		CheckRegistry:               true,
+		CheckRegistryFormat:         "json",
 		UpdateEvents:                true,
+		EventsPollInterval:          30 * time.Second,
+		KubeEvents:                  true,
+		HealthMaxMissedIntervals:    5,
+		LeaderElection:              true,
+		LeaderElectionNamespace:     "kube-system",
+		LeaderElectionLeaseName:     "external-dns-public",
+		LeaderElectionLeaseDuration: 30 * time.Second,
+		LeaderElectionRenewDeadline: 20 * time.Second,
+		LeaderElectionRetryPeriod:   5 * time.Second,
 		LogFormat:                   "json",
 		MetricsAddress:              "127.0.0.1:9099",
 		LogLevel:                    logrus.DebugLevel.String(),
@@ -212,8 +265,13 @@ func TestParseFlags(t *testing.T) {
 				"--source=ingress",
 				"--source=connector",
 				"--namespace=namespace",
+				"--namespace=other",
+				"--namespace-selector=team=dns",
 				"--fqdn-template={{.Name}}.service.example.com",
 				"--ignore-hostname-annotation",
+				"--label-filter=app=external-dns",
+				"--ingress-class=nginx",
+				"--ingress-class=internal",
 				"--compatibility=mate",
 				"--provider=google",
 				"--google-project=project",
@@ -263,14 +321,42 @@ func TestParseFlags(t *testing.T) {
 				"--aws-prefer-cname",
 				"--no-aws-evaluate-target-health",
 				"--policy=upsert-only",
+				"--conflict-resolver=priority",
+				"--deletion-guard-max-deletes=100",
+				"--deletion-guard-max-percentage=20",
+				"--deletion-guard-override",
 				"--registry=noop",
 				"--txt-owner-id=owner-1",
+				"--txt-previous-owner-id=owner-0",
+				"--txt-previous-owner-id=owner-blue",
 				"--txt-prefix=associated-txt-record",
+				"--txt-name-template={{.RecordType}}-{{.Label}}.{{.Zone}}",
 				"--txt-cache-interval=12h",
+				"--txt-key-file=/keys/current",
+				"--txt-key-file=/keys/previous",
+				"--txt-encrypt",
+				"--txt-allow-unprotected",
+				"--txt-shared-records",
+				"--file-registry-path=/var/lib/external-dns/ownership.json",
 				"--interval=10m",
+				"--retry-backoff-base=30s",
+				"--retry-backoff-max=1h",
 				"--once",
 				"--dry-run",
+				"--plan-output-format=json",
+				"--plan-output-file=/tmp/plan.json",
+				"--check-registry",
+				"--check-registry-format=json",
 				"--events",
+				"--events-poll-interval=30s",
+				"--kube-events",
+				"--health-max-missed-intervals=5",
+				"--leader-election",
+				"--leader-election-namespace=kube-system",
+				"--leader-election-lease-name=external-dns-public",
+				"--leader-election-lease-duration=30s",
+				"--leader-election-renew-deadline=20s",
+				"--leader-election-retry-period=5s",
 				"--log-format=json",
 				"--metrics-address=127.0.0.1:9099",
 				"--log-level=debug",
@@ -300,9 +386,12 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_CONTOUR_LOAD_BALANCER":           "heptio-contour-other/contour-other",
 				"EXTERNAL_DNS_SKIPPER_ROUTEGROUP_GROUPVERSION": "zalando.org/v2",
 				"EXTERNAL_DNS_SOURCE":                          "service\ningress\nconnector",
-				"EXTERNAL_DNS_NAMESPACE":                       "namespace",
+				"EXTERNAL_DNS_NAMESPACE":                       "namespace\nother",
+				"EXTERNAL_DNS_NAMESPACE_SELECTOR":              "team=dns",
 				"EXTERNAL_DNS_FQDN_TEMPLATE":                   "{{.Name}}.service.example.com",
 				"EXTERNAL_DNS_IGNORE_HOSTNAME_ANNOTATION":      "1",
+				"EXTERNAL_DNS_LABEL_FILTER":                    "app=external-dns",
+				"EXTERNAL_DNS_INGRESS_CLASS":                   "nginx\ninternal",
 				"EXTERNAL_DNS_COMPATIBILITY":                   "mate",
 				"EXTERNAL_DNS_PROVIDER":                        "google",
 				"EXTERNAL_DNS_GOOGLE_PROJECT":                  "project",
@@ -349,14 +438,40 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_AWS_API_RETRIES":                 "13",
 				"EXTERNAL_DNS_AWS_PREFER_CNAME":                "true",
 				"EXTERNAL_DNS_POLICY":                          "upsert-only",
+				"EXTERNAL_DNS_CONFLICT_RESOLVER":               "priority",
+				"EXTERNAL_DNS_DELETION_GUARD_MAX_DELETES":      "100",
+				"EXTERNAL_DNS_DELETION_GUARD_MAX_PERCENTAGE":   "20",
+				"EXTERNAL_DNS_DELETION_GUARD_OVERRIDE":         "1",
 				"EXTERNAL_DNS_REGISTRY":                        "noop",
 				"EXTERNAL_DNS_TXT_OWNER_ID":                    "owner-1",
+				"EXTERNAL_DNS_TXT_PREVIOUS_OWNER_ID":           "owner-0\nowner-blue",
 				"EXTERNAL_DNS_TXT_PREFIX":                      "associated-txt-record",
+				"EXTERNAL_DNS_TXT_NAME_TEMPLATE":               "{{.RecordType}}-{{.Label}}.{{.Zone}}",
 				"EXTERNAL_DNS_TXT_CACHE_INTERVAL":              "12h",
+				"EXTERNAL_DNS_TXT_KEY_FILE":                    "/keys/current\n/keys/previous",
+				"EXTERNAL_DNS_TXT_ENCRYPT":                     "1",
+				"EXTERNAL_DNS_TXT_ALLOW_UNPROTECTED":           "1",
+				"EXTERNAL_DNS_TXT_SHARED_RECORDS":              "1",
+				"EXTERNAL_DNS_FILE_REGISTRY_PATH":              "/var/lib/external-dns/ownership.json",
 				"EXTERNAL_DNS_INTERVAL":                        "10m",
+				"EXTERNAL_DNS_RETRY_BACKOFF_BASE":              "30s",
+				"EXTERNAL_DNS_RETRY_BACKOFF_MAX":               "1h",
 				"EXTERNAL_DNS_ONCE":                            "1",
 				"EXTERNAL_DNS_DRY_RUN":                         "1",
+				"EXTERNAL_DNS_PLAN_OUTPUT_FORMAT":              "json",
+				"EXTERNAL_DNS_PLAN_OUTPUT_FILE":                "/tmp/plan.json",
+				"EXTERNAL_DNS_CHECK_REGISTRY":                 "1",
+				"EXTERNAL_DNS_CHECK_REGISTRY_FORMAT":          "json",
 				"EXTERNAL_DNS_EVENTS":                          "1",
+				"EXTERNAL_DNS_EVENTS_POLL_INTERVAL":            "30s",
+				"EXTERNAL_DNS_KUBE_EVENTS":                     "1",
+				"EXTERNAL_DNS_HEALTH_MAX_MISSED_INTERVALS":     "5",
+				"EXTERNAL_DNS_LEADER_ELECTION":                 "1",
+				"EXTERNAL_DNS_LEADER_ELECTION_NAMESPACE":       "kube-system",
+				"EXTERNAL_DNS_LEADER_ELECTION_LEASE_NAME":      "external-dns-public",
+				"EXTERNAL_DNS_LEADER_ELECTION_LEASE_DURATION":  "30s",
+				"EXTERNAL_DNS_LEADER_ELECTION_RENEW_DEADLINE":  "20s",
+				"EXTERNAL_DNS_LEADER_ELECTION_RETRY_PERIOD":    "5s",
 				"EXTERNAL_DNS_LOG_FORMAT":                      "json",
 				"EXTERNAL_DNS_METRICS_ADDRESS":                 "127.0.0.1:9099",
 				"EXTERNAL_DNS_LOG_LEVEL":                       "debug",
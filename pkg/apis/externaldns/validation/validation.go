@@ -20,6 +20,8 @@ import (
 	"errors"
 	"fmt"
 
+	"k8s.io/apimachinery/pkg/labels"
+
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
 )
 
@@ -92,9 +94,55 @@ func ValidateConfig(cfg *externaldns.Config) error {
 		return errors.New("FQDN Template must be set if ignoring annotations")
 	}
 
+	if cfg.DeletionGuardMaxDeletes < 0 {
+		return errors.New("deletion guard max deletes is negative")
+	}
+
+	if cfg.DeletionGuardMaxPercentage < 0 || cfg.DeletionGuardMaxPercentage > 100 {
+		return errors.New("deletion guard max percentage must be between 0 and 100")
+	}
+
+	if cfg.RetryBackoffBase < 0 || cfg.RetryBackoffMax < 0 {
+		return errors.New("retry backoff is negative")
+	}
+
+	if cfg.UpdateEvents && cfg.EventsPollInterval <= 0 {
+		return errors.New("events poll interval must be positive")
+	}
+
+	if cfg.HealthMaxMissedIntervals < 0 {
+		return errors.New("health max missed intervals is negative")
+	}
+
+	if (cfg.TXTEncrypt || cfg.TXTAllowUnprotected) && len(cfg.TXTKeyFiles) == 0 {
+		return errors.New("txt-encrypt and txt-allow-unprotected require txt-key-file")
+	}
+
+	for _, previous := range cfg.TXTPreviousOwnerIDs {
+		if previous == "" || previous == cfg.TXTOwnerID {
+			return errors.New("txt-previous-owner-id must be neither empty nor the txt-owner-id")
+		}
+	}
+
 	if len(cfg.TXTPrefix) > 0 && len(cfg.TXTSuffix) > 0 {
 		return errors.New("txt-prefix and txt-suffix are mutual exclusive")
 	}
 
+	if len(cfg.TXTNameTemplate) > 0 && (len(cfg.TXTPrefix) > 0 || len(cfg.TXTSuffix) > 0) {
+		return errors.New("txt-name-template is mutual exclusive with txt-prefix and txt-suffix")
+	}
+
+	if cfg.Registry == "file" && cfg.FileRegistryPath == "" {
+		return errors.New("file-registry-path must be set when using the file registry")
+	}
+
+	if _, err := labels.Parse(cfg.LabelFilter); err != nil {
+		return fmt.Errorf("invalid label filter: %v", err)
+	}
+
+	if _, err := labels.Parse(cfg.NamespaceSelector); err != nil {
+		return fmt.Errorf("invalid namespace selector: %v", err)
+	}
+
 	return nil
 }
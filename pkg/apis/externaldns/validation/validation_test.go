@@ -18,6 +18,7 @@ package validation
 
 import (
 	"testing"
+	"time"
 
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
 
@@ -150,3 +151,213 @@ func TestValidateGoodRfc2136Config(t *testing.T) {
 
 	assert.Nil(t, err)
 }
+
+func TestValidateBadDeletionGuardConfig(t *testing.T) {
+	for _, cfg := range []*externaldns.Config{
+		{DeletionGuardMaxDeletes: -1},
+		{DeletionGuardMaxPercentage: -1},
+		{DeletionGuardMaxPercentage: 101},
+	} {
+		cfg.LogFormat = "json"
+		cfg.Sources = []string{"test-source"}
+		cfg.Provider = "test-provider"
+
+		assert.Error(t, ValidateConfig(cfg))
+	}
+}
+
+func TestValidateGoodDeletionGuardConfig(t *testing.T) {
+	cfg := externaldns.NewConfig()
+
+	cfg.LogFormat = "json"
+	cfg.Sources = []string{"test-source"}
+	cfg.Provider = "test-provider"
+	cfg.DeletionGuardMaxDeletes = 100
+	cfg.DeletionGuardMaxPercentage = 20
+
+	assert.Nil(t, ValidateConfig(cfg))
+}
+
+func TestValidateBadRetryBackoffConfig(t *testing.T) {
+	for _, cfg := range []*externaldns.Config{
+		{RetryBackoffBase: -time.Second},
+		{RetryBackoffMax: -time.Second},
+	} {
+		cfg.LogFormat = "json"
+		cfg.Sources = []string{"test-source"}
+		cfg.Provider = "test-provider"
+
+		assert.Error(t, ValidateConfig(cfg))
+	}
+}
+
+func TestValidateBadHealthConfig(t *testing.T) {
+	cfg := externaldns.NewConfig()
+
+	cfg.LogFormat = "json"
+	cfg.Sources = []string{"test-source"}
+	cfg.Provider = "test-provider"
+	cfg.HealthMaxMissedIntervals = -1
+
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestValidateBadEventsPollIntervalConfig(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		cfg := externaldns.NewConfig()
+
+		cfg.LogFormat = "json"
+		cfg.Sources = []string{"test-source"}
+		cfg.Provider = "test-provider"
+		cfg.UpdateEvents = true
+		cfg.EventsPollInterval = interval
+
+		assert.Error(t, ValidateConfig(cfg))
+	}
+}
+
+func TestValidateBadTXTKeyConfig(t *testing.T) {
+	for _, cfg := range []*externaldns.Config{
+		{TXTEncrypt: true},
+		{TXTAllowUnprotected: true},
+	} {
+		cfg.LogFormat = "json"
+		cfg.Sources = []string{"test-source"}
+		cfg.Provider = "test-provider"
+
+		assert.Error(t, ValidateConfig(cfg))
+	}
+}
+
+func TestValidateGoodTXTKeyConfig(t *testing.T) {
+	cfg := externaldns.NewConfig()
+
+	cfg.LogFormat = "json"
+	cfg.Sources = []string{"test-source"}
+	cfg.Provider = "test-provider"
+	cfg.TXTKeyFiles = []string{"/keys/current"}
+	cfg.TXTEncrypt = true
+
+	assert.Nil(t, ValidateConfig(cfg))
+}
+
+func TestValidateTXTPreviousOwnerIDs(t *testing.T) {
+	for _, tc := range []struct {
+		previousOwnerIDs []string
+		valid            bool
+	}{
+		{previousOwnerIDs: []string{"owner-0", "owner-blue"}, valid: true},
+		{previousOwnerIDs: []string{""}, valid: false},
+		{previousOwnerIDs: []string{"owner-0", "default"}, valid: false},
+	} {
+		cfg := externaldns.NewConfig()
+		cfg.LogFormat = "json"
+		cfg.Sources = []string{"test-source"}
+		cfg.Provider = "test-provider"
+		cfg.TXTOwnerID = "default"
+		cfg.TXTPreviousOwnerIDs = tc.previousOwnerIDs
+
+		if tc.valid {
+			assert.Nil(t, ValidateConfig(cfg))
+		} else {
+			assert.Error(t, ValidateConfig(cfg))
+		}
+	}
+}
+
+func TestValidateTXTNameTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		prefix string
+		suffix string
+		valid  bool
+	}{
+		{valid: true},
+		{prefix: "txt-", valid: false},
+		{suffix: "-txt", valid: false},
+	} {
+		cfg := externaldns.NewConfig()
+		cfg.LogFormat = "json"
+		cfg.Sources = []string{"test-source"}
+		cfg.Provider = "test-provider"
+		cfg.TXTNameTemplate = "{{.RecordType}}-{{.Label}}.{{.Zone}}"
+		cfg.TXTPrefix = tc.prefix
+		cfg.TXTSuffix = tc.suffix
+
+		if tc.valid {
+			assert.Nil(t, ValidateConfig(cfg))
+		} else {
+			assert.Error(t, ValidateConfig(cfg))
+		}
+	}
+}
+
+func TestValidateFileRegistry(t *testing.T) {
+	for _, tc := range []struct {
+		registry string
+		path     string
+		valid    bool
+	}{
+		{registry: "txt", valid: true},
+		{registry: "file", path: "/var/lib/external-dns/ownership.json", valid: true},
+		{registry: "file", valid: false},
+	} {
+		cfg := externaldns.NewConfig()
+		cfg.LogFormat = "json"
+		cfg.Sources = []string{"test-source"}
+		cfg.Provider = "test-provider"
+		cfg.Registry = tc.registry
+		cfg.FileRegistryPath = tc.path
+
+		if tc.valid {
+			assert.Nil(t, ValidateConfig(cfg))
+		} else {
+			assert.Error(t, ValidateConfig(cfg))
+		}
+	}
+}
+
+func TestValidateLabelFilter(t *testing.T) {
+	for _, tc := range []struct {
+		labelFilter string
+		valid       bool
+	}{
+		{valid: true},
+		{labelFilter: "app=external-dns,tier in (public, dmz)", valid: true},
+		{labelFilter: "app in external-dns", valid: false},
+	} {
+		cfg := externaldns.NewConfig()
+		cfg.LogFormat = "json"
+		cfg.Sources = []string{"test-source"}
+		cfg.Provider = "test-provider"
+		cfg.LabelFilter = tc.labelFilter
+
+		if tc.valid {
+			assert.Nil(t, ValidateConfig(cfg))
+		} else {
+			assert.Error(t, ValidateConfig(cfg))
+		}
+	}
+}
+
+func TestValidateNamespaceSelector(t *testing.T) {
+	for _, tc := range []struct {
+		namespaceSelector string
+		valid             bool
+	}{
+		{valid: true},
+		{namespaceSelector: "team=dns,environment!=test", valid: true},
+		{namespaceSelector: "team in dns", valid: false},
+	} {
+		cfg := externaldns.NewConfig()
+		cfg.LogFormat = "json"
+		cfg.Sources = []string{"test-source"}
+		cfg.Provider = "test-provider"
+		cfg.NamespaceSelector = tc.namespaceSelector
+
+		if tc.valid {
+			assert.Nil(t, ValidateConfig(cfg))
+		} else {
+			assert.Error(t, ValidateConfig(cfg))
+		}
+	}
+}
@@ -18,6 +18,7 @@ package plan
 
 import (
 	"sort"
+	"strconv"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
@@ -29,6 +30,13 @@ type ConflictResolver interface {
 	ResolveUpdate(current *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint
 }
 
+// ConflictResolvers is a registry of available conflict resolvers.
+var ConflictResolvers = map[string]ConflictResolver{
+	"per-resource":  PerResource{},
+	"merge-targets": MergeTargets{},
+	"priority":      Priority{},
+}
+
 // PerResource allows only one resource to own a given dns name
 type PerResource struct{}
 
@@ -67,4 +75,85 @@ func (s PerResource) less(x, y *endpoint.Endpoint) bool {
 	return x.Targets.IsLess(y.Targets)
 }
 
-// TODO: with cross-resource/cross-cluster setup alternative variations of ConflictResolver can be used
+// MergeTargets lets all resources requesting a given dns name share it by publishing the union of their targets
+type MergeTargets struct{}
+
+// ResolveCreate is invoked when dns name is not owned by any resource
+// ResolveCreate takes the endpoint PerResource would pick and extends its targets with those of the other candidates
+func (s MergeTargets) ResolveCreate(candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	return s.merge(PerResource{}.ResolveCreate(candidates), candidates)
+}
+
+// ResolveUpdate is invoked when dns name is already owned by "current" endpoint
+// ResolveUpdate takes the endpoint PerResource would pick and extends its targets with those of the other candidates
+func (s MergeTargets) ResolveUpdate(current *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	return s.merge(PerResource{}.ResolveUpdate(current, candidates), candidates)
+}
+
+// merge returns a copy of base carrying the targets of all candidates of the same record type.
+// CNAME records cannot hold more than one target, hence base is returned as is for them.
+func (s MergeTargets) merge(base *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	if base == nil || base.RecordType == endpoint.RecordTypeCNAME {
+		return base
+	}
+	seen := map[string]bool{}
+	targets := endpoint.Targets{}
+	for _, ep := range candidates {
+		if ep.RecordType != base.RecordType {
+			continue
+		}
+		for _, target := range ep.Targets {
+			if !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	sort.Sort(targets)
+
+	merged := base.DeepCopy()
+	merged.Targets = targets
+	return merged
+}
+
+// Priority allows the resource with the highest priority to own a given dns name
+// Priority is read from endpoint.PriorityLabelKey, endpoints without a valid priority have priority 0
+type Priority struct{}
+
+// ResolveCreate is invoked when dns name is not owned by any resource
+// ResolveCreate falls back to PerResource among the candidates sharing the highest priority
+func (s Priority) ResolveCreate(candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	return PerResource{}.ResolveCreate(s.highest(candidates))
+}
+
+// ResolveUpdate is invoked when dns name is already owned by "current" endpoint
+// ResolveUpdate keeps the current resource only as long as no other resource has a higher priority
+func (s Priority) ResolveUpdate(current *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	return PerResource{}.ResolveUpdate(current, s.highest(candidates))
+}
+
+// highest returns the candidates sharing the highest priority
+func (s Priority) highest(candidates []*endpoint.Endpoint) []*endpoint.Endpoint {
+	var result []*endpoint.Endpoint
+	var max int
+	for _, ep := range candidates {
+		p := s.priority(ep)
+		switch {
+		case len(result) == 0 || p > max:
+			max = p
+			result = []*endpoint.Endpoint{ep}
+		case p == max:
+			result = append(result, ep)
+		}
+	}
+	return result
+}
+
+// priority returns the priority of an endpoint
+func (s Priority) priority(ep *endpoint.Endpoint) int {
+	p, err := strconv.Atoi(ep.Labels[endpoint.PriorityLabelKey])
+	if err != nil {
+		return 0
+	}
+	return p
+}
@@ -25,10 +25,14 @@ import (
 )
 
 var _ ConflictResolver = PerResource{}
+var _ ConflictResolver = MergeTargets{}
+var _ ConflictResolver = Priority{}
 
 type ResolverSuite struct {
 	// resolvers
-	perResource PerResource
+	perResource  PerResource
+	mergeTargets MergeTargets
+	priority     Priority
 	// endpoints
 	fooV1Cname          *endpoint.Endpoint
 	fooV2Cname          *endpoint.Endpoint
@@ -38,11 +42,16 @@ type ResolverSuite struct {
 	bar192A             *endpoint.Endpoint
 	bar127AAnother      *endpoint.Endpoint
 	legacyBar192A       *endpoint.Endpoint // record created in AWS now without resource label
+	bar192APriority10   *endpoint.Endpoint
+	bar8APriority10     *endpoint.Endpoint
+	bar127APriorityBad  *endpoint.Endpoint
 	suite.Suite
 }
 
 func (suite *ResolverSuite) SetupTest() {
 	suite.perResource = PerResource{}
+	suite.mergeTargets = MergeTargets{}
+	suite.priority = Priority{}
 	// initialize endpoints used in tests
 	suite.fooV1Cname = &endpoint.Endpoint{
 		DNSName:    "foo",
@@ -105,6 +114,33 @@ func (suite *ResolverSuite) SetupTest() {
 		Targets:    endpoint.Targets{"192.168.0.1"},
 		RecordType: "A",
 	}
+	suite.bar192APriority10 = &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"192.168.0.1"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/bar-192",
+			endpoint.PriorityLabelKey: "10",
+		},
+	}
+	suite.bar8APriority10 = &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"8.8.8.8"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/bar-8",
+			endpoint.PriorityLabelKey: "10",
+		},
+	}
+	suite.bar127APriorityBad = &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"127.0.0.1"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/bar-127",
+			endpoint.PriorityLabelKey: "high",
+		},
+	}
 }
 
 func (suite *ResolverSuite) TestStrictResolver() {
@@ -133,6 +169,35 @@ func (suite *ResolverSuite) TestStrictResolver() {
 	suite.Equal(suite.bar127A, suite.perResource.ResolveUpdate(suite.legacyBar192A, []*endpoint.Endpoint{suite.bar127A, suite.bar192A}), " legacy record's resource value will not match, should pick minimum")
 }
 
+func (suite *ResolverSuite) TestMergeTargetsResolver() {
+	// test that mergeTargets resolver unions the targets of all candidates
+	merged := suite.mergeTargets.ResolveCreate([]*endpoint.Endpoint{suite.bar192A, suite.bar127A, suite.bar127AAnother})
+	suite.Equal(endpoint.Targets{"127.0.0.1", "192.168.0.1", "8.8.8.8"}, merged.Targets, "should merge all targets")
+	suite.Equal(suite.bar127A.Labels, merged.Labels, "should keep the labels of the min one")
+	suite.Equal(endpoint.Targets{"127.0.0.1"}, suite.bar127A.Targets, "should not modify candidates")
+
+	// test that mergeTargets resolver keeps the current resource as base
+	merged = suite.mergeTargets.ResolveUpdate(suite.bar192A, []*endpoint.Endpoint{suite.bar127A, suite.bar192A})
+	suite.Equal(endpoint.Targets{"127.0.0.1", "192.168.0.1"}, merged.Targets, "should merge all targets")
+	suite.Equal(suite.bar192A.Labels, merged.Labels, "should keep the labels of the existing resource")
+
+	// test that mergeTargets resolver does not merge across record types or into CNAMEs
+	suite.Equal(endpoint.Targets{"5.5.5.5"}, suite.mergeTargets.ResolveCreate([]*endpoint.Endpoint{suite.fooA5, suite.fooV1Cname}).Targets, "should not merge CNAME targets into A")
+	suite.Equal(suite.fooV1Cname, suite.mergeTargets.ResolveCreate([]*endpoint.Endpoint{suite.fooV2Cname, suite.fooV1Cname}), "should pick min one for CNAME")
+}
+
+func (suite *ResolverSuite) TestPriorityResolver() {
+	// test that priority resolver picks the highest priority for create list
+	suite.Equal(suite.bar192APriority10, suite.priority.ResolveCreate([]*endpoint.Endpoint{suite.bar127A, suite.bar192APriority10}), "should pick highest priority")
+	suite.Equal(suite.bar192APriority10, suite.priority.ResolveCreate([]*endpoint.Endpoint{suite.bar8APriority10, suite.bar192APriority10, suite.bar127A}), "should pick min one among highest priority")
+	suite.Equal(suite.bar127APriorityBad, suite.priority.ResolveCreate([]*endpoint.Endpoint{suite.bar192A, suite.bar127APriorityBad}), "should treat invalid priority as 0")
+
+	// test that priority resolver preserves resource unless a higher priority shows up
+	suite.Equal(suite.bar127A, suite.priority.ResolveUpdate(suite.bar127A, []*endpoint.Endpoint{suite.bar192A, suite.bar127A}), "should pick existing resource")
+	suite.Equal(suite.bar192APriority10, suite.priority.ResolveUpdate(suite.bar127A, []*endpoint.Endpoint{suite.bar127A, suite.bar192APriority10}), "should pick higher priority over existing resource")
+	suite.Equal(suite.bar8APriority10, suite.priority.ResolveUpdate(suite.bar8APriority10, []*endpoint.Endpoint{suite.bar192APriority10, suite.bar8APriority10}), "should pick existing resource among highest priority")
+}
+
 func TestConflictResolver(t *testing.T) {
 	suite.Run(t, new(ResolverSuite))
 }
@@ -0,0 +1,72 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// OutputFormatJSON writes changes as indented JSON documents
+	OutputFormatJSON = "json"
+	// OutputFormatYAML writes changes as YAML documents
+	OutputFormatYAML = "yaml"
+)
+
+// Export writes the changes to w in the given format.
+// Every call writes a complete document, so that the output of several synchronizations can be appended to the same writer.
+func (c *Changes) Export(w io.Writer, format string) error {
+	output := &Changes{
+		Create:    nonNil(c.Create),
+		UpdateOld: nonNil(c.UpdateOld),
+		UpdateNew: nonNil(c.UpdateNew),
+		Delete:    nonNil(c.Delete),
+	}
+
+	var data []byte
+	var err error
+	switch format {
+	case OutputFormatJSON:
+		data, err = json.MarshalIndent(output, "", "  ")
+		data = append(data, '\n')
+	case OutputFormatYAML:
+		data, err = yaml.Marshal(output)
+		data = append([]byte("---\n"), data...)
+	default:
+		return fmt.Errorf("unknown plan output format: %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// nonNil returns an empty list for nil, so that empty lists are written as such rather than as null.
+func nonNil(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	if endpoints == nil {
+		return []*endpoint.Endpoint{}
+	}
+	return endpoints
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func testChanges() *Changes {
+	return &Changes{
+		Create: []*endpoint.Endpoint{
+			{
+				DNSName:    "foo.example.org",
+				Targets:    endpoint.Targets{"1.2.3.4"},
+				RecordType: endpoint.RecordTypeA,
+				Labels: endpoint.Labels{
+					endpoint.ResourceLabelKey: "service/default/foo",
+				},
+			},
+		},
+		Delete: []*endpoint.Endpoint{
+			{
+				DNSName:    "bar.example.org",
+				Targets:    endpoint.Targets{"lb.example.org"},
+				RecordType: endpoint.RecordTypeCNAME,
+				Labels: endpoint.Labels{
+					endpoint.OwnerLabelKey:    "owner",
+					endpoint.ResourceLabelKey: "ingress/default/bar",
+				},
+			},
+		},
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, testChanges().Export(&b, OutputFormatJSON))
+
+	assert.Equal(t, `{
+  "create": [
+    {
+      "dnsName": "foo.example.org",
+      "targets": [
+        "1.2.3.4"
+      ],
+      "recordType": "A",
+      "labels": {
+        "resource": "service/default/foo"
+      }
+    }
+  ],
+  "updateOld": [],
+  "updateNew": [],
+  "delete": [
+    {
+      "dnsName": "bar.example.org",
+      "targets": [
+        "lb.example.org"
+      ],
+      "recordType": "CNAME",
+      "labels": {
+        "owner": "owner",
+        "resource": "ingress/default/bar"
+      }
+    }
+  ]
+}
+`, b.String())
+}
+
+func TestExportYAML(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, testChanges().Export(&b, OutputFormatYAML))
+	require.NoError(t, (&Changes{}).Export(&b, OutputFormatYAML))
+
+	assert.Equal(t, `---
+create:
+- dnsName: foo.example.org
+  labels:
+    resource: service/default/foo
+  recordType: A
+  targets:
+  - 1.2.3.4
+delete:
+- dnsName: bar.example.org
+  labels:
+    owner: owner
+    resource: ingress/default/bar
+  recordType: CNAME
+  targets:
+  - lb.example.org
+updateNew: []
+updateOld: []
+---
+create: []
+delete: []
+updateNew: []
+updateOld: []
+`, b.String())
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	var b bytes.Buffer
+	assert.Error(t, testChanges().Export(&b, "xml"))
+	assert.Empty(t, b.String())
+}
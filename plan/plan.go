@@ -18,6 +18,7 @@ package plan
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -43,18 +44,23 @@ type Plan struct {
 	DomainFilter endpoint.DomainFilter
 	// Property comparator compares custom properties of providers
 	PropertyComparator PropertyComparator
+	// ConflictResolver decides which candidate acquires a DNS name, defaults to PerResource
+	ConflictResolver ConflictResolver
+	// OwnerID identifies the owned records, unowned records are claimed on behalf of it. If empty, nothing is claimed
+	OwnerID string
 }
 
 // Changes holds lists of actions to be executed by dns providers
 type Changes struct {
 	// Records that need to be created
-	Create []*endpoint.Endpoint
+	Create []*endpoint.Endpoint `json:"create"`
 	// Records that need to be updated (current data)
-	UpdateOld []*endpoint.Endpoint
+	UpdateOld []*endpoint.Endpoint `json:"updateOld"`
 	// Records that need to be updated (desired data)
-	UpdateNew []*endpoint.Endpoint
+	UpdateNew []*endpoint.Endpoint `json:"updateNew"`
 	// Records that need to be deleted
-	Delete []*endpoint.Endpoint
+	// A record changing its type is deleted and recreated, hence deletions should be applied before creations
+	Delete []*endpoint.Endpoint `json:"delete"`
 }
 
 // planTable is a supplementary struct for Plan
@@ -71,12 +77,27 @@ bar.com |                | [->191.1.1.1, ->190.1.1.1]  |  = create (bar.com -> 1
 "=", i.e. result of calculation relies on supplied ConflictResolver
 */
 type planTable struct {
-	rows     map[string]map[string]*planTableRow
+	rows     map[string]map[planKey]*planTableRow
 	resolver ConflictResolver
 }
 
-func newPlanTable() planTable { //TODO: make resolver configurable
-	return planTable{map[string]map[string]*planTableRow{}, PerResource{}}
+func newPlanTable(resolver ConflictResolver) planTable {
+	if resolver == nil {
+		resolver = PerResource{}
+	}
+	return planTable{map[string]map[planKey]*planTableRow{}, resolver}
+}
+
+// planKey identifies a row among the records sharing a single dns name.
+// Records of different types are planned independently, so that a change of the record type
+// is planned as the deletion of the old record and the creation of the new one.
+type planKey struct {
+	setIdentifier string
+	recordType    string
+}
+
+func newPlanKey(e *endpoint.Endpoint) planKey {
+	return planKey{setIdentifier: e.SetIdentifier, recordType: e.RecordType}
 }
 
 // planTableRow
@@ -93,31 +114,85 @@ func (t planTableRow) String() string {
 
 func (t planTable) addCurrent(e *endpoint.Endpoint) {
 	dnsName := normalizeDNSName(e.DNSName)
+	key := newPlanKey(e)
 	if _, ok := t.rows[dnsName]; !ok {
-		t.rows[dnsName] = make(map[string]*planTableRow)
+		t.rows[dnsName] = make(map[planKey]*planTableRow)
 	}
-	if _, ok := t.rows[dnsName][e.SetIdentifier]; !ok {
-		t.rows[dnsName][e.SetIdentifier] = &planTableRow{}
+	if _, ok := t.rows[dnsName][key]; !ok {
+		t.rows[dnsName][key] = &planTableRow{}
 	}
-	t.rows[dnsName][e.SetIdentifier].current = e
+	t.rows[dnsName][key].current = e
 }
 
 func (t planTable) addCandidate(e *endpoint.Endpoint) {
 	dnsName := normalizeDNSName(e.DNSName)
+	key := newPlanKey(e)
 	if _, ok := t.rows[dnsName]; !ok {
-		t.rows[dnsName] = make(map[string]*planTableRow)
+		t.rows[dnsName] = make(map[planKey]*planTableRow)
 	}
-	if _, ok := t.rows[dnsName][e.SetIdentifier]; !ok {
-		t.rows[dnsName][e.SetIdentifier] = &planTableRow{}
+	if _, ok := t.rows[dnsName][key]; !ok {
+		t.rows[dnsName][key] = &planTableRow{}
+	}
+	t.rows[dnsName][key].candidates = append(t.rows[dnsName][key].candidates, e)
+}
+
+// resolveCNAMEConflicts enforces that a CNAME record does not coexist with records of other types
+// at the same dns name (RFC 1034, section 3.6.2). When candidates of both kinds request the name,
+// the resolver picks the winning candidate and the candidates of the losing kind are dropped,
+// which turns the records of the losing kind into deletions.
+func (t planTable) resolveCNAMEConflicts(rows map[planKey]*planTableRow) {
+	keysBySetIdentifier := map[string][]planKey{}
+	for key := range rows {
+		keysBySetIdentifier[key.setIdentifier] = append(keysBySetIdentifier[key.setIdentifier], key)
+	}
+
+	for _, keys := range keysBySetIdentifier {
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].recordType < keys[j].recordType
+		})
+
+		var current *endpoint.Endpoint
+		var candidates []*endpoint.Endpoint
+		var hasCNAME, hasOther bool
+		for _, key := range keys {
+			row := rows[key]
+			if row.current != nil && (current == nil || key.recordType == endpoint.RecordTypeCNAME) {
+				current = row.current
+			}
+			if len(row.candidates) == 0 {
+				continue
+			}
+			if key.recordType == endpoint.RecordTypeCNAME {
+				hasCNAME = true
+			} else {
+				hasOther = true
+			}
+			candidates = append(candidates, row.candidates...)
+		}
+		if !hasCNAME || !hasOther {
+			continue
+		}
+
+		var winner *endpoint.Endpoint
+		if current == nil {
+			winner = t.resolver.ResolveCreate(candidates)
+		} else {
+			winner = t.resolver.ResolveUpdate(current, candidates)
+		}
+		keepCNAME := winner.RecordType == endpoint.RecordTypeCNAME
+		for _, key := range keys {
+			if (key.recordType == endpoint.RecordTypeCNAME) != keepCNAME {
+				rows[key].candidates = nil
+			}
+		}
 	}
-	t.rows[dnsName][e.SetIdentifier].candidates = append(t.rows[dnsName][e.SetIdentifier].candidates, e)
 }
 
 // Calculate computes the actions needed to move current state towards desired
 // state. It then passes those changes to the current policy for further
 // processing. It returns a copy of Plan with the changes populated.
 func (p *Plan) Calculate() *Plan {
-	t := newPlanTable()
+	t := newPlanTable(p.ConflictResolver)
 
 	for _, current := range filterRecordsForPlan(p.Current, p.DomainFilter) {
 		t.addCurrent(current)
@@ -129,19 +204,19 @@ func (p *Plan) Calculate() *Plan {
 	changes := &Changes{}
 
 	for _, topRow := range t.rows {
+		t.resolveCNAMEConflicts(topRow)
 		for _, row := range topRow {
-			if row.current == nil { //dns name not taken
+			if row.current == nil && len(row.candidates) > 0 { //dns name not taken
 				changes.Create = append(changes.Create, t.resolver.ResolveCreate(row.candidates))
 			}
 			if row.current != nil && len(row.candidates) == 0 {
 				changes.Delete = append(changes.Delete, row.current)
 			}
 
-			// TODO: allows record type change, which might not be supported by all dns providers
 			if row.current != nil && len(row.candidates) > 0 { //dns name is taken
 				update := t.resolver.ResolveUpdate(row.current, row.candidates)
 				// compare "update" to "current" to figure out if actual update is required
-				if shouldUpdateTTL(update, row.current) || targetChanged(update, row.current) || p.shouldUpdateProviderSpecific(update, row.current) {
+				if shouldUpdateTTL(update, row.current) || targetChanged(update, row.current) || p.shouldUpdateProviderSpecific(update, row.current) || p.shouldClaim(update, row.current) {
 					inheritOwner(row.current, update)
 					changes.UpdateNew = append(changes.UpdateNew, update)
 					changes.UpdateOld = append(changes.UpdateOld, row.current)
@@ -173,6 +248,12 @@ func inheritOwner(from, to *endpoint.Endpoint) {
 	to.Labels[endpoint.OwnerLabelKey] = from.Labels[endpoint.OwnerLabelKey]
 }
 
+// shouldClaim returns true if the desired record claims the current record, which isn't owned by anyone.
+// The update lets the registry take over the ownership of the record.
+func (p *Plan) shouldClaim(desired, current *endpoint.Endpoint) bool {
+	return p.OwnerID != "" && desired.Labels[endpoint.ClaimLabelKey] == "true" && current.Labels[endpoint.OwnerLabelKey] == ""
+}
+
 func targetChanged(desired, current *endpoint.Endpoint) bool {
 	return !desired.Targets.Same(current.Targets)
 }
@@ -226,6 +307,8 @@ func (p *Plan) shouldUpdateProviderSpecific(desired, current *endpoint.Endpoint)
 // filterRecordsForPlan removes records that are not relevant to the planner.
 // Currently this just removes TXT records to prevent them from being
 // deleted erroneously by the planner (only the TXT registry should do this.)
+// The orphaned ownership records marked by the TXT registry are kept, so that
+// their deletion is subject to the policies like any other deletion.
 //
 // Per RFC 1034, CNAME records conflict with all other records - it is the
 // only record with this property. The behavior of the planner may need to be
@@ -240,10 +323,14 @@ func filterRecordsForPlan(records []*endpoint.Endpoint, domainFilter endpoint.Do
 		}
 
 		// Explicitly specify which records we want to use for planning.
-		// TODO: Add AAAA records as well when they are supported.
 		switch record.RecordType {
-		case endpoint.RecordTypeA, endpoint.RecordTypeCNAME:
+		case endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME:
 			filtered = append(filtered, record)
+		case endpoint.RecordTypeTXT:
+			// orphaned ownership records are planned for deletion
+			if record.Labels[endpoint.OrphanedLabelKey] == "true" {
+				filtered = append(filtered, record)
+			}
 		default:
 			continue
 		}
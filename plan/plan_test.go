@@ -34,12 +34,16 @@ type PlanTestSuite struct {
 	fooV2CnameNoLabel                *endpoint.Endpoint
 	fooV3CnameSameResource           *endpoint.Endpoint
 	fooA5                            *endpoint.Endpoint
+	fooLoopbackAAAA                  *endpoint.Endpoint
 	bar127A                          *endpoint.Endpoint
 	bar127AWithTTL                   *endpoint.Endpoint
 	bar127AWithProviderSpecificTrue  *endpoint.Endpoint
 	bar127AWithProviderSpecificFalse *endpoint.Endpoint
 	bar127AWithProviderSpecificUnset *endpoint.Endpoint
 	bar192A                          *endpoint.Endpoint
+	barElbCname                      *endpoint.Endpoint
+	barLoopbackAAAA                  *endpoint.Endpoint
+	barLinkLocalAAAA                 *endpoint.Endpoint
 	multiple1                        *endpoint.Endpoint
 	multiple2                        *endpoint.Endpoint
 	multiple3                        *endpoint.Endpoint
@@ -94,6 +98,14 @@ func (suite *PlanTestSuite) SetupTest() {
 			endpoint.ResourceLabelKey: "ingress/default/foo-5",
 		},
 	}
+	suite.fooLoopbackAAAA = &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"::1"},
+		RecordType: "AAAA",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/foo-5",
+		},
+	}
 	suite.bar127A = &endpoint.Endpoint{
 		DNSName:    "bar",
 		Targets:    endpoint.Targets{"127.0.0.1"},
@@ -156,6 +168,30 @@ func (suite *PlanTestSuite) SetupTest() {
 			endpoint.ResourceLabelKey: "ingress/default/bar-192",
 		},
 	}
+	suite.barElbCname = &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"bar.elb.com"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/bar-127",
+		},
+	}
+	suite.barLoopbackAAAA = &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"::1"},
+		RecordType: "AAAA",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/bar-127",
+		},
+	}
+	suite.barLinkLocalAAAA = &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"fe80::1"},
+		RecordType: "AAAA",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/bar-127",
+		},
+	}
 	suite.multiple1 = &endpoint.Endpoint{
 		DNSName:       "multiple",
 		Targets:       endpoint.Targets{"192.168.0.1"},
@@ -404,9 +440,114 @@ func (suite *PlanTestSuite) TestIdempotency() {
 func (suite *PlanTestSuite) TestDifferentTypes() {
 	current := []*endpoint.Endpoint{suite.fooV1Cname}
 	desired := []*endpoint.Endpoint{suite.fooV2Cname, suite.fooA5}
+	expectedCreate := []*endpoint.Endpoint{suite.fooA5}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{suite.fooV1Cname}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestRecordTypeChangeAToCNAME() {
+	current := []*endpoint.Endpoint{suite.bar127A}
+	desired := []*endpoint.Endpoint{suite.barElbCname}
+	expectedCreate := []*endpoint.Endpoint{suite.barElbCname}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{suite.bar127A}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestRecordTypeChangeCNAMEToDualStack() {
+	current := []*endpoint.Endpoint{suite.fooV1Cname}
+	desired := []*endpoint.Endpoint{suite.fooA5, suite.fooLoopbackAAAA}
+	expectedCreate := []*endpoint.Endpoint{suite.fooA5, suite.fooLoopbackAAAA}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{suite.fooV1Cname}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestRecordTypeChangeDualStackToCNAME() {
+	current := []*endpoint.Endpoint{suite.fooA5, suite.fooLoopbackAAAA}
+	desired := []*endpoint.Endpoint{suite.fooV1Cname}
+	expectedCreate := []*endpoint.Endpoint{suite.fooV1Cname}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{suite.fooA5, suite.fooLoopbackAAAA}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestCNAMEExclusivityCreate() {
+	current := []*endpoint.Endpoint{}
+	desired := []*endpoint.Endpoint{suite.fooV1Cname, suite.fooA5, suite.fooLoopbackAAAA}
+	expectedCreate := []*endpoint.Endpoint{suite.fooA5, suite.fooLoopbackAAAA}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestCNAMEExclusivityKeepsCurrent() {
+	current := []*endpoint.Endpoint{suite.fooV1Cname}
+	desired := []*endpoint.Endpoint{suite.fooV1Cname, suite.fooA5, suite.fooLoopbackAAAA}
 	expectedCreate := []*endpoint.Endpoint{}
-	expectedUpdateOld := []*endpoint.Endpoint{suite.fooV1Cname}
-	expectedUpdateNew := []*endpoint.Endpoint{suite.fooA5}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
 	expectedDelete := []*endpoint.Endpoint{}
 
 	p := &Plan{
@@ -574,6 +715,98 @@ func (suite *PlanTestSuite) TestSetIdentifierUpdateCreatesAndDeletes() {
 	validateEntries(suite.T(), changes.Delete, expectedDelete)
 }
 
+func (suite *PlanTestSuite) TestConflictResolverMergeTargets() {
+	current := []*endpoint.Endpoint{suite.bar127A}
+	desired := []*endpoint.Endpoint{suite.bar192A, suite.bar127A}
+	expectedCreate := []*endpoint.Endpoint{}
+	expectedUpdateOld := []*endpoint.Endpoint{suite.bar127A}
+	expectedUpdateNew := []*endpoint.Endpoint{{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"127.0.0.1", "192.168.0.1"},
+		RecordType: "A",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/bar-127",
+		},
+	}}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies:         []Policy{&SyncPolicy{}},
+		Current:          current,
+		Desired:          desired,
+		ConflictResolver: MergeTargets{},
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestDualStackCreate() {
+	current := []*endpoint.Endpoint{}
+	desired := []*endpoint.Endpoint{suite.bar127A, suite.barLoopbackAAAA}
+	expectedCreate := []*endpoint.Endpoint{suite.bar127A, suite.barLoopbackAAAA}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestDualStackUpdateAAAAOnly() {
+	current := []*endpoint.Endpoint{suite.bar127A, suite.barLoopbackAAAA}
+	desired := []*endpoint.Endpoint{suite.bar127A, suite.barLinkLocalAAAA}
+	expectedCreate := []*endpoint.Endpoint{}
+	expectedUpdateOld := []*endpoint.Endpoint{suite.barLoopbackAAAA}
+	expectedUpdateNew := []*endpoint.Endpoint{suite.barLinkLocalAAAA}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestDualStackDeleteAAAA() {
+	current := []*endpoint.Endpoint{suite.bar127A, suite.barLoopbackAAAA}
+	desired := []*endpoint.Endpoint{suite.bar127A}
+	expectedCreate := []*endpoint.Endpoint{}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{suite.barLoopbackAAAA}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
 func (suite *PlanTestSuite) TestDomainFiltersInitial() {
 
 	current := []*endpoint.Endpoint{suite.domainFilterExcluded}
@@ -620,6 +853,94 @@ func (suite *PlanTestSuite) TestDomainFiltersUpdate() {
 	validateEntries(suite.T(), changes.Delete, expectedDelete)
 }
 
+func (suite *PlanTestSuite) TestOrphanedOwnershipRecords() {
+	orphan := &endpoint.Endpoint{
+		DNSName:    "a-foo",
+		Targets:    endpoint.Targets{"\"heritage=external-dns,external-dns/owner=pwner\""},
+		RecordType: "TXT",
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey:    "pwner",
+			endpoint.OrphanedLabelKey: "true",
+		},
+	}
+	current := []*endpoint.Endpoint{orphan, suite.fooV2TXT}
+	desired := []*endpoint.Endpoint{}
+	expectedCreate := []*endpoint.Endpoint{}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{orphan}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  current,
+		Desired:  desired,
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+
+	p.Policies = []Policy{&UpsertOnlyPolicy{}}
+	validateEntries(suite.T(), p.Calculate().Changes.Delete, []*endpoint.Endpoint{})
+}
+
+func (suite *PlanTestSuite) TestClaim() {
+	unowned := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: "CNAME",
+		Labels:     map[string]string{},
+	}
+	owned := &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey: "other",
+		},
+	}
+	claimFoo := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/foo",
+			endpoint.ClaimLabelKey:    "true",
+		},
+	}
+	claimBar := &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/bar",
+			endpoint.ClaimLabelKey:    "true",
+		},
+	}
+
+	p := &Plan{
+		Policies: []Policy{&SyncPolicy{}},
+		Current:  []*endpoint.Endpoint{unowned, owned},
+		Desired:  []*endpoint.Endpoint{claimFoo, claimBar},
+		OwnerID:  "pwner",
+	}
+
+	// only the record which isn't owned by anyone is claimed
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), changes.UpdateNew, []*endpoint.Endpoint{claimFoo})
+	validateEntries(suite.T(), changes.UpdateOld, []*endpoint.Endpoint{unowned})
+	validateEntries(suite.T(), changes.Delete, []*endpoint.Endpoint{})
+
+	// nothing is claimed without owner
+	p.OwnerID = ""
+	changes = p.Calculate().Changes
+	validateEntries(suite.T(), changes.UpdateNew, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), changes.UpdateOld, []*endpoint.Endpoint{})
+}
+
 func TestPlan(t *testing.T) {
 	suite.Run(t, new(PlanTestSuite))
 }
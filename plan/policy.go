@@ -16,6 +16,37 @@ limitations under the License.
 
 package plan
 
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+var (
+	deletionGuardBlockedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "plan",
+			Name:      "deletion_guard_blocked_total",
+			Help:      "Number of plans refused by the deletion guard.",
+		},
+	)
+	deletionGuardBlockedDeletes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "plan",
+			Name:      "deletion_guard_blocked_deletes",
+			Help:      "Number of deletions in the last plan refused by the deletion guard.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(deletionGuardBlockedTotal)
+	prometheus.MustRegister(deletionGuardBlockedDeletes)
+}
+
 // Policy allows to apply different rules to a set of changes.
 type Policy interface {
 	Apply(changes *Changes) *Changes
@@ -57,3 +88,54 @@ func (p *CreateOnlyPolicy) Apply(changes *Changes) *Changes {
 		Create: changes.Create,
 	}
 }
+
+// DeletionGuardPolicy refuses a set of changes which deletes too many records at once.
+// It is meant to be applied after one of the other policies.
+type DeletionGuardPolicy struct {
+	// MaxDeletes is the maximum number of deletions, 0 disables the check
+	MaxDeletes int
+	// MaxDeletePercentage is the maximum percentage of the owned records to delete, 0 disables the check
+	MaxDeletePercentage int
+	// OwnerID identifies the owned records, if empty all records are considered owned
+	OwnerID string
+	// Current is the list of records currently present at the DNS provider
+	Current []*endpoint.Endpoint
+}
+
+// Apply applies the deletion guard policy which returns no changes at all if the deletions exceed the thresholds.
+func (p *DeletionGuardPolicy) Apply(changes *Changes) *Changes {
+	deletes := p.countOwned(changes.Delete)
+	if deletes == 0 {
+		deletionGuardBlockedDeletes.Set(0)
+		return changes
+	}
+
+	owned := p.countOwned(p.Current)
+	exceedsCount := p.MaxDeletes > 0 && deletes > p.MaxDeletes
+	exceedsPercentage := p.MaxDeletePercentage > 0 && owned > 0 && deletes*100 > owned*p.MaxDeletePercentage
+	if !exceedsCount && !exceedsPercentage {
+		deletionGuardBlockedDeletes.Set(0)
+		return changes
+	}
+
+	log.Warnf("Refusing to apply changes deleting %d of %d owned records (max deletes: %d, max delete percentage: %d%%), use --deletion-guard-override to apply them anyway",
+		deletes, owned, p.MaxDeletes, p.MaxDeletePercentage)
+	deletionGuardBlockedTotal.Inc()
+	deletionGuardBlockedDeletes.Set(float64(deletes))
+
+	return &Changes{}
+}
+
+// countOwned returns the number of records owned by OwnerID.
+func (p *DeletionGuardPolicy) countOwned(records []*endpoint.Endpoint) int {
+	if p.OwnerID == "" {
+		return len(records)
+	}
+	count := 0
+	for _, record := range records {
+		if record.Labels[endpoint.OwnerLabelKey] == p.OwnerID {
+			count++
+		}
+	}
+	return count
+}
@@ -20,6 +20,8 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
@@ -70,6 +72,94 @@ func TestApply(t *testing.T) {
 	}
 }
 
+// TestDeletionGuardPolicy tests that the deletion guard refuses changes exceeding its thresholds.
+func TestDeletionGuardPolicy(t *testing.T) {
+	empty := []*endpoint.Endpoint{}
+	owned := func(name string) *endpoint.Endpoint {
+		return &endpoint.Endpoint{DNSName: name, Targets: endpoint.Targets{"v1"}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}}
+	}
+	foreign := &endpoint.Endpoint{DNSName: "foreign", Targets: endpoint.Targets{"v1"}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "other"}}
+	current := []*endpoint.Endpoint{owned("foo"), owned("bar"), owned("baz"), owned("qux"), foreign}
+	create := []*endpoint.Endpoint{owned("new")}
+
+	for _, tc := range []struct {
+		title    string
+		policy   *DeletionGuardPolicy
+		changes  *Changes
+		expected *Changes
+	}{
+		{
+			"disabled guard keeps all changes",
+			&DeletionGuardPolicy{Current: current},
+			&Changes{Create: create, Delete: current},
+			&Changes{Create: create, Delete: current},
+		},
+		{
+			"deletes within the count threshold are kept",
+			&DeletionGuardPolicy{MaxDeletes: 2, OwnerID: "owner", Current: current},
+			&Changes{Create: create, Delete: []*endpoint.Endpoint{owned("foo"), owned("bar"), foreign}},
+			&Changes{Create: create, Delete: []*endpoint.Endpoint{owned("foo"), owned("bar"), foreign}},
+		},
+		{
+			"deletes exceeding the count threshold refuse all changes",
+			&DeletionGuardPolicy{MaxDeletes: 2, OwnerID: "owner", Current: current},
+			&Changes{Create: create, Delete: []*endpoint.Endpoint{owned("foo"), owned("bar"), owned("baz")}},
+			&Changes{Create: empty, Delete: empty},
+		},
+		{
+			"deletes within the percentage threshold are kept",
+			&DeletionGuardPolicy{MaxDeletePercentage: 50, OwnerID: "owner", Current: current},
+			&Changes{Create: create, Delete: []*endpoint.Endpoint{owned("foo"), owned("bar")}},
+			&Changes{Create: create, Delete: []*endpoint.Endpoint{owned("foo"), owned("bar")}},
+		},
+		{
+			"deletes exceeding the percentage threshold refuse all changes",
+			&DeletionGuardPolicy{MaxDeletePercentage: 50, OwnerID: "owner", Current: current},
+			&Changes{Create: create, Delete: []*endpoint.Endpoint{owned("foo"), owned("bar"), owned("baz")}},
+			&Changes{Create: empty, Delete: empty},
+		},
+		{
+			"without owner all records count",
+			&DeletionGuardPolicy{MaxDeletePercentage: 50, Current: current},
+			&Changes{Create: create, Delete: []*endpoint.Endpoint{owned("foo"), owned("bar"), foreign}},
+			&Changes{Create: empty, Delete: empty},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			changes := tc.policy.Apply(tc.changes)
+
+			validateEntries(t, changes.Create, tc.expected.Create)
+			validateEntries(t, changes.UpdateOld, tc.expected.UpdateOld)
+			validateEntries(t, changes.UpdateNew, tc.expected.UpdateNew)
+			validateEntries(t, changes.Delete, tc.expected.Delete)
+		})
+	}
+}
+
+// TestDeletionGuardPolicyBlockedDeletes tests that the gauge of the blocked deletions is reset once nothing is blocked.
+func TestDeletionGuardPolicyBlockedDeletes(t *testing.T) {
+	owned := func(name string) *endpoint.Endpoint {
+		return &endpoint.Endpoint{DNSName: name, Targets: endpoint.Targets{"v1"}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}}
+	}
+	policy := &DeletionGuardPolicy{MaxDeletes: 1, OwnerID: "owner", Current: []*endpoint.Endpoint{owned("foo"), owned("bar")}}
+
+	policy.Apply(&Changes{Delete: []*endpoint.Endpoint{owned("foo"), owned("bar")}})
+	if blocked := testutil.ToFloat64(deletionGuardBlockedDeletes); blocked != 2 {
+		t.Errorf("expected 2 blocked deletions, got %v", blocked)
+	}
+
+	policy.Apply(&Changes{Delete: []*endpoint.Endpoint{owned("foo")}})
+	if blocked := testutil.ToFloat64(deletionGuardBlockedDeletes); blocked != 0 {
+		t.Errorf("expected no blocked deletions, got %v", blocked)
+	}
+
+	policy.Apply(&Changes{Delete: []*endpoint.Endpoint{owned("foo"), owned("bar")}})
+	policy.Apply(&Changes{})
+	if blocked := testutil.ToFloat64(deletionGuardBlockedDeletes); blocked != 0 {
+		t.Errorf("expected no blocked deletions without deletions, got %v", blocked)
+	}
+}
+
 // TestPolicies tests that policies are correctly registered.
 func TestPolicies(t *testing.T) {
 	validatePolicy(t, Policies["sync"], &SyncPolicy{})
@@ -229,7 +229,7 @@ func (p *AWSProvider) Zones(ctx context.Context) (map[string]*route53.HostedZone
 
 	err := p.client.ListHostedZonesPagesWithContext(ctx, &route53.ListHostedZonesInput{}, f)
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 	if tagErr != nil {
 		return nil, tagErr
@@ -263,6 +263,7 @@ func (p *AWSProvider) Records(ctx context.Context) (endpoints []*endpoint.Endpoi
 
 func (p *AWSProvider) records(ctx context.Context, zones map[string]*route53.HostedZone) ([]*endpoint.Endpoint, error) {
 	endpoints := make([]*endpoint.Endpoint, 0)
+	aaaaAliases := map[string]string{}
 	f := func(resp *route53.ListResourceRecordSetsOutput, lastPage bool) (shouldContinue bool) {
 		for _, r := range resp.ResourceRecordSets {
 			newEndpoints := make([]*endpoint.Endpoint, 0)
@@ -274,6 +275,13 @@ func (p *AWSProvider) records(ctx context.Context, zones map[string]*route53.Hos
 				continue
 			}
 
+			// An AAAA alias is the IPv6 half of a dualstack alias, which is represented by
+			// the dualstack label on the endpoint of the corresponding A alias.
+			if r.AliasTarget != nil && aws.StringValue(r.Type) == route53.RRTypeAaaa {
+				aaaaAliases[aliasKey(wildcardUnescape(aws.StringValue(r.Name)), aws.StringValue(r.SetIdentifier))] = wildcardUnescape(aws.StringValue(r.Name))
+				continue
+			}
+
 			var ttl endpoint.TTL
 			if r.TTL != nil {
 				ttl = endpoint.TTL(*r.TTL)
@@ -339,13 +347,30 @@ func (p *AWSProvider) records(ctx context.Context, zones map[string]*route53.Hos
 		}
 
 		if err := p.client.ListResourceRecordSetsPagesWithContext(ctx, params, f); err != nil {
-			return nil, err
+			return nil, classifyError(err)
 		}
 	}
 
+	for _, ep := range endpoints {
+		key := aliasKey(ep.DNSName, ep.SetIdentifier)
+		if _, ok := aaaaAliases[key]; ok && ep.RecordType == endpoint.RecordTypeCNAME {
+			ep.Labels[endpoint.DualstackLabelKey] = "true"
+			delete(aaaaAliases, key)
+		}
+	}
+	// AAAA aliases can't be represented on their own, as alias records are represented by CNAME endpoints
+	for _, name := range aaaaAliases {
+		log.Debugf("Ignoring the AAAA alias record %s, which has no corresponding A alias record", name)
+	}
+
 	return endpoints, nil
 }
 
+// aliasKey identifies the alias records of an endpoint by their name and set identifier.
+func aliasKey(dnsName, setIdentifier string) string {
+	return strings.TrimSuffix(dnsName, ".") + "::" + setIdentifier
+}
+
 // CreateRecords creates a given set of DNS records in the given hosted zone.
 func (p *AWSProvider) CreateRecords(ctx context.Context, endpoints []*endpoint.Endpoint) error {
 	return p.doRecords(ctx, route53.ChangeActionCreate, endpoints)
@@ -414,6 +439,7 @@ func (p *AWSProvider) submitChanges(ctx context.Context, changes []*route53.Chan
 	}
 
 	var failedZones []string
+	var throttled bool
 	for z, cs := range changesByZone {
 		var failedUpdate bool
 
@@ -436,6 +462,7 @@ func (p *AWSProvider) submitChanges(ctx context.Context, changes []*route53.Chan
 					log.Errorf("Failure in zone %s [Id: %s]", aws.StringValue(zones[z].Name), z)
 					log.Error(err) //TODO(ideahitme): consider changing the interface in cases when this error might be a concern for other components
 					failedUpdate = true
+					throttled = throttled || request.IsErrorThrottle(err)
 				} else {
 					// z is the R53 Hosted Zone ID already as aws.StringValue
 					log.Infof("%d record(s) in zone %s [Id: %s] were successfully updated", len(b), aws.StringValue(zones[z].Name), z)
@@ -453,12 +480,25 @@ func (p *AWSProvider) submitChanges(ctx context.Context, changes []*route53.Chan
 	}
 
 	if len(failedZones) > 0 {
-		return fmt.Errorf("failed to submit all changes for the following zones: %v", failedZones)
+		err := fmt.Errorf("failed to submit all changes for the following zones: %v", failedZones)
+		if throttled {
+			return provider.NewRateLimitedError(err, 0)
+		}
+		return err
 	}
 
 	return nil
 }
 
+// classifyError marks errors caused by exceeding the Route53 API rate limits, so that the controller backs off.
+// The SDK already retried the request before returning them.
+func classifyError(err error) error {
+	if request.IsErrorThrottle(err) {
+		return provider.NewRateLimitedError(err, 0)
+	}
+	return err
+}
+
 // newChanges returns a collection of Changes based on the given records and action.
 func (p *AWSProvider) newChanges(action string, endpoints []*endpoint.Endpoint, recordsCache []*endpoint.Endpoint, zones map[string]*route53.HostedZone) []*route53.Change {
 	changes := make([]*route53.Change, 0, len(endpoints))
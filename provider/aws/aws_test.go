@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/stretchr/testify/assert"
@@ -350,6 +351,64 @@ func TestAWSRecords(t *testing.T) {
 	})
 }
 
+func TestAWSRecordsDualstack(t *testing.T) {
+	provider, _ := newAWSProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), provider.NewZoneIDFilter([]string{}), provider.NewZoneTypeFilter(""), false, false, []*endpoint.Endpoint{
+		{
+			DNSName:    "dualstack-test.zone-1.ext-dns-test-2.teapot.zalan.do",
+			RecordType: endpoint.RecordTypeCNAME,
+			Targets:    endpoint.Targets{"foo.eu-central-1.elb.amazonaws.com"},
+			Labels:     map[string]string{endpoint.DualstackLabelKey: "true"},
+		},
+		endpoint.NewEndpointWithTTL("ipv6-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeAAAA, endpoint.TTL(recordTTL), "2001:db8::1"),
+	})
+
+	records, err := provider.Records(context.Background())
+	require.NoError(t, err)
+
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("dualstack-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeCNAME, endpoint.TTL(recordTTL), "foo.eu-central-1.elb.amazonaws.com").WithProviderSpecific(providerSpecificEvaluateTargetHealth, "false"),
+		endpoint.NewEndpointWithTTL("ipv6-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeAAAA, endpoint.TTL(recordTTL), "2001:db8::1"),
+	})
+
+	for _, r := range records {
+		if r.RecordType == endpoint.RecordTypeCNAME {
+			assert.Equal(t, "true", r.Labels[endpoint.DualstackLabelKey])
+		}
+	}
+}
+
+func TestAWSRecordsAAAAAliasWithoutAAlias(t *testing.T) {
+	provider, _ := newAWSProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), provider.NewZoneIDFilter([]string{}), provider.NewZoneTypeFilter(""), false, false, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("ipv6-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeAAAA, endpoint.TTL(recordTTL), "2001:db8::1"),
+	})
+	_, err := provider.client.ChangeResourceRecordSetsWithContext(context.Background(), &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String("/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do."),
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: []*route53.Change{{
+				Action: aws.String(route53.ChangeActionCreate),
+				ResourceRecordSet: &route53.ResourceRecordSet{
+					Name: aws.String("ipv6-only-alias.zone-1.ext-dns-test-2.teapot.zalan.do."),
+					Type: aws.String(route53.RRTypeAaaa),
+					AliasTarget: &route53.AliasTarget{
+						DNSName:              aws.String("foo.eu-central-1.elb.amazonaws.com."),
+						HostedZoneId:         aws.String("Z215JYRZR1TBD5"),
+						EvaluateTargetHealth: aws.Bool(false),
+					},
+				},
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	records, err := provider.Records(context.Background())
+	require.NoError(t, err)
+
+	// an AAAA alias record is only represented as the IPv6 half of a dualstack alias, it's left alone on its own
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("ipv6-test.zone-1.ext-dns-test-2.teapot.zalan.do", endpoint.RecordTypeAAAA, endpoint.TTL(recordTTL), "2001:db8::1"),
+	})
+}
+
 func TestAWSCreateRecords(t *testing.T) {
 	customTTL := endpoint.TTL(60)
 	provider, _ := newAWSProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), provider.NewZoneIDFilter([]string{}), provider.NewZoneTypeFilter(""), defaultEvaluateTargetHealth, false, []*endpoint.Endpoint{})
@@ -1053,6 +1112,14 @@ func TestAWSCanonicalHostedZone(t *testing.T) {
 	}
 }
 
+func TestAWSClassifyError(t *testing.T) {
+	throttled := awserr.New("Throttling", "Rate exceeded", nil)
+	assert.Equal(t, provider.ErrorClassRateLimited, provider.ClassifyError(classifyError(throttled)))
+
+	denied := awserr.New("AccessDenied", "User is not authorized", nil)
+	assert.Equal(t, denied, classifyError(denied))
+}
+
 func TestAWSSuitableZones(t *testing.T) {
 	zones := map[string]*route53.HostedZone{
 		// Public domain
@@ -262,9 +262,9 @@ func NewCoreDNSProvider(domainFilter endpoint.DomainFilter, prefix string, dryRu
 
 // findEp takes an Endpoint slice and looks for an element in it. If found it will
 // return Endpoint, otherwise it will return nil and a bool of false.
-func findEp(slice []*endpoint.Endpoint, dnsName string) (*endpoint.Endpoint, bool) {
+func findEp(slice []*endpoint.Endpoint, dnsName, recordType string) (*endpoint.Endpoint, bool) {
 	for _, item := range slice {
-		if item.DNSName == dnsName {
+		if item.DNSName == dnsName && item.RecordType == recordType {
 			return item, true
 		}
 	}
@@ -283,7 +283,7 @@ func findLabelInTargets(targets []string, label string) (string, bool) {
 }
 
 // Records returns all DNS records found in CoreDNS etcd backend. Depending on the record fields
-// it may be mapped to one or two records of type A, CNAME, TXT, A+TXT, CNAME+TXT
+// it may be mapped to one or two records of type A, AAAA, CNAME, TXT, A+TXT, AAAA+TXT, CNAME+TXT
 func (p coreDNSProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
 	var result []*endpoint.Endpoint
 	services, err := p.client.GetServices(p.coreDNSPrefix)
@@ -300,14 +300,15 @@ func (p coreDNSProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, err
 		log.Debugf("Getting service (%v) with service host (%s)", service, service.Host)
 		prefix := strings.Join(domains[:service.TargetStrip], ".")
 		if service.Host != "" {
-			ep, found := findEp(result, dnsName)
+			recordType := guessRecordType(service.Host)
+			ep, found := findEp(result, dnsName, recordType)
 			if found {
 				ep.Targets = append(ep.Targets, service.Host)
 				log.Debugf("Extending ep (%s) with new service host (%s)", ep, service.Host)
 			} else {
 				ep = endpoint.NewEndpointWithTTL(
 					dnsName,
-					guessRecordType(service.Host),
+					recordType,
 					endpoint.TTL(service.TTL),
 					service.Host,
 				)
@@ -331,7 +332,7 @@ func (p coreDNSProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, err
 	return result, nil
 }
 
-// ApplyChanges stores changes back to etcd converting them to CoreDNS format and aggregating A/CNAME and TXT records
+// ApplyChanges stores changes back to etcd converting them to CoreDNS format and aggregating A/AAAA/CNAME and TXT records
 func (p coreDNSProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	grouped := map[string][]*endpoint.Endpoint{}
 	for _, ep := range changes.Create {
@@ -458,10 +459,15 @@ func (p coreDNSProvider) etcdKeyFor(dnsName string) string {
 }
 
 func guessRecordType(target string) string {
-	if net.ParseIP(target) != nil {
+	ip := net.ParseIP(target)
+	switch {
+	case ip == nil:
+		return endpoint.RecordTypeCNAME
+	case ip.To4() == nil:
+		return endpoint.RecordTypeAAAA
+	default:
 		return endpoint.RecordTypeA
 	}
-	return endpoint.RecordTypeCNAME
 }
 
 func reverse(slice []string) {
@@ -84,6 +84,66 @@ func TestAServiceTranslation(t *testing.T) {
 	}
 }
 
+func TestAAAAServiceTranslation(t *testing.T) {
+	expectedTarget := "2001:db8::1"
+	expectedDNSName := "example.com"
+	expectedRecordType := endpoint.RecordTypeAAAA
+
+	client := fakeETCDClient{
+		map[string]*Service{
+			"/skydns/com/example": {Host: expectedTarget},
+		},
+	}
+	provider := coreDNSProvider{
+		client:        client,
+		coreDNSPrefix: defaultCoreDNSPrefix,
+	}
+	endpoints, err := provider.Records(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 1 {
+		t.Fatalf("got unexpected number of endpoints: %d", len(endpoints))
+	}
+	if endpoints[0].DNSName != expectedDNSName {
+		t.Errorf("got unexpected DNS name: %s != %s", endpoints[0].DNSName, expectedDNSName)
+	}
+	if endpoints[0].Targets[0] != expectedTarget {
+		t.Errorf("got unexpected DNS target: %s != %s", endpoints[0].Targets[0], expectedTarget)
+	}
+	if endpoints[0].RecordType != expectedRecordType {
+		t.Errorf("got unexpected DNS record type: %s != %s", endpoints[0].RecordType, expectedRecordType)
+	}
+}
+
+func TestDualStackServiceTranslation(t *testing.T) {
+	client := fakeETCDClient{
+		map[string]*Service{
+			"/skydns/com/example/v4": {Host: "1.2.3.4"},
+			"/skydns/com/example/v6": {Host: "2001:db8::1"},
+		},
+	}
+	provider := coreDNSProvider{
+		client:        client,
+		coreDNSPrefix: defaultCoreDNSPrefix,
+	}
+	endpoints, err := provider.Records(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 2 {
+		t.Fatalf("got unexpected number of endpoints: %d", len(endpoints))
+	}
+	for _, ep := range endpoints {
+		if len(ep.Targets) != 1 {
+			t.Fatalf("got unexpected number of targets for %s: %d", ep.RecordType, len(ep.Targets))
+		}
+		if expectedRecordType := guessRecordType(ep.Targets[0]); ep.RecordType != expectedRecordType {
+			t.Errorf("got unexpected DNS record type for %s: %s != %s", ep.Targets[0], ep.RecordType, expectedRecordType)
+		}
+	}
+}
+
 func TestCNAMEServiceTranslation(t *testing.T) {
 	expectedTarget := "example.net"
 	expectedDNSName := "example.com"
@@ -247,6 +247,9 @@ func apiRetryLoop(f func() error) error {
 		time.Sleep(1 * time.Minute)
 	}
 
+	if err == dynect.ErrRateLimited {
+		return provider.NewRateLimitedError(err, time.Minute)
+	}
 	return err
 }
 
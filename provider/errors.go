@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrorClass tells the controller how to retry after an error.
+type ErrorClass int
+
+const (
+	// ErrorClassTransient errors are likely to go away when retrying soon, e.g. network errors.
+	// Errors which aren't classified are considered transient.
+	ErrorClassTransient ErrorClass = iota
+	// ErrorClassPermanent errors won't go away by retrying before the configuration or the records change,
+	// e.g. missing permissions or invalid changes.
+	ErrorClassPermanent
+	// ErrorClassRateLimited errors are caused by exceeding the rate limit of the DNS provider's API,
+	// retrying is only useful after backing off.
+	ErrorClassRateLimited
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassPermanent:
+		return "permanent"
+	case ErrorClassRateLimited:
+		return "rate_limited"
+	default:
+		return "transient"
+	}
+}
+
+// ClassifiedError wraps an error together with its class.
+type ClassifiedError struct {
+	Class ErrorClass
+	// RetryAfter is the duration the DNS provider asked to wait before retrying, if any
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// NewTransientError marks an error as transient.
+func NewTransientError(err error) error {
+	return &ClassifiedError{Class: ErrorClassTransient, Err: err}
+}
+
+// NewPermanentError marks an error as permanent.
+func NewPermanentError(err error) error {
+	return &ClassifiedError{Class: ErrorClassPermanent, Err: err}
+}
+
+// NewRateLimitedError marks an error as caused by rate limiting, retryAfter is optional.
+func NewRateLimitedError(err error, retryAfter time.Duration) error {
+	return &ClassifiedError{Class: ErrorClassRateLimited, RetryAfter: retryAfter, Err: err}
+}
+
+// ClassifyError returns the class of the outermost ClassifiedError in the error's chain,
+// or ErrorClassTransient if the error isn't classified.
+func ClassifyError(err error) ErrorClass {
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return classified.Class
+	}
+	return ErrorClassTransient
+}
+
+// RetryAfter returns the duration the DNS provider asked to wait before retrying, or zero if unknown.
+func RetryAfter(err error) time.Duration {
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return classified.RetryAfter
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	cause := errors.New("failure")
+
+	for _, tc := range []struct {
+		title              string
+		err                error
+		expectedClass      ErrorClass
+		expectedRetryAfter time.Duration
+	}{
+		{
+			title:         "unclassified",
+			err:           cause,
+			expectedClass: ErrorClassTransient,
+		},
+		{
+			title:         "transient",
+			err:           NewTransientError(cause),
+			expectedClass: ErrorClassTransient,
+		},
+		{
+			title:         "permanent",
+			err:           NewPermanentError(cause),
+			expectedClass: ErrorClassPermanent,
+		},
+		{
+			title:              "rate limited",
+			err:                NewRateLimitedError(cause, time.Minute),
+			expectedClass:      ErrorClassRateLimited,
+			expectedRetryAfter: time.Minute,
+		},
+		{
+			title:              "wrapped",
+			err:                fmt.Errorf("applying changes: %w", NewRateLimitedError(cause, time.Second)),
+			expectedClass:      ErrorClassRateLimited,
+			expectedRetryAfter: time.Second,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.expectedClass, ClassifyError(tc.err))
+			assert.Equal(t, tc.expectedRetryAfter, RetryAfter(tc.err))
+			assert.True(t, errors.Is(tc.err, cause))
+			assert.Contains(t, tc.err.Error(), "failure")
+		})
+	}
+}
+
+func TestErrorClassString(t *testing.T) {
+	assert.Equal(t, "transient", ErrorClassTransient.String())
+	assert.Equal(t, "permanent", ErrorClassPermanent.String())
+	assert.Equal(t, "rate_limited", ErrorClassRateLimited.String())
+}
@@ -430,6 +430,18 @@ func (p *PDNSProvider) Records(ctx context.Context) (endpoints []*endpoint.Endpo
 func (p *PDNSProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	startTime := time.Now()
 
+	// Delete first, so that a record replaced by one of another type (e.g. A by CNAME)
+	// is gone before its replacement is created
+	for _, change := range changes.Delete {
+		log.Debugf("DELETE: %+v", change)
+	}
+	if len(changes.Delete) > 0 {
+		err := p.mutateRecords(changes.Delete, PdnsDelete)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Create
 	for _, change := range changes.Create {
 		log.Debugf("CREATE: %+v", change)
@@ -465,16 +477,6 @@ func (p *PDNSProvider) ApplyChanges(ctx context.Context, changes *plan.Changes)
 		}
 	}
 
-	// Delete
-	for _, change := range changes.Delete {
-		log.Debugf("DELETE: %+v", change)
-	}
-	if len(changes.Delete) > 0 {
-		err := p.mutateRecords(changes.Delete, PdnsDelete)
-		if err != nil {
-			return err
-		}
-	}
 	log.Debugf("Changes pushed out to PowerDNS in %s\n", time.Since(startTime))
 	return nil
 }
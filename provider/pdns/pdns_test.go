@@ -899,6 +899,22 @@ func (suite *NewPDNSProviderTestSuite) TestPDNSConvertEndpointsToZones() {
 	}
 }
 
+func (suite *NewPDNSProviderTestSuite) TestPDNSConvertEndpointsToZonesAAAA() {
+	p := &PDNSProvider{
+		client: &PDNSAPIClientStubEmptyZones{},
+	}
+
+	// Check endpoints of type AAAA are passed through with their addresses untouched
+	zlist, err := p.ConvertEndpointsToZones([]*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("example.com", endpoint.RecordTypeAAAA, endpoint.TTL(300), "2001:db8::1"),
+	}, PdnsReplace)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), zlist, 1)
+	assert.Len(suite.T(), zlist[0].Rrsets, 1)
+	assert.Equal(suite.T(), "AAAA", zlist[0].Rrsets[0].Type_)
+	assert.Equal(suite.T(), "2001:db8::1", zlist[0].Rrsets[0].Records[0].Content)
+}
+
 func (suite *NewPDNSProviderTestSuite) TestPDNSConvertEndpointsToZonesPartitionZones() {
 	// Test DomainFilters
 	p := &PDNSProvider{
@@ -130,6 +130,53 @@ func TestRfc2136GetRecordsMultipleTargets(t *testing.T) {
 	assert.Equal(t, 0, len(recs[0].ProviderSpecific), "expected no provider specific config")
 }
 
+// TestRfc2136GetRecordsDualStack simulates A and AAAA records sharing a name.
+func TestRfc2136GetRecordsDualStack(t *testing.T) {
+	stub := newStub()
+	err := stub.setOutput([]string{
+		"foo.com 3600 IN A 1.1.1.1",
+		"foo.com 3600 IN AAAA 2001:db8::1",
+	})
+	assert.NoError(t, err)
+
+	provider, err := createRfc2136StubProvider(stub)
+	assert.NoError(t, err)
+
+	recs, err := provider.Records(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, len(recs), "expected a record per type")
+	assert.Equal(t, "A", recs[0].RecordType)
+	assert.Equal(t, endpoint.Targets{"1.1.1.1"}, recs[0].Targets)
+	assert.Equal(t, "AAAA", recs[1].RecordType)
+	assert.Equal(t, endpoint.Targets{"2001:db8::1"}, recs[1].Targets)
+}
+
+func TestRfc2136ApplyChangesAAAA(t *testing.T) {
+	stub := newStub()
+	provider, err := createRfc2136StubProvider(stub)
+	assert.NoError(t, err)
+
+	p := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			{
+				DNSName:    "v1.foo.com",
+				RecordType: "AAAA",
+				Targets:    []string{"2001:db8::1"},
+				RecordTTL:  endpoint.TTL(400),
+			},
+		},
+	}
+
+	err = provider.ApplyChanges(context.Background(), p)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(stub.createMsgs))
+	assert.True(t, strings.Contains(stub.createMsgs[0].String(), "v1.foo.com"))
+	assert.True(t, strings.Contains(stub.createMsgs[0].String(), "AAAA"))
+	assert.True(t, strings.Contains(stub.createMsgs[0].String(), "2001:db8::1"))
+}
+
 func TestRfc2136GetRecords(t *testing.T) {
 	stub := newStub()
 	err := stub.setOutput([]string{
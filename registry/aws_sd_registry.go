@@ -64,8 +64,9 @@ func (sdr *AWSSDRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, er
 }
 
 // ApplyChanges filters out records not owned the External-DNS, additionally it adds the required label
-// inserted in the AWS SD instance as a CreateID field
+// inserted in the AWS SD instance as a CreateID field. The claimed records are taken over along with their update.
 func (sdr *AWSSDRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	claimRecords(sdr.ownerID, changes)
 	filteredChanges := &plan.Changes{
 		Create:    changes.Create,
 		UpdateNew: filterOwnedRecords(sdr.ownerID, changes.UpdateNew),
@@ -78,12 +79,15 @@ func (sdr *AWSSDRegistry) ApplyChanges(ctx context.Context, changes *plan.Change
 	sdr.updateLabels(filteredChanges.UpdateOld)
 	sdr.updateLabels(filteredChanges.Delete)
 
-	return sdr.provider.ApplyChanges(ctx, filteredChanges)
+	return applyChanges(ctx, sdr.provider, filteredChanges)
 }
 
 func (sdr *AWSSDRegistry) updateLabels(endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.OwnerLabelKey] = sdr.ownerID
+		for _, k := range planningLabelKeys {
+			delete(ep.Labels, k)
+		}
 		ep.Labels[endpoint.AWSSDDescriptionLabel] = ep.Labels.Serialize(false)
 	}
 }
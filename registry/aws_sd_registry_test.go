@@ -159,6 +159,48 @@ func TestAWSSDRegistry_Records_ApplyChanges(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestAWSSDRegistry_ApplyChanges_Claim(t *testing.T) {
+	claimed := newEndpointWithOwner("tar.test-zone.example.org", "new-tar.loadbalancer.com", endpoint.RecordTypeCNAME, "")
+	claimed.Labels[endpoint.ClaimLabelKey] = "true"
+	claimed.Labels[endpoint.PriorityLabelKey] = "10"
+	foreign := newEndpointWithOwner("bar.test-zone.example.org", "new-bar.loadbalancer.com", endpoint.RecordTypeCNAME, "other")
+	foreign.Labels[endpoint.ClaimLabelKey] = "true"
+	changes := &plan.Changes{
+		UpdateNew: []*endpoint.Endpoint{
+			claimed,
+			foreign,
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("tar.test-zone.example.org", "tar.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "bar.loadbalancer.com", endpoint.RecordTypeCNAME, "other"),
+		},
+	}
+	expected := map[string][]*endpoint.Endpoint{
+		"Create": {},
+		"UpdateNew": {
+			newEndpointWithOwnerAndDescription("tar.test-zone.example.org", "new-tar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "\"heritage=external-dns,external-dns/owner=owner\""),
+		},
+		"UpdateOld": {
+			newEndpointWithOwnerAndDescription("tar.test-zone.example.org", "tar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "\"heritage=external-dns,external-dns/owner=owner\""),
+		},
+		"Delete": {},
+	}
+	p := newInMemoryProvider(nil, func(got *plan.Changes) {
+		mGot := map[string][]*endpoint.Endpoint{
+			"Create":    got.Create,
+			"UpdateNew": got.UpdateNew,
+			"UpdateOld": got.UpdateOld,
+			"Delete":    got.Delete,
+		}
+		assert.True(t, testutils.SamePlanChanges(mGot, expected))
+	})
+	r, err := NewAWSSDRegistry(p, "owner")
+	require.NoError(t, err)
+
+	err = r.ApplyChanges(context.Background(), changes)
+	require.NoError(t, err)
+}
+
 func newEndpointWithOwnerAndDescription(dnsName, target, recordType, ownerID string, description string) *endpoint.Endpoint {
 	e := endpoint.NewEndpoint(dnsName, recordType, target)
 	e.Labels[endpoint.OwnerLabelKey] = ownerID
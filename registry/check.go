@@ -0,0 +1,163 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// CheckFormatTable writes the report as a table
+	CheckFormatTable = "table"
+	// CheckFormatJSON writes the report as an indented JSON document
+	CheckFormatJSON = "json"
+)
+
+// RecordStatus classifies a record by its ownership.
+type RecordStatus string
+
+const (
+	// RecordOwned records are owned by the current instance, possibly after adopting them from a previous owner
+	RecordOwned RecordStatus = "owned"
+	// RecordForeign records are owned by another owner
+	RecordForeign RecordStatus = "foreign"
+	// RecordUnowned records aren't owned by anyone
+	RecordUnowned RecordStatus = "unowned"
+	// RecordOrphaned ownership records don't own any record
+	RecordOrphaned RecordStatus = "orphaned"
+	// RecordInconsistent records or ownership records can't be matched reliably, see the reason
+	RecordInconsistent RecordStatus = "inconsistent"
+)
+
+// CheckedRecord is a record or an ownership record classified by its ownership.
+type CheckedRecord struct {
+	DNSName       string       `json:"dnsName"`
+	RecordType    string       `json:"recordType"`
+	SetIdentifier string       `json:"setIdentifier,omitempty"`
+	Owner         string       `json:"owner,omitempty"`
+	Status        RecordStatus `json:"status"`
+	Reason        string       `json:"reason,omitempty"`
+}
+
+// Checker is implemented by the registries which can check the consistency of the records and their ownership records.
+type Checker interface {
+	Check(ctx context.Context) ([]CheckedRecord, error)
+}
+
+// Report is the outcome of checking the records of a registry.
+type Report struct {
+	Records []CheckedRecord      `json:"records"`
+	Summary map[RecordStatus]int `json:"summary"`
+}
+
+// Check classifies all records of the given registry without applying any change. The registries which don't implement
+// Checker only tell owned, foreign and unowned records apart, with an empty owner id every record is owned.
+func Check(ctx context.Context, r Registry, ownerID string) (*Report, error) {
+	var checked []CheckedRecord
+	if checker, ok := r.(Checker); ok {
+		var err error
+		if checked, err = checker.Check(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		records, err := r.Records(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			owner := record.Labels[endpoint.OwnerLabelKey]
+			status := classifyOwner(owner, func(owner string) bool { return ownerID == "" || owner == ownerID })
+			checked = append(checked, newCheckedRecord(record, owner, status, ""))
+		}
+	}
+
+	sort.SliceStable(checked, func(i, j int) bool {
+		a, b := checked[i], checked[j]
+		if a.DNSName != b.DNSName {
+			return a.DNSName < b.DNSName
+		}
+		if a.RecordType != b.RecordType {
+			return a.RecordType < b.RecordType
+		}
+		return a.SetIdentifier < b.SetIdentifier
+	})
+
+	report := &Report{Records: checked, Summary: map[RecordStatus]int{}}
+	if report.Records == nil {
+		report.Records = []CheckedRecord{}
+	}
+	for _, record := range report.Records {
+		report.Summary[record.Status]++
+	}
+	return report, nil
+}
+
+// Export writes the report to w in the given format.
+func (r *Report) Export(w io.Writer, format string) error {
+	switch format {
+	case CheckFormatJSON:
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case CheckFormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "STATUS\tNAME\tTYPE\tSET IDENTIFIER\tOWNER\tREASON")
+		for _, record := range r.Records {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", record.Status, record.DNSName, record.RecordType, record.SetIdentifier, record.Owner, record.Reason)
+		}
+		fmt.Fprintln(tw)
+		for _, status := range []RecordStatus{RecordOwned, RecordForeign, RecordUnowned, RecordOrphaned, RecordInconsistent} {
+			fmt.Fprintf(tw, "%s:\t%d\n", status, r.Summary[status])
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown check output format: %s", format)
+	}
+}
+
+// classifyOwner classifies a record with the given owner, isOwner tells whether the owner is the current instance.
+func classifyOwner(owner string, isOwner func(string) bool) RecordStatus {
+	switch {
+	case isOwner(owner):
+		return RecordOwned
+	case owner == "":
+		return RecordUnowned
+	default:
+		return RecordForeign
+	}
+}
+
+func newCheckedRecord(record *endpoint.Endpoint, owner string, status RecordStatus, reason string) CheckedRecord {
+	return CheckedRecord{
+		DNSName:       record.DNSName,
+		RecordType:    record.RecordType,
+		SetIdentifier: record.SetIdentifier,
+		Owner:         owner,
+		Status:        status,
+		Reason:        reason,
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func newCheckTestProvider(t *testing.T, records ...*endpoint.Endpoint) *inmemory.InMemoryProvider {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: records}))
+	return p
+}
+
+func TestCheckTXTRegistry(t *testing.T) {
+	p := newCheckTestProvider(t,
+		newEndpointWithOwner("owned.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("txt.a-owned.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		newEndpointWithOwner("previous.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("txt.previous.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		newEndpointWithOwner("foreign.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("txt.a-foreign.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner-2,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		newEndpointWithOwner("unowned.test-zone.example.org", "1.2.3.7", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("txt.a-orphaned.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		// the ownership record was written with another set identifier
+		newEndpointWithOwner("set.test-zone.example.org", "1.2.3.8", endpoint.RecordTypeA, "").WithSetIdentifier("new"),
+		newEndpointWithOwner("txt.a-set.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("old"),
+		// the ownership record was written with another prefix
+		newEndpointWithOwner("prefix.test-zone.example.org", "1.2.3.9", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("a-prefix.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		// a TXT record which isn't an ownership record
+		newEndpointWithOwner("txt.test-zone.example.org", "\"some text\"", endpoint.RecordTypeTXT, ""),
+	)
+	r, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Prefix: "txt."})
+	require.NoError(t, err)
+
+	report, err := Check(context.Background(), r, "owner")
+	require.NoError(t, err)
+
+	statuses := map[string]RecordStatus{}
+	for _, record := range report.Records {
+		statuses[record.DNSName+" "+record.RecordType] = record.Status
+	}
+	assert.Equal(t, map[string]RecordStatus{
+		"owned.test-zone.example.org A":            RecordOwned,
+		"txt.a-owned.test-zone.example.org TXT":    RecordOwned,
+		"previous.test-zone.example.org A":         RecordOwned,
+		"txt.previous.test-zone.example.org TXT":   RecordOwned,
+		"foreign.test-zone.example.org A":          RecordForeign,
+		"txt.a-foreign.test-zone.example.org TXT":  RecordForeign,
+		"unowned.test-zone.example.org A":          RecordUnowned,
+		"txt.a-orphaned.test-zone.example.org TXT": RecordOrphaned,
+		"set.test-zone.example.org A":              RecordInconsistent,
+		"txt.a-set.test-zone.example.org TXT":      RecordInconsistent,
+		"prefix.test-zone.example.org A":           RecordUnowned,
+		"a-prefix.test-zone.example.org TXT":       RecordInconsistent,
+		"txt.test-zone.example.org TXT":            RecordUnowned,
+	}, statuses)
+	assert.Equal(t, map[RecordStatus]int{
+		RecordOwned:        4,
+		RecordForeign:      2,
+		RecordUnowned:      3,
+		RecordOrphaned:     1,
+		RecordInconsistent: 3,
+	}, report.Summary)
+
+	// nothing has been changed
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, records, 13)
+}
+
+func TestCheckTXTRegistryKeyring(t *testing.T) {
+	keyring := newTestTXTKeyring(t, false, false, testTXTKey)
+	p := newCheckTestProvider(t,
+		newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("a-bar.test-zone.example.org", sealTXTValue(t, keyring, "a-bar.test-zone.example.org", endpoint.Labels{endpoint.OwnerLabelKey: "owner", txtFormatLabelKey: txtFormatVersion}), endpoint.RecordTypeTXT, ""),
+	)
+	r, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Keyring: keyring})
+	require.NoError(t, err)
+
+	report, err := Check(context.Background(), r, "owner")
+	require.NoError(t, err)
+	assert.Equal(t, []CheckedRecord{
+		{DNSName: "a-bar.test-zone.example.org", RecordType: endpoint.RecordTypeTXT, Owner: "owner", Status: RecordOwned},
+		{DNSName: "a-foo.test-zone.example.org", RecordType: endpoint.RecordTypeTXT, Owner: "owner", Status: RecordInconsistent, Reason: "neither signed nor encrypted"},
+		{DNSName: "bar.test-zone.example.org", RecordType: endpoint.RecordTypeA, Owner: "owner", Status: RecordOwned},
+		{DNSName: "foo.test-zone.example.org", RecordType: endpoint.RecordTypeA, Status: RecordUnowned},
+	}, report.Records)
+}
+
+func TestCheckRegistryWithoutChecker(t *testing.T) {
+	p := newCheckTestProvider(t,
+		newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+	)
+	r, err := NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	// without owner id every record is owned
+	report, err := Check(context.Background(), r, "")
+	require.NoError(t, err)
+	assert.Equal(t, []CheckedRecord{
+		{DNSName: "foo.test-zone.example.org", RecordType: endpoint.RecordTypeA, Status: RecordOwned},
+	}, report.Records)
+
+	report, err = Check(context.Background(), r, "owner")
+	require.NoError(t, err)
+	assert.Equal(t, map[RecordStatus]int{RecordUnowned: 1}, report.Summary)
+}
+
+func TestReportExport(t *testing.T) {
+	report := &Report{
+		Records: []CheckedRecord{
+			{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeA, Owner: "owner", Status: RecordOwned},
+			{DNSName: "a-bar.example.org", RecordType: endpoint.RecordTypeTXT, SetIdentifier: "old", Owner: "owner", Status: RecordInconsistent, Reason: "mismatch"},
+		},
+		Summary: map[RecordStatus]int{RecordOwned: 1, RecordInconsistent: 1},
+	}
+
+	var table bytes.Buffer
+	require.NoError(t, report.Export(&table, CheckFormatTable))
+	lines := strings.Split(table.String(), "\n")
+	assert.Equal(t, "STATUS        NAME               TYPE  SET IDENTIFIER  OWNER  REASON", strings.TrimSpace(lines[0]))
+	assert.Equal(t, "owned         foo.example.org    A                     owner", strings.TrimSpace(lines[1]))
+	assert.Equal(t, "inconsistent  a-bar.example.org  TXT   old             owner  mismatch", strings.TrimSpace(lines[2]))
+	assert.Contains(t, table.String(), "orphaned:      0")
+
+	var data bytes.Buffer
+	require.NoError(t, report.Export(&data, CheckFormatJSON))
+	exported := &Report{}
+	require.NoError(t, json.Unmarshal(data.Bytes(), exported))
+	assert.Equal(t, report, exported)
+	assert.Contains(t, data.String(), `"status": "inconsistent"`)
+
+	assert.Error(t, report.Export(&data, "xml"))
+}
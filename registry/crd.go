@@ -0,0 +1,291 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const (
+	ownershipRecordAPIVersion = "externaldns.k8s.io/v1alpha1"
+	ownershipRecordKind       = "DNSOwnershipRecord"
+)
+
+// errOwnedByAnotherOwner is returned when storing the ownership of a record whose ownership record belongs to another owner.
+var errOwnedByAnotherOwner = errors.New("the ownership record belongs to another owner")
+
+// OwnershipRecordResource is the cluster scoped custom resource holding the ownership of a DNS record
+var OwnershipRecordResource = schema.GroupVersionResource{Group: "externaldns.k8s.io", Version: "v1alpha1", Resource: "dnsownershiprecords"}
+
+// CRDRegistry implements registry interface with ownership information stored in the Kubernetes cluster,
+// as a DNSOwnershipRecord custom resource per owned record, instead of the DNS provider.
+type CRDRegistry struct {
+	provider provider.Provider
+	client   dynamic.Interface
+	ownerID  string
+}
+
+// NewCRDRegistry returns new CRDRegistry object
+func NewCRDRegistry(provider provider.Provider, client dynamic.Interface, ownerID string) (*CRDRegistry, error) {
+	if ownerID == "" {
+		return nil, errors.New("owner id cannot be empty")
+	}
+	if client == nil {
+		return nil, errors.New("kubernetes client cannot be nil")
+	}
+
+	return &CRDRegistry{
+		provider: provider,
+		client:   client,
+		ownerID:  ownerID,
+	}, nil
+}
+
+// Records returns the current records from the DNS provider, with the labels of their ownership records.
+func (im *CRDRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	records, err := im.provider.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := im.client.Resource(OwnershipRecordResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the ownership records: %w", err)
+	}
+
+	labels := map[string]endpoint.Labels{}
+	for _, item := range list.Items {
+		dnsName, _, _ := unstructured.NestedString(item.Object, "spec", "dnsName")
+		recordType, _, _ := unstructured.NestedString(item.Object, "spec", "recordType")
+		setIdentifier, _, _ := unstructured.NestedString(item.Object, "spec", "setIdentifier")
+		itemLabels, _, err := unstructured.NestedStringMap(item.Object, "spec", "labels")
+		if err != nil {
+			log.Warnf("Ignoring the invalid ownership record %s: %v", item.GetName(), err)
+			continue
+		}
+		labels[ownershipRecordName(dnsName, recordType, setIdentifier)] = itemLabels
+	}
+
+	for _, record := range records {
+		if record.Labels == nil {
+			record.Labels = endpoint.NewLabels()
+		}
+		for k, v := range labels[ownershipRecordName(record.DNSName, record.RecordType, record.SetIdentifier)] {
+			record.Labels[k] = v
+		}
+	}
+
+	return records, nil
+}
+
+// ApplyChanges filters out the changes of records which aren't owned and updates the ownership records along with the DNS provider.
+// The ownership of created and updated records is stored first, so that a failure never leaves a record without owner,
+// and the ownership records created for them are removed if the DNS provider fails. The records whose ownership record
+// belongs to another owner, e.g. of another instance which created it concurrently, are skipped.
+// The ownership of deleted records is removed once they have been deleted.
+func (im *CRDRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	claimRecords(im.ownerID, changes)
+	filteredChanges := &plan.Changes{
+		Create:    changes.Create,
+		UpdateNew: filterOwnedRecords(im.ownerID, changes.UpdateNew),
+		UpdateOld: filterOwnedRecords(im.ownerID, changes.UpdateOld),
+		Delete:    filterOwnedRecords(im.ownerID, changes.Delete),
+	}
+
+	for _, r := range filteredChanges.Create {
+		if r.Labels == nil {
+			r.Labels = make(map[string]string)
+		}
+		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
+	}
+
+	created := []*endpoint.Endpoint{}
+	skipped := map[string]bool{}
+	for _, endpoints := range []*[]*endpoint.Endpoint{&filteredChanges.Create, &filteredChanges.UpdateNew} {
+		stored := []*endpoint.Endpoint{}
+		for _, r := range *endpoints {
+			isNew, err := im.storeOwnership(ctx, r)
+			if errors.Is(err, errOwnedByAnotherOwner) {
+				log.Warnf("Skipping %s %s: %v", r.RecordType, r.DNSName, err)
+				skipped[claimKey(r)] = true
+				continue
+			}
+			if err != nil {
+				im.removeOwnerships(ctx, created)
+				return err
+			}
+			if isNew {
+				created = append(created, r)
+			}
+			stored = append(stored, r)
+		}
+		*endpoints = stored
+	}
+	if len(skipped) > 0 {
+		updateOld := []*endpoint.Endpoint{}
+		for _, r := range filteredChanges.UpdateOld {
+			if !skipped[claimKey(r)] {
+				updateOld = append(updateOld, r)
+			}
+		}
+		filteredChanges.UpdateOld = updateOld
+	}
+
+	if err := applyChanges(ctx, im.provider, filteredChanges); err != nil {
+		// the records may not have been created, their ownership records would never be removed
+		im.removeOwnerships(ctx, created)
+		return err
+	}
+
+	for _, r := range filteredChanges.Delete {
+		if err := im.removeOwnership(ctx, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check classifies the records of the DNS provider and the ownership records which don't own any record, without applying any change.
+func (im *CRDRegistry) Check(ctx context.Context) ([]CheckedRecord, error) {
+	records, err := im.provider.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := im.client.Resource(OwnershipRecordResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the ownership records: %w", err)
+	}
+
+	existing := map[string]bool{}
+	checked := []CheckedRecord{}
+	for _, record := range records {
+		existing[ownershipRecordName(record.DNSName, record.RecordType, record.SetIdentifier)] = true
+	}
+
+	owners := map[string]string{}
+	for _, item := range list.Items {
+		dnsName, _, _ := unstructured.NestedString(item.Object, "spec", "dnsName")
+		recordType, _, _ := unstructured.NestedString(item.Object, "spec", "recordType")
+		setIdentifier, _, _ := unstructured.NestedString(item.Object, "spec", "setIdentifier")
+		owned := &endpoint.Endpoint{DNSName: dnsName, RecordType: recordType, SetIdentifier: setIdentifier}
+		labels, _, err := unstructured.NestedStringMap(item.Object, "spec", "labels")
+		if err != nil {
+			checked = append(checked, newCheckedRecord(owned, "", RecordInconsistent, fmt.Sprintf("invalid ownership record %s: %v", item.GetName(), err)))
+			continue
+		}
+		name := ownershipRecordName(dnsName, recordType, setIdentifier)
+		if !existing[name] {
+			checked = append(checked, newCheckedRecord(owned, labels[endpoint.OwnerLabelKey], RecordOrphaned, fmt.Sprintf("ownership record %s", item.GetName())))
+			continue
+		}
+		owners[name] = labels[endpoint.OwnerLabelKey]
+	}
+
+	for _, record := range records {
+		owner := owners[ownershipRecordName(record.DNSName, record.RecordType, record.SetIdentifier)]
+		checked = append(checked, newCheckedRecord(record, owner, classifyOwner(owner, func(owner string) bool { return owner == im.ownerID }), ""))
+	}
+	return checked, nil
+}
+
+// PropertyValuesEqual compares two attribute values for equality
+func (im *CRDRegistry) PropertyValuesEqual(name string, previous string, current string) bool {
+	return im.provider.PropertyValuesEqual(name, previous, current)
+}
+
+// storeOwnership creates or updates the ownership record of a record, and returns whether it was created.
+// An existing ownership record is only updated if it belongs to this owner, or to no owner at all.
+func (im *CRDRegistry) storeOwnership(ctx context.Context, r *endpoint.Endpoint) (bool, error) {
+	labels := map[string]interface{}{}
+	for k, v := range ownershipLabels(r.Labels) {
+		labels[k] = v
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": ownershipRecordAPIVersion,
+		"kind":       ownershipRecordKind,
+		"metadata": map[string]interface{}{
+			"name": ownershipRecordName(r.DNSName, r.RecordType, r.SetIdentifier),
+		},
+		"spec": map[string]interface{}{
+			"dnsName":       r.DNSName,
+			"recordType":    r.RecordType,
+			"setIdentifier": r.SetIdentifier,
+			"labels":        labels,
+		},
+	}}
+
+	client := im.client.Resource(OwnershipRecordResource)
+	_, err := client.Create(ctx, obj, metav1.CreateOptions{})
+	if err == nil {
+		return true, nil
+	}
+	if kubeerrors.IsAlreadyExists(err) {
+		var existing *unstructured.Unstructured
+		existing, err = client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err == nil {
+			owner, _, _ := unstructured.NestedString(existing.Object, "spec", "labels", endpoint.OwnerLabelKey)
+			if owner != "" && owner != im.ownerID {
+				return false, fmt.Errorf("%w (%s)", errOwnedByAnotherOwner, owner)
+			}
+			obj.SetResourceVersion(existing.GetResourceVersion())
+			_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to store the ownership of %s %s: %w", r.RecordType, r.DNSName, err)
+	}
+	return false, nil
+}
+
+func (im *CRDRegistry) removeOwnership(ctx context.Context, r *endpoint.Endpoint) error {
+	name := ownershipRecordName(r.DNSName, r.RecordType, r.SetIdentifier)
+	err := im.client.Resource(OwnershipRecordResource).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !kubeerrors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove the ownership of %s %s: %w", r.RecordType, r.DNSName, err)
+	}
+	return nil
+}
+
+// removeOwnerships removes the ownership records of records which failed to be created. The failures are only logged,
+// as the error which caused the removal is returned.
+func (im *CRDRegistry) removeOwnerships(ctx context.Context, endpoints []*endpoint.Endpoint) {
+	for _, r := range endpoints {
+		if err := im.removeOwnership(ctx, r); err != nil {
+			log.Warnf("Failed to clean up: %v", err)
+		}
+	}
+}
+
+// ownershipRecordName returns the name of the ownership record of a DNS record. DNS names can't be used as is,
+// e.g. wildcards aren't valid in object names, hence the name is derived from a hash of the record's identity.
+func ownershipRecordName(dnsName, recordType, setIdentifier string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprintf("%s::%s::%s", dnsName, recordType, setIdentifier))))[:32]
+}
@@ -0,0 +1,422 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func newOwnershipRecord(dnsName, recordType, setIdentifier string, labels map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": ownershipRecordAPIVersion,
+		"kind":       ownershipRecordKind,
+		"metadata": map[string]interface{}{
+			"name": ownershipRecordName(dnsName, recordType, setIdentifier),
+		},
+		"spec": map[string]interface{}{
+			"dnsName":       dnsName,
+			"recordType":    recordType,
+			"setIdentifier": setIdentifier,
+			"labels":        labels,
+		},
+	}}
+}
+
+func TestCRDRegistry(t *testing.T) {
+	t.Run("TestNewCRDRegistry", testCRDRegistryNew)
+	t.Run("TestRecords", testCRDRegistryRecords)
+	t.Run("TestApplyChanges", testCRDRegistryApplyChanges)
+	t.Run("TestApplyChangesFailure", testCRDRegistryApplyChangesFailure)
+	t.Run("TestOtherOwner", testCRDRegistryOtherOwner)
+	t.Run("TestClaim", testCRDRegistryClaim)
+	t.Run("TestCheck", testCRDRegistryCheck)
+}
+
+func testCRDRegistryNew(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme())
+
+	_, err := NewCRDRegistry(p, client, "")
+	require.Error(t, err)
+
+	_, err = NewCRDRegistry(p, nil, "owner")
+	require.Error(t, err)
+
+	r, err := NewCRDRegistry(p, client, "owner")
+	require.NoError(t, err)
+	assert.Equal(t, p, r.provider)
+	assert.Equal(t, "owner", r.ownerID)
+}
+
+func testCRDRegistryRecords(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "2001:db8::4", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "my-domain.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("multiple.test-zone.example.org", "lb1.loadbalancer.com", endpoint.RecordTypeCNAME, "").WithSetIdentifier("test-set-1"),
+			newEndpointWithOwner("multiple.test-zone.example.org", "lb2.loadbalancer.com", endpoint.RecordTypeCNAME, "").WithSetIdentifier("test-set-2"),
+			newEndpointWithOwner("*.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+		},
+	})
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		newOwnershipRecord("foo.test-zone.example.org", endpoint.RecordTypeA, "", map[string]interface{}{
+			endpoint.OwnerLabelKey:    "owner",
+			endpoint.ResourceLabelKey: "ingress/default/my-ingress",
+		}),
+		newOwnershipRecord("bar.test-zone.example.org", endpoint.RecordTypeCNAME, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner-2",
+		}),
+		newOwnershipRecord("multiple.test-zone.example.org", endpoint.RecordTypeCNAME, "test-set-2", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner",
+		}),
+		newOwnershipRecord("*.test-zone.example.org", endpoint.RecordTypeA, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner",
+		}),
+		// the record doesn't exist anymore
+		newOwnershipRecord("gone.test-zone.example.org", endpoint.RecordTypeA, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner",
+		}),
+	)
+
+	expectedRecords := []*endpoint.Endpoint{
+		{
+			DNSName:    "foo.test-zone.example.org",
+			Targets:    endpoint.Targets{"1.2.3.4"},
+			RecordType: endpoint.RecordTypeA,
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey:    "owner",
+				endpoint.ResourceLabelKey: "ingress/default/my-ingress",
+			},
+		},
+		{
+			DNSName:    "foo.test-zone.example.org",
+			Targets:    endpoint.Targets{"2001:db8::4"},
+			RecordType: endpoint.RecordTypeAAAA,
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey: "",
+			},
+		},
+		{
+			DNSName:    "bar.test-zone.example.org",
+			Targets:    endpoint.Targets{"my-domain.com"},
+			RecordType: endpoint.RecordTypeCNAME,
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey: "owner-2",
+			},
+		},
+		{
+			DNSName:       "multiple.test-zone.example.org",
+			Targets:       endpoint.Targets{"lb1.loadbalancer.com"},
+			RecordType:    endpoint.RecordTypeCNAME,
+			SetIdentifier: "test-set-1",
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey: "",
+			},
+		},
+		{
+			DNSName:       "multiple.test-zone.example.org",
+			Targets:       endpoint.Targets{"lb2.loadbalancer.com"},
+			RecordType:    endpoint.RecordTypeCNAME,
+			SetIdentifier: "test-set-2",
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey: "owner",
+			},
+		},
+		{
+			DNSName:    "*.test-zone.example.org",
+			Targets:    endpoint.Targets{"1.2.3.5"},
+			RecordType: endpoint.RecordTypeA,
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey: "owner",
+			},
+		},
+	}
+
+	r, _ := NewCRDRegistry(p, client, "owner")
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+
+	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
+}
+
+func testCRDRegistryApplyChanges(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("tar.test-zone.example.org", "tar.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("foobar.test-zone.example.org", "foobar.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("other.test-zone.example.org", "other.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		},
+	})
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		newOwnershipRecord("tar.test-zone.example.org", endpoint.RecordTypeCNAME, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner",
+		}),
+		newOwnershipRecord("foobar.test-zone.example.org", endpoint.RecordTypeCNAME, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner",
+		}),
+		newOwnershipRecord("other.test-zone.example.org", endpoint.RecordTypeCNAME, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner-2",
+		}),
+	)
+	r, _ := NewCRDRegistry(p, client, "owner")
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", endpoint.RecordTypeCNAME, "", "ingress/default/my-ingress"),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("foobar.test-zone.example.org", "foobar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+			newEndpointWithOwner("other.test-zone.example.org", "other.loadbalancer.com", endpoint.RecordTypeCNAME, "owner-2"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("tar.test-zone.example.org", "new-tar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress-2"),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("tar.test-zone.example.org", "tar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+		},
+	}
+	expected := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress"),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("foobar.test-zone.example.org", "foobar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("tar.test-zone.example.org", "new-tar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress-2"),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("tar.test-zone.example.org", "tar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+		},
+	}
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		mExpected := map[string][]*endpoint.Endpoint{
+			"Create":    expected.Create,
+			"UpdateNew": expected.UpdateNew,
+			"UpdateOld": expected.UpdateOld,
+			"Delete":    expected.Delete,
+		}
+		mGot := map[string][]*endpoint.Endpoint{
+			"Create":    got.Create,
+			"UpdateNew": got.UpdateNew,
+			"UpdateOld": got.UpdateOld,
+			"Delete":    got.Delete,
+		}
+		assert.True(t, testutils.SamePlanChanges(mGot, mExpected))
+	}
+	require.NoError(t, r.ApplyChanges(ctx, changes))
+
+	list, err := client.Resource(OwnershipRecordResource).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	owners := map[string]map[string]string{}
+	for _, item := range list.Items {
+		dnsName, _, _ := unstructured.NestedString(item.Object, "spec", "dnsName")
+		labels, _, _ := unstructured.NestedStringMap(item.Object, "spec", "labels")
+		owners[dnsName] = labels
+	}
+	assert.Equal(t, map[string]map[string]string{
+		"new-record-1.test-zone.example.org": {endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/my-ingress"},
+		"tar.test-zone.example.org":          {endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/my-ingress-2"},
+		"other.test-zone.example.org":        {endpoint.OwnerLabelKey: "owner-2"},
+	}, owners)
+
+	// the registry reads its own changes back
+	p.OnApplyChanges = nil
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	for _, record := range records {
+		if record.DNSName == "new-record-1.test-zone.example.org" {
+			assert.Equal(t, "owner", record.Labels[endpoint.OwnerLabelKey])
+		}
+	}
+}
+
+func testCRDRegistryApplyChangesFailure(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		newOwnershipRecord("other.test-zone.example.org", endpoint.RecordTypeCNAME, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner-2",
+		}),
+	)
+	r, _ := NewCRDRegistry(p, client, "owner")
+
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		// the ownership is stored before the record is created
+		_, err := client.Resource(OwnershipRecordResource).Get(ctx, ownershipRecordName("new.test-zone.example.org", endpoint.RecordTypeA, ""), metav1.GetOptions{})
+		assert.NoError(t, err)
+	}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("new.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	}))
+
+	// the ownership of a record which failed to be deleted is kept
+	err := r.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("missing.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("new.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+		},
+	})
+	require.Error(t, err)
+	_, err = client.Resource(OwnershipRecordResource).Get(ctx, ownershipRecordName("new.test-zone.example.org", endpoint.RecordTypeA, ""), metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	// the ownership of a record which failed to be created is removed
+	p.OnApplyChanges = func(ctx context.Context, changes *plan.Changes) {}
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("exists.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+	}))
+	err = r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("exists.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	})
+	require.Error(t, err)
+	_, err = client.Resource(OwnershipRecordResource).Get(ctx, ownershipRecordName("exists.test-zone.example.org", endpoint.RecordTypeA, ""), metav1.GetOptions{})
+	assert.True(t, kubeerrors.IsNotFound(err), "should remove the ownership record")
+	_, err = client.Resource(OwnershipRecordResource).Get(ctx, ownershipRecordName("new.test-zone.example.org", endpoint.RecordTypeA, ""), metav1.GetOptions{})
+	assert.NoError(t, err, "should keep the ownership records which existed before")
+}
+
+func testCRDRegistryOtherOwner(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		newOwnershipRecord("other.test-zone.example.org", endpoint.RecordTypeCNAME, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner-2",
+		}),
+	)
+	r, _ := NewCRDRegistry(p, client, "owner")
+
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		// the record whose ownership record belongs to another owner is skipped
+		require.Len(t, got.Create, 1)
+		assert.Equal(t, "new.test-zone.example.org", got.Create[0].DNSName)
+	}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("other.test-zone.example.org", "other.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("new.test-zone.example.org", "new.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		},
+	}))
+
+	obj, err := client.Resource(OwnershipRecordResource).Get(ctx, ownershipRecordName("other.test-zone.example.org", endpoint.RecordTypeCNAME, ""), metav1.GetOptions{})
+	require.NoError(t, err)
+	owner, _, _ := unstructured.NestedString(obj.Object, "spec", "labels", endpoint.OwnerLabelKey)
+	assert.Equal(t, "owner-2", owner, "should not take over the ownership")
+}
+
+func testCRDRegistryClaim(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		},
+	})
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		newOwnershipRecord("other.test-zone.example.org", endpoint.RecordTypeCNAME, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner-2",
+		}),
+	)
+	r, _ := NewCRDRegistry(p, client, "owner")
+
+	claim := newEndpointWithOwnerAndLabels("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "", endpoint.Labels{
+		endpoint.ResourceLabelKey: "ingress/default/foo",
+		endpoint.ClaimLabelKey:    "true",
+		endpoint.PriorityLabelKey: "10",
+	})
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		UpdateNew: []*endpoint.Endpoint{claim},
+		UpdateOld: []*endpoint.Endpoint{newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "")},
+	}))
+
+	obj, err := client.Resource(OwnershipRecordResource).Get(ctx, ownershipRecordName("foo.test-zone.example.org", endpoint.RecordTypeCNAME, ""), metav1.GetOptions{})
+	require.NoError(t, err)
+	labels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "labels")
+	assert.Equal(t, map[string]string{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/foo"}, labels)
+}
+
+func testCRDRegistryCheck(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "bar.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("baz.test-zone.example.org", "baz.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		},
+	})
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		newOwnershipRecord("foo.test-zone.example.org", endpoint.RecordTypeCNAME, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner",
+		}),
+		newOwnershipRecord("bar.test-zone.example.org", endpoint.RecordTypeCNAME, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner-2",
+		}),
+		newOwnershipRecord("gone.test-zone.example.org", endpoint.RecordTypeCNAME, "", map[string]interface{}{
+			endpoint.OwnerLabelKey: "owner",
+		}),
+	)
+	r, _ := NewCRDRegistry(p, client, "owner")
+
+	report, err := Check(ctx, r, "owner")
+	require.NoError(t, err)
+	assert.Equal(t, []CheckedRecord{
+		{DNSName: "bar.test-zone.example.org", RecordType: endpoint.RecordTypeCNAME, Owner: "owner-2", Status: RecordForeign},
+		{DNSName: "baz.test-zone.example.org", RecordType: endpoint.RecordTypeCNAME, Status: RecordUnowned},
+		{DNSName: "foo.test-zone.example.org", RecordType: endpoint.RecordTypeCNAME, Owner: "owner", Status: RecordOwned},
+		{DNSName: "gone.test-zone.example.org", RecordType: endpoint.RecordTypeCNAME, Owner: "owner", Status: RecordOrphaned,
+			Reason: "ownership record " + ownershipRecordName("gone.test-zone.example.org", endpoint.RecordTypeCNAME, "")},
+	}, report.Records)
+}
+
+func TestOwnershipRecordName(t *testing.T) {
+	name := ownershipRecordName("*.example.org", endpoint.RecordTypeA, "")
+	assert.Len(t, name, 32)
+	assert.Regexp(t, "^[0-9a-f]+$", name)
+	assert.Equal(t, name, ownershipRecordName("*.example.org", endpoint.RecordTypeA, ""))
+	assert.NotEqual(t, name, ownershipRecordName("*.example.org", endpoint.RecordTypeAAAA, ""))
+	assert.NotEqual(t, name, ownershipRecordName("*.example.org", endpoint.RecordTypeA, "set-1"))
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// fileRegistryVersion is the version of the format of the file holding the ownership records
+const fileRegistryVersion = 1
+
+// FileRegistry implements registry interface with ownership information stored in a local JSON file
+// instead of the DNS provider, e.g. for deployments outside of Kubernetes. The file is replaced atomically
+// and changed under an exclusive lock, so that several instances can share it on a single host.
+type FileRegistry struct {
+	provider provider.Provider
+	path     string
+	ownerID  string
+}
+
+// fileOwnershipRecord is the ownership of a DNS record stored in the file.
+type fileOwnershipRecord struct {
+	DNSName       string          `json:"dnsName"`
+	RecordType    string          `json:"recordType"`
+	SetIdentifier string          `json:"setIdentifier,omitempty"`
+	Labels        endpoint.Labels `json:"labels"`
+}
+
+// fileOwnershipRecords is the content of the file.
+type fileOwnershipRecords struct {
+	Version int                    `json:"version"`
+	Records []*fileOwnershipRecord `json:"records"`
+}
+
+// NewFileRegistry returns new FileRegistry object
+func NewFileRegistry(provider provider.Provider, path, ownerID string) (*FileRegistry, error) {
+	if ownerID == "" {
+		return nil, errors.New("owner id cannot be empty")
+	}
+	if path == "" {
+		return nil, errors.New("file registry path cannot be empty")
+	}
+
+	return &FileRegistry{
+		provider: provider,
+		path:     path,
+		ownerID:  ownerID,
+	}, nil
+}
+
+// Records returns the current records from the DNS provider, with the labels of their ownership records.
+func (im *FileRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	records, err := im.provider.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerships, err := im.read()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.Labels == nil {
+			record.Labels = endpoint.NewLabels()
+		}
+		if ownership, ok := ownerships[versionedOwnershipKey(record)]; ok {
+			for k, v := range ownership.Labels {
+				record.Labels[k] = v
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// ApplyChanges filters out the changes of records which aren't owned and updates the ownership records along with the DNS provider.
+// The ownership of created and updated records is stored first, so that a failure never leaves a record without owner,
+// and the ownership records created for them are removed if the DNS provider fails. The records whose ownership record
+// belongs to another owner, e.g. of another instance which created it concurrently, are skipped.
+// The ownership of deleted records is removed once they have been deleted. The file is locked meanwhile.
+func (im *FileRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	claimRecords(im.ownerID, changes)
+	filteredChanges := &plan.Changes{
+		Create:    changes.Create,
+		UpdateNew: filterOwnedRecords(im.ownerID, changes.UpdateNew),
+		UpdateOld: filterOwnedRecords(im.ownerID, changes.UpdateOld),
+		Delete:    filterOwnedRecords(im.ownerID, changes.Delete),
+	}
+
+	for _, r := range filteredChanges.Create {
+		if r.Labels == nil {
+			r.Labels = make(map[string]string)
+		}
+		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
+	}
+
+	unlock, err := lockFile(im.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock the ownership records: %w", err)
+	}
+	defer unlock()
+
+	ownerships, err := im.read()
+	if err != nil {
+		return err
+	}
+
+	created := []string{}
+	skipped := map[string]bool{}
+	for _, endpoints := range []*[]*endpoint.Endpoint{&filteredChanges.Create, &filteredChanges.UpdateNew} {
+		stored := []*endpoint.Endpoint{}
+		for _, r := range *endpoints {
+			key := versionedOwnershipKey(r)
+			existing, exists := ownerships[key]
+			if exists {
+				if owner := existing.Labels[endpoint.OwnerLabelKey]; owner != "" && owner != im.ownerID {
+					log.Warnf("Skipping %s %s: the ownership record belongs to another owner (%s)", r.RecordType, r.DNSName, owner)
+					skipped[claimKey(r)] = true
+					continue
+				}
+			} else {
+				created = append(created, key)
+			}
+			labels := ownershipLabels(r.Labels)
+			ownerships[key] = &fileOwnershipRecord{DNSName: r.DNSName, RecordType: r.RecordType, SetIdentifier: r.SetIdentifier, Labels: labels}
+			stored = append(stored, r)
+		}
+		*endpoints = stored
+	}
+	if len(skipped) > 0 {
+		updateOld := []*endpoint.Endpoint{}
+		for _, r := range filteredChanges.UpdateOld {
+			if !skipped[claimKey(r)] {
+				updateOld = append(updateOld, r)
+			}
+		}
+		filteredChanges.UpdateOld = updateOld
+	}
+	if len(filteredChanges.Create) > 0 || len(filteredChanges.UpdateNew) > 0 {
+		if err := im.write(ownerships); err != nil {
+			return err
+		}
+	}
+
+	if err := applyChanges(ctx, im.provider, filteredChanges); err != nil {
+		// the records may not have been created, their ownership records would never be removed
+		if len(created) > 0 {
+			for _, key := range created {
+				delete(ownerships, key)
+			}
+			if err := im.write(ownerships); err != nil {
+				log.Warnf("Failed to clean up: %v", err)
+			}
+		}
+		return err
+	}
+
+	if len(filteredChanges.Delete) == 0 {
+		return nil
+	}
+	for _, r := range filteredChanges.Delete {
+		delete(ownerships, versionedOwnershipKey(r))
+	}
+	return im.write(ownerships)
+}
+
+// Check classifies the records of the DNS provider and the ownership records which don't own any record, without applying any change.
+func (im *FileRegistry) Check(ctx context.Context) ([]CheckedRecord, error) {
+	records, err := im.provider.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ownerships, err := im.read()
+	if err != nil {
+		return nil, err
+	}
+
+	checked := []CheckedRecord{}
+	for _, record := range records {
+		owner := ""
+		if ownership, ok := ownerships[versionedOwnershipKey(record)]; ok {
+			owner = ownership.Labels[endpoint.OwnerLabelKey]
+			delete(ownerships, versionedOwnershipKey(record))
+		}
+		checked = append(checked, newCheckedRecord(record, owner, classifyOwner(owner, func(owner string) bool { return owner == im.ownerID }), ""))
+	}
+	for _, ownership := range ownerships {
+		owned := &endpoint.Endpoint{DNSName: ownership.DNSName, RecordType: ownership.RecordType, SetIdentifier: ownership.SetIdentifier}
+		checked = append(checked, newCheckedRecord(owned, ownership.Labels[endpoint.OwnerLabelKey], RecordOrphaned, "ownership record in "+im.path))
+	}
+	return checked, nil
+}
+
+// PropertyValuesEqual compares two attribute values for equality
+func (im *FileRegistry) PropertyValuesEqual(name string, previous string, current string) bool {
+	return im.provider.PropertyValuesEqual(name, previous, current)
+}
+
+// read returns the ownership records stored in the file keyed like the versioned ownership records,
+// none if the file doesn't exist yet.
+func (im *FileRegistry) read() (map[string]*fileOwnershipRecord, error) {
+	ownerships := map[string]*fileOwnershipRecord{}
+	data, err := ioutil.ReadFile(im.path)
+	if os.IsNotExist(err) {
+		return ownerships, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the ownership records: %w", err)
+	}
+
+	content := &fileOwnershipRecords{}
+	if err := json.Unmarshal(data, content); err != nil {
+		return nil, fmt.Errorf("failed to parse the ownership records in %s: %w", im.path, err)
+	}
+	if content.Version != fileRegistryVersion {
+		return nil, fmt.Errorf("unsupported version %d of the ownership records in %s", content.Version, im.path)
+	}
+	for _, ownership := range content.Records {
+		ownerships[versionedOwnershipKey(&endpoint.Endpoint{DNSName: ownership.DNSName, RecordType: ownership.RecordType, SetIdentifier: ownership.SetIdentifier})] = ownership
+	}
+	return ownerships, nil
+}
+
+// write replaces the file with the given ownership records. They're written to a temporary file in the same directory
+// first, which is renamed once synced to the disk, so that the file is never left partially written.
+func (im *FileRegistry) write(ownerships map[string]*fileOwnershipRecord) error {
+	keys := make([]string, 0, len(ownerships))
+	for key := range ownerships {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	content := &fileOwnershipRecords{Version: fileRegistryVersion, Records: []*fileOwnershipRecord{}}
+	for _, key := range keys {
+		content.Records = append(content.Records, ownerships[key])
+	}
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(im.path), filepath.Base(im.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write the ownership records: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the ownership records: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the ownership records: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the ownership records: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), im.path); err != nil {
+		return fmt.Errorf("failed to replace the ownership records: %w", err)
+	}
+	return nil
+}
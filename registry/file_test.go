@@ -0,0 +1,359 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func newFileRegistryPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "file-registry")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "ownership.json")
+}
+
+func TestFileRegistry(t *testing.T) {
+	t.Run("TestNewFileRegistry", testFileRegistryNew)
+	t.Run("TestRecords", testFileRegistryRecords)
+	t.Run("TestApplyChanges", testFileRegistryApplyChanges)
+	t.Run("TestApplyChangesFailure", testFileRegistryApplyChangesFailure)
+	t.Run("TestOtherOwner", testFileRegistryOtherOwner)
+	t.Run("TestConcurrentChanges", testFileRegistryConcurrentChanges)
+	t.Run("TestInvalidFile", testFileRegistryInvalidFile)
+	t.Run("TestCheck", testFileRegistryCheck)
+}
+
+func testFileRegistryNew(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+
+	_, err := NewFileRegistry(p, "ownership.json", "")
+	require.Error(t, err)
+
+	_, err = NewFileRegistry(p, "", "owner")
+	require.Error(t, err)
+
+	r, err := NewFileRegistry(p, "ownership.json", "owner")
+	require.NoError(t, err)
+	assert.Equal(t, p, r.provider)
+	assert.Equal(t, "ownership.json", r.path)
+	assert.Equal(t, "owner", r.ownerID)
+}
+
+func testFileRegistryRecords(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "2001:db8::4", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "my-domain.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("multiple.test-zone.example.org", "lb1.loadbalancer.com", endpoint.RecordTypeCNAME, "").WithSetIdentifier("test-set-1"),
+			newEndpointWithOwner("multiple.test-zone.example.org", "lb2.loadbalancer.com", endpoint.RecordTypeCNAME, "").WithSetIdentifier("test-set-2"),
+		},
+	})
+	path := newFileRegistryPath(t)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{
+  "version": 1,
+  "records": [
+    {"dnsName": "foo.test-zone.example.org", "recordType": "A", "labels": {"owner": "owner", "resource": "ingress/default/my-ingress"}},
+    {"dnsName": "bar.test-zone.example.org", "recordType": "CNAME", "labels": {"owner": "owner-2"}},
+    {"dnsName": "multiple.test-zone.example.org", "recordType": "CNAME", "setIdentifier": "test-set-2", "labels": {"owner": "owner"}},
+    {"dnsName": "gone.test-zone.example.org", "recordType": "A", "labels": {"owner": "owner"}}
+  ]
+}`), 0600))
+	r, _ := NewFileRegistry(p, path, "owner")
+
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "ingress/default/my-ingress"),
+		newEndpointWithOwner("foo.test-zone.example.org", "2001:db8::4", endpoint.RecordTypeAAAA, ""),
+		newEndpointWithOwner("bar.test-zone.example.org", "my-domain.com", endpoint.RecordTypeCNAME, "owner-2"),
+		newEndpointWithOwner("multiple.test-zone.example.org", "lb1.loadbalancer.com", endpoint.RecordTypeCNAME, "").WithSetIdentifier("test-set-1"),
+		newEndpointWithOwner("multiple.test-zone.example.org", "lb2.loadbalancer.com", endpoint.RecordTypeCNAME, "owner").WithSetIdentifier("test-set-2"),
+	}))
+
+	// a missing file holds no ownership records
+	r, _ = NewFileRegistry(p, newFileRegistryPath(t), "owner")
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 5)
+}
+
+func testFileRegistryApplyChanges(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("other.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, ""),
+		},
+	})
+	path := newFileRegistryPath(t)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"version": 1, "records": [
+    {"dnsName": "foo.test-zone.example.org", "recordType": "A", "labels": {"owner": "owner"}},
+    {"dnsName": "bar.test-zone.example.org", "recordType": "A", "labels": {"owner": "owner"}},
+    {"dnsName": "other.test-zone.example.org", "recordType": "A", "labels": {"owner": "owner-2"}}
+]}`), 0600))
+	r, _ := NewFileRegistry(p, path, "owner")
+
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwnerAndLabels("new.test-zone.example.org", "1.2.3.7", endpoint.RecordTypeA, "", endpoint.Labels{endpoint.ResourceLabelKey: "ingress/default/new", endpoint.PriorityLabelKey: "10"}),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("other.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, "owner-2"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwnerAndLabels("foo.test-zone.example.org", "1.2.3.8", endpoint.RecordTypeA, "owner", endpoint.Labels{endpoint.ResourceLabelKey: "ingress/default/foo"}),
+			newEndpointWithOwner("other.test-zone.example.org", "1.2.3.9", endpoint.RecordTypeA, "owner-2"),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, "owner"),
+		},
+	}))
+
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("new.test-zone.example.org", "1.2.3.7", endpoint.RecordTypeA, "owner", "ingress/default/new"),
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.8", endpoint.RecordTypeA, "owner", "ingress/default/foo"),
+		// the record of another owner is left alone
+		newEndpointWithOwner("other.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, "owner-2"),
+	}))
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "version": 1,
+  "records": [
+    {
+      "dnsName": "foo.test-zone.example.org",
+      "recordType": "A",
+      "labels": {
+        "owner": "owner",
+        "resource": "ingress/default/foo"
+      }
+    },
+    {
+      "dnsName": "new.test-zone.example.org",
+      "recordType": "A",
+      "labels": {
+        "owner": "owner",
+        "resource": "ingress/default/new"
+      }
+    },
+    {
+      "dnsName": "other.test-zone.example.org",
+      "recordType": "A",
+      "labels": {
+        "owner": "owner-2"
+      }
+    }
+  ]
+}
+`, string(data))
+
+	// no temporary file is left behind
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	assert.ElementsMatch(t, []string{"ownership.json", "ownership.json.lock"}, names)
+}
+
+func testFileRegistryApplyChangesFailure(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	path := newFileRegistryPath(t)
+	r, _ := NewFileRegistry(p, path, "owner")
+
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		// the ownership is stored before the record is created
+		ownerships, err := r.read()
+		require.NoError(t, err)
+		assert.Contains(t, ownerships, "new.test-zone.example.org::A::")
+	}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("new.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	}))
+
+	// the ownership of a record which failed to be deleted is kept
+	err := r.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("missing.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("new.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+		},
+	})
+	require.Error(t, err)
+	ownerships, err := r.read()
+	require.NoError(t, err)
+	assert.Contains(t, ownerships, "new.test-zone.example.org::A::")
+
+	// the ownership of a record which failed to be created is removed
+	p.OnApplyChanges = func(ctx context.Context, changes *plan.Changes) {}
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("exists.test-zone.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+	}))
+	err = r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("exists.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	})
+	require.Error(t, err)
+	ownerships, err = r.read()
+	require.NoError(t, err)
+	assert.NotContains(t, ownerships, "exists.test-zone.example.org::A::", "should remove the ownership record")
+	assert.Contains(t, ownerships, "new.test-zone.example.org::A::", "should keep the ownership records which existed before")
+}
+
+func testFileRegistryOtherOwner(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	path := newFileRegistryPath(t)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{
+  "version": 1,
+  "records": [
+    {"dnsName": "other.test-zone.example.org", "recordType": "CNAME", "labels": {"owner": "owner-2"}}
+  ]
+}`), 0600))
+	r, _ := NewFileRegistry(p, path, "owner")
+
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		// the record whose ownership record belongs to another owner is skipped
+		require.Len(t, got.Create, 1)
+		assert.Equal(t, "new.test-zone.example.org", got.Create[0].DNSName)
+	}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("other.test-zone.example.org", "other.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("new.test-zone.example.org", "new.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		},
+	}))
+
+	ownerships, err := r.read()
+	require.NoError(t, err)
+	require.Contains(t, ownerships, "other.test-zone.example.org::CNAME::")
+	assert.Equal(t, "owner-2", ownerships["other.test-zone.example.org::CNAME::"].Labels[endpoint.OwnerLabelKey], "should not take over the ownership")
+	assert.Contains(t, ownerships, "new.test-zone.example.org::CNAME::")
+}
+
+func testFileRegistryConcurrentChanges(t *testing.T) {
+	path := newFileRegistryPath(t)
+
+	// several instances sharing the file on the same host don't lose each other's changes
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := inmemory.NewInMemoryProvider()
+			p.CreateZone(testZone)
+			r, _ := NewFileRegistry(p, path, fmt.Sprintf("owner-%d", i))
+			for j := 0; j < 5; j++ {
+				assert.NoError(t, r.ApplyChanges(context.Background(), &plan.Changes{
+					Create: []*endpoint.Endpoint{
+						newEndpointWithOwner(fmt.Sprintf("foo-%d-%d.test-zone.example.org", i, j), "1.2.3.4", endpoint.RecordTypeA, ""),
+					},
+				}))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	r, _ := NewFileRegistry(inmemory.NewInMemoryProvider(), path, "owner")
+	ownerships, err := r.read()
+	require.NoError(t, err)
+	assert.Len(t, ownerships, 25)
+}
+
+func testFileRegistryInvalidFile(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+
+	for _, content := range []string{"{", `{"version": 2, "records": []}`} {
+		path := newFileRegistryPath(t)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		r, _ := NewFileRegistry(p, path, "owner")
+
+		_, err := r.Records(context.Background())
+		assert.Error(t, err)
+		err = r.ApplyChanges(context.Background(), &plan.Changes{
+			Create: []*endpoint.Endpoint{newEndpointWithOwner("new.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "")},
+		})
+		assert.Error(t, err)
+
+		// the invalid file is left alone
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+}
+
+func testFileRegistryCheck(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("baz.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, ""),
+		},
+	})
+	path := newFileRegistryPath(t)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"version": 1, "records": [
+    {"dnsName": "foo.test-zone.example.org", "recordType": "A", "labels": {"owner": "owner"}},
+    {"dnsName": "bar.test-zone.example.org", "recordType": "A", "labels": {"owner": "owner-2"}},
+    {"dnsName": "gone.test-zone.example.org", "recordType": "A", "labels": {"owner": "owner"}}
+]}`), 0600))
+	r, _ := NewFileRegistry(p, path, "owner")
+
+	report, err := Check(ctx, r, "owner")
+	require.NoError(t, err)
+	assert.Equal(t, []CheckedRecord{
+		{DNSName: "bar.test-zone.example.org", RecordType: endpoint.RecordTypeA, Owner: "owner-2", Status: RecordForeign},
+		{DNSName: "baz.test-zone.example.org", RecordType: endpoint.RecordTypeA, Status: RecordUnowned},
+		{DNSName: "foo.test-zone.example.org", RecordType: endpoint.RecordTypeA, Owner: "owner", Status: RecordOwned},
+		{DNSName: "gone.test-zone.example.org", RecordType: endpoint.RecordTypeA, Owner: "owner", Status: RecordOrphaned, Reason: "ownership record in " + path},
+	}, report.Records)
+}
@@ -43,7 +43,7 @@ func (im *NoopRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, erro
 
 // ApplyChanges propagates changes to the dns provider
 func (im *NoopRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
-	return im.provider.ApplyChanges(ctx, changes)
+	return applyChanges(ctx, im.provider, changes)
 }
 
 // PropertyValuesEqual compares two property values for equality
@@ -23,6 +23,7 @@ import (
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
 )
 
 // Registry is an interface which should enables ownership concept in external-dns
@@ -35,6 +36,28 @@ type Registry interface {
 	PropertyValuesEqual(attribute string, previous string, current string) bool
 }
 
+// propagatedChangesKey is the context key of the changes propagated to the DNS provider, see WithPropagatedChanges.
+type propagatedChangesKey struct{}
+
+// WithPropagatedChanges returns a context in which the registries record the changes they propagate to the
+// DNS provider in the returned changes: the planned changes without the records owned by other owners,
+// along with the changes of the ownership records.
+func WithPropagatedChanges(ctx context.Context) (context.Context, *plan.Changes) {
+	changes := &plan.Changes{}
+	return context.WithValue(ctx, propagatedChangesKey{}, changes), changes
+}
+
+// applyChanges propagates the changes to the DNS provider and records them, see WithPropagatedChanges.
+func applyChanges(ctx context.Context, p provider.Provider, changes *plan.Changes) error {
+	if propagated, ok := ctx.Value(propagatedChangesKey{}).(*plan.Changes); ok {
+		propagated.Create = append(propagated.Create, changes.Create...)
+		propagated.UpdateOld = append(propagated.UpdateOld, changes.UpdateOld...)
+		propagated.UpdateNew = append(propagated.UpdateNew, changes.UpdateNew...)
+		propagated.Delete = append(propagated.Delete, changes.Delete...)
+	}
+	return p.ApplyChanges(ctx, changes)
+}
+
 //TODO(ideahitme): consider moving this to Plan
 func filterOwnedRecords(ownerID string, eps []*endpoint.Endpoint) []*endpoint.Endpoint {
 	filtered := []*endpoint.Endpoint{}
@@ -47,3 +70,45 @@ func filterOwnedRecords(ownerID string, eps []*endpoint.Endpoint) []*endpoint.En
 	}
 	return filtered
 }
+
+// claimRecords takes over the ownership of the updated records which aren't owned by anyone and are claimed
+// by the desired records, see endpoint.ClaimLabelKey. The ownership is recorded by the registry along with the update.
+func claimRecords(ownerID string, changes *plan.Changes) {
+	claimed := map[string]bool{}
+	for _, ep := range changes.UpdateNew {
+		if ep.Labels[endpoint.ClaimLabelKey] != "true" || ep.Labels[endpoint.OwnerLabelKey] != "" {
+			continue
+		}
+		log.Infof("Claiming the ownership of %s %s", ep.RecordType, ep.DNSName)
+		ep.Labels[endpoint.OwnerLabelKey] = ownerID
+		claimed[claimKey(ep)] = true
+	}
+	for _, ep := range changes.UpdateOld {
+		if claimed[claimKey(ep)] && ep.Labels[endpoint.OwnerLabelKey] == "" {
+			if ep.Labels == nil {
+				ep.Labels = endpoint.NewLabels()
+			}
+			ep.Labels[endpoint.OwnerLabelKey] = ownerID
+		}
+	}
+}
+
+func claimKey(ep *endpoint.Endpoint) string {
+	return ep.DNSName + "::" + ep.RecordType + "::" + ep.SetIdentifier
+}
+
+// planningLabelKeys are the labels of the desired records which only matter while planning the changes,
+// the claim has been settled and the conflict resolved once the ownership is stored.
+var planningLabelKeys = []string{endpoint.ClaimLabelKey, endpoint.PriorityLabelKey}
+
+// ownershipLabels returns a copy of the labels of a record without the ones which only matter while planning.
+func ownershipLabels(labels endpoint.Labels) endpoint.Labels {
+	owned := endpoint.NewLabels()
+	for k, v := range labels {
+		owned[k] = v
+	}
+	for _, k := range planningLabelKeys {
+		delete(owned, k)
+	}
+	return owned
+}
@@ -20,45 +20,129 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/publicsuffix"
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 )
 
+const (
+	// txtFormatLabelKey marks the ownership records in the versioned format,
+	// whose name encodes the type of the owned record, e.g. a-foo.example.org
+	txtFormatLabelKey = "txt-format"
+	txtFormatVersion  = "2"
+
+	// txtMaxValueLength is the maximum length of a character string of a TXT record,
+	// the DNS providers reject longer values or split them, which breaks their labels
+	txtMaxValueLength = 255
+)
+
+var orphanedOwnershipRecords = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "registry",
+		Name:      "orphaned_ownership_records",
+		Help:      "Number of owned TXT ownership records without owned record found by the last listing of the records.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(orphanedOwnershipRecords)
+}
+
 // TXTRegistry implements registry interface with ownership implemented via associated TXT records
 type TXTRegistry struct {
 	provider provider.Provider
 	ownerID  string //refers to the owner id of the current instance
 	mapper   nameMapper
 
+	// the owner ids whose records are adopted by the current instance, e.g. of the cluster it replaces
+	previousOwnerIDs []string
+
+	// the ownership records found by the last call to Records, keyed by the owned records.
+	// The previous format shares an ownership record between the records of all types with the same name,
+	// the versioned format has an ownership record per record type.
+	previousTXTs  map[string]*endpoint.Endpoint
+	versionedTXTs map[string]*endpoint.Endpoint
+
+	// protects the labels of the ownership records, optional
+	keyring *TXTKeyring
+	// the versioned ownership records which aren't protected with the current key and mode of the keyring,
+	// or which are owned by a previous owner id
+	staleTXTs map[string]*endpoint.Endpoint
+
+	// share the A and AAAA records with other owners, each of them contributing its own targets,
+	// and the shared records found by the last call to Records keyed like the versioned ownership records
+	shared        bool
+	sharedRecords map[string]*sharedRecord
+
 	// cache the records in memory and update on an interval instead.
-	recordsCache            []*endpoint.Endpoint
-	recordsCacheRefreshTime time.Time
-	cacheInterval           time.Duration
+	cache         *recordsCache
+	cacheInterval time.Duration
+}
+
+// TXTRegistryConfig is the configuration of a TXTRegistry.
+type TXTRegistryConfig struct {
+	// The owner id of the records managed by this instance, required
+	OwnerID string
+	// The prefix of the names of the ownership records, mutually exclusive with Suffix
+	Prefix string
+	// The suffix of the names of the ownership records, mutually exclusive with Prefix
+	Suffix string
+	// The template of the names of the ownership records, mutually exclusive with Prefix and Suffix
+	NameTemplate string
+	// The interval during which the records are cached in memory, zero disables the cache
+	CacheInterval time.Duration
+	// The keyring protecting the labels of the ownership records, nil leaves them unprotected
+	Keyring *TXTKeyring
+	// The owner ids whose records are adopted, e.g. of the cluster this instance replaces
+	PreviousOwnerIDs []string
+	// Share the A and AAAA records with other owners, each of them contributing its own targets
+	SharedRecords bool
+	// The zone apexes, which tell the ownership records located below them from the other names
+	Zones []string
 }
 
 // NewTXTRegistry returns new TXTRegistry object
-func NewTXTRegistry(provider provider.Provider, txtPrefix, txtSuffix, ownerID string, cacheInterval time.Duration) (*TXTRegistry, error) {
-	if ownerID == "" {
+func NewTXTRegistry(provider provider.Provider, cfg TXTRegistryConfig) (*TXTRegistry, error) {
+	if cfg.OwnerID == "" {
 		return nil, errors.New("owner id cannot be empty")
 	}
 
-	if len(txtPrefix) > 0 && len(txtSuffix) > 0 {
+	if len(cfg.Prefix) > 0 && len(cfg.Suffix) > 0 {
 		return nil, errors.New("txt-prefix and txt-suffix are mutual exclusive")
 	}
 
-	mapper := newaffixNameMapper(txtPrefix, txtSuffix)
+	var mapper nameMapper = newaffixNameMapper(cfg.Prefix, cfg.Suffix, cfg.Zones)
+	if cfg.NameTemplate != "" {
+		if len(cfg.Prefix) > 0 || len(cfg.Suffix) > 0 {
+			return nil, errors.New("txt-name-template is mutual exclusive with txt-prefix and txt-suffix")
+		}
+		var err error
+		if mapper, err = newTemplateNameMapper(cfg.NameTemplate, cfg.Zones); err != nil {
+			return nil, err
+		}
+	}
 
 	return &TXTRegistry{
-		provider:      provider,
-		ownerID:       ownerID,
-		mapper:        mapper,
-		cacheInterval: cacheInterval,
+		provider:         provider,
+		ownerID:          cfg.OwnerID,
+		mapper:           mapper,
+		previousOwnerIDs: cfg.PreviousOwnerIDs,
+		previousTXTs:     map[string]*endpoint.Endpoint{},
+		versionedTXTs:    map[string]*endpoint.Endpoint{},
+		keyring:          cfg.Keyring,
+		shared:           cfg.SharedRecords,
+		sharedRecords:    map[string]*sharedRecord{},
+		cache:            newRecordsCache(cfg.CacheInterval),
+		cacheInterval:    cfg.CacheInterval,
 	}, nil
 }
 
@@ -68,9 +152,11 @@ func NewTXTRegistry(provider provider.Provider, txtPrefix, txtSuffix, ownerID st
 func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
 	// If we have the zones cached AND we have refreshed the cache since the
 	// last given interval, then just use the cached results.
-	if im.recordsCache != nil && time.Since(im.recordsCacheRefreshTime) < im.cacheInterval {
-		log.Debug("Using cached records.")
-		return im.recordsCache, nil
+	if im.cacheInterval > 0 {
+		if records, ok := im.cache.get(); ok {
+			log.Debug("Using cached records.")
+			return records, nil
+		}
 	}
 
 	records, err := im.provider.Records(ctx)
@@ -80,7 +166,11 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 
 	endpoints := []*endpoint.Endpoint{}
 
-	labelMap := map[string]endpoint.Labels{}
+	previousTXTs := map[string]*endpoint.Endpoint{}
+	versionedTXTs := map[string]*endpoint.Endpoint{}
+	staleTXTs := map[string]*endpoint.Endpoint{}
+	previousLabels := map[string]endpoint.Labels{}
+	versionedLabels := map[string]endpoint.Labels{}
 
 	for _, record := range records {
 		if record.RecordType != endpoint.RecordTypeTXT {
@@ -99,26 +189,74 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 		if err != nil {
 			return nil, err
 		}
-		key := fmt.Sprintf("%s::%s", im.mapper.toEndpointName(record.DNSName), record.SetIdentifier)
-		labelMap[key] = labels
+		protection := txtCurrent
+		if im.keyring != nil {
+			labels, protection, err = im.keyring.open(record.DNSName, labels)
+			if err != nil {
+				log.Warnf("Ignoring the ownership record %s: %v", record.DNSName, err)
+				continue
+			}
+			if protection == txtUnprotected && !im.keyring.allowUnprotected {
+				log.Warnf("Ignoring the ownership record %s, which is neither signed nor encrypted", record.DNSName)
+				continue
+			}
+		}
+		adopted := false
+		if owner := labels[endpoint.OwnerLabelKey]; owner != im.ownerID && im.isOwner(owner) {
+			log.Debugf("Adopting the ownership record %s of the previous owner %s", record.DNSName, owner)
+			labels[endpoint.OwnerLabelKey] = im.ownerID
+			adopted = true
+		}
+		if im.shared && im.adoptsContributions(labels) {
+			adopted = true
+		}
+		key, versioned := im.ownedRecordKey(record, labels)
+		delete(labels, txtFormatLabelKey)
+		if versioned {
+			versionedTXTs[key] = record
+			versionedLabels[key] = labels
+			if protection != txtCurrent || adopted {
+				staleTXTs[key] = record
+			}
+		} else {
+			previousTXTs[key] = record
+			previousLabels[key] = labels
+		}
 	}
 
 	for _, ep := range endpoints {
 		if ep.Labels == nil {
 			ep.Labels = endpoint.NewLabels()
 		}
-		key := fmt.Sprintf("%s::%s", ep.DNSName, ep.SetIdentifier)
-		if labels, ok := labelMap[key]; ok {
-			for k, v := range labels {
-				ep.Labels[k] = v
-			}
+		labels, ok := versionedLabels[versionedOwnershipKey(ep)]
+		if !ok {
+			labels = previousLabels[ownershipKey(ep)]
+		}
+		for k, v := range labels {
+			ep.Labels[k] = v
 		}
 	}
 
+	orphans := im.orphanedTXTs(endpoints, previousTXTs, previousLabels, versionedTXTs, versionedLabels)
+	for key := range versionedTXTs {
+		if isOrphaned(versionedTXTs[key]) {
+			// orphaned ownership records are deleted rather than protected
+			delete(staleTXTs, key)
+		}
+	}
+	orphanedOwnershipRecords.Set(float64(len(orphans)))
+	endpoints = append(endpoints, orphans...)
+	if im.shared {
+		endpoints = im.shareRecords(endpoints, versionedTXTs, versionedLabels)
+	}
+
+	im.previousTXTs = previousTXTs
+	im.versionedTXTs = versionedTXTs
+	im.staleTXTs = staleTXTs
+
 	// Update the cache.
 	if im.cacheInterval > 0 {
-		im.recordsCache = endpoints
-		im.recordsCacheRefreshTime = time.Now()
+		im.cache.set(endpoints)
 	}
 
 	return endpoints, nil
@@ -127,68 +265,553 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 // ApplyChanges updates dns provider with the changes
 // for each created/deleted record it will also take into account TXT records for creation/deletion
 func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	claimRecords(im.ownerID, changes)
 	filteredChanges := &plan.Changes{
 		Create:    changes.Create,
 		UpdateNew: filterOwnedRecords(im.ownerID, changes.UpdateNew),
 		UpdateOld: filterOwnedRecords(im.ownerID, changes.UpdateOld),
 		Delete:    filterOwnedRecords(im.ownerID, changes.Delete),
 	}
-	for _, r := range filteredChanges.Create {
+	shared := &plan.Changes{}
+	if im.shared {
+		var err error
+		if shared, err = im.shareChanges(filteredChanges); err != nil {
+			return err
+		}
+	}
+	created, updated, updatedOld, deleted := filteredChanges.Create, filteredChanges.UpdateNew, filteredChanges.UpdateOld, filteredChanges.Delete
+
+	reused := map[*endpoint.Endpoint]bool{}
+	for _, r := range created {
 		if r.Labels == nil {
 			r.Labels = make(map[string]string)
 		}
 		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
+
+		txt, err := im.generateTXTRecord(r)
+		if err != nil {
+			return err
+		}
+		if orphan, ok := im.versionedTXTs[versionedOwnershipKey(r)]; ok && isOrphaned(orphan) {
+			// the record is created again while its ownership record is still around, replace it instead of deleting it
+			filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, orphan)
+			filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, txt)
+			reused[orphan] = true
+			continue
+		}
 		filteredChanges.Create = append(filteredChanges.Create, txt)
+	}
+	if len(reused) > 0 {
+		remaining := []*endpoint.Endpoint{}
+		for _, r := range filteredChanges.Delete {
+			if !reused[r] {
+				remaining = append(remaining, r)
+			}
+		}
+		filteredChanges.Delete = remaining
+	}
 
-		if im.cacheInterval > 0 {
-			im.addToCache(r)
+	for _, r := range deleted {
+		if txt, ok := im.versionedTXTs[versionedOwnershipKey(r)]; ok {
+			filteredChanges.Delete = append(filteredChanges.Delete, txt)
 		}
 	}
 
-	for _, r := range filteredChanges.Delete {
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
+	// make sure TXT records are consistently updated as well
+	for _, r := range updated {
+		txt, err := im.generateTXTRecord(r)
+		if err != nil {
+			return err
+		}
+		if existing, ok := im.versionedTXTs[versionedOwnershipKey(r)]; ok {
+			filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, existing)
+			filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, txt)
+		} else {
+			// the record is owned through an ownership record in the previous format
+			filteredChanges.Create = append(filteredChanges.Create, txt)
+		}
+	}
 
-		// when we delete TXT records for which value has changed (due to new label) this would still work because
-		// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
-		filteredChanges.Delete = append(filteredChanges.Delete, txt)
+	records, _ := ctx.Value(provider.RecordsContextKey).([]*endpoint.Endpoint)
+	if err := im.migrate(filteredChanges, records, created, updated, deleted); err != nil {
+		return err
+	}
+	if err := im.refresh(filteredChanges, updated, deleted, shared); err != nil {
+		return err
+	}
+	filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, shared.UpdateOld...)
+	filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, shared.UpdateNew...)
+	filteredChanges.Delete = append(filteredChanges.Delete, shared.Delete...)
 
+	// when caching is enabled, disable the provider from using the cache
+	if im.cacheInterval > 0 {
+		ctx = context.WithValue(ctx, provider.RecordsContextKey, nil)
+	}
+	if err := applyChanges(ctx, im.provider, filteredChanges); err != nil {
 		if im.cacheInterval > 0 {
-			im.removeFromCache(r)
+			// the changes may have been applied partially, the records are listed again
+			im.cache.invalidate()
 		}
+		return err
 	}
 
-	// make sure TXT records are consistently updated as well
-	for _, r := range filteredChanges.UpdateOld {
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
-		// when we updateOld TXT records for which value has changed (due to new label) this would still work because
-		// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
-		filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, txt)
-		// remove old version of record from cache
-		if im.cacheInterval > 0 {
-			im.removeFromCache(r)
+	if im.cacheInterval > 0 {
+		for _, records := range [][]*endpoint.Endpoint{deleted, updatedOld} {
+			for _, r := range records {
+				im.cache.remove(r)
+			}
+		}
+		for _, records := range [][]*endpoint.Endpoint{created, updated} {
+			for _, r := range records {
+				im.cache.add(r)
+			}
+		}
+		if len(shared.UpdateNew) > 0 || len(shared.Delete) > 0 {
+			// the views of the shared records are derived from their ownership records, they're listed again
+			im.cache.reset()
 		}
 	}
+	im.updateOwnershipRecords(filteredChanges)
+	return nil
+}
 
-	// make sure TXT records are consistently updated as well
-	for _, r := range filteredChanges.UpdateNew {
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
-		filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, txt)
-		// add new version of record to cache
-		if im.cacheInterval > 0 {
-			im.addToCache(r)
+// orphanedTXTs returns the owned ownership records whose owned records are gone, e.g. deleted out-of-band or
+// left behind by a failure between the changes of a record and of its ownership record. They're marked as
+// orphaned and returned along with the records, so that the plan deletes them subject to the policies.
+func (im *TXTRegistry) orphanedTXTs(records []*endpoint.Endpoint, previousTXTs map[string]*endpoint.Endpoint, previousLabels map[string]endpoint.Labels,
+	versionedTXTs map[string]*endpoint.Endpoint, versionedLabels map[string]endpoint.Labels) []*endpoint.Endpoint {
+	existing := map[string]bool{}
+	for _, r := range records {
+		existing[ownershipKey(r)] = true
+		existing[versionedOwnershipKey(r)] = true
+	}
+
+	orphans := []*endpoint.Endpoint{}
+	for _, txts := range []struct {
+		records map[string]*endpoint.Endpoint
+		labels  map[string]endpoint.Labels
+	}{
+		{records: previousTXTs, labels: previousLabels},
+		{records: versionedTXTs, labels: versionedLabels},
+	} {
+		keys := make([]string, 0, len(txts.records))
+		for key := range txts.records {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			txt := txts.records[key]
+			if existing[key] || txts.labels[key][endpoint.OwnerLabelKey] != im.ownerID {
+				continue
+			}
+			if im.mapper.toEndpointName(txt.DNSName) == "" {
+				// the name doesn't match the prefix, suffix or name template, hence it can't be told which record is owned
+				continue
+			}
+			log.Debugf("Found the orphaned ownership record %s", txt.DNSName)
+			txt.Labels = endpoint.Labels{
+				endpoint.OwnerLabelKey:    im.ownerID,
+				endpoint.OrphanedLabelKey: "true",
+			}
+			orphans = append(orphans, txt)
 		}
 	}
+	return orphans
+}
 
-	// when caching is enabled, disable the provider from using the cache
-	if im.cacheInterval > 0 {
-		ctx = context.WithValue(ctx, provider.RecordsContextKey, nil)
+// migrate adds the changes replacing the owned ownership records in the previous format by ones in the versioned format.
+// First, the versioned ownership records are created for the owned records which lack them. Once all records sharing
+// an ownership record in the previous format have been migrated, it's deleted in a following synchronization,
+// or right away if the last of them is deleted.
+func (im *TXTRegistry) migrate(changes *plan.Changes, records, created, updated, deleted []*endpoint.Endpoint) error {
+	migrated := map[string]bool{}
+	for key := range im.versionedTXTs {
+		migrated[key] = true
+	}
+	for _, r := range created {
+		migrated[versionedOwnershipKey(r)] = true
+	}
+	updating := map[string]bool{}
+	for _, r := range updated {
+		updating[versionedOwnershipKey(r)] = true
+	}
+	deleting := map[string]bool{}
+	sharedDeleted := map[string]bool{}
+	for _, r := range deleted {
+		if isOrphaned(r) {
+			continue
+		}
+		deleting[versionedOwnershipKey(r)] = true
+		sharedDeleted[ownershipKey(r)] = true
+	}
+
+	// the owned records sharing an ownership record in the previous format after the changes
+	remaining := map[string][]*endpoint.Endpoint{}
+	for _, r := range filterOwnedRecords(im.ownerID, records) {
+		if !isOrphaned(r) && !deleting[versionedOwnershipKey(r)] {
+			remaining[ownershipKey(r)] = append(remaining[ownershipKey(r)], r)
+		}
+	}
+	for _, r := range created {
+		remaining[ownershipKey(r)] = append(remaining[ownershipKey(r)], r)
+	}
+
+	keys := make([]string, 0, len(im.previousTXTs))
+	for key := range im.previousTXTs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		txt := im.previousTXTs[key]
+		if labels, ok := im.ownershipLabels(txt); !ok || !im.isOwner(labels[endpoint.OwnerLabelKey]) {
+			continue
+		}
+		if len(remaining[key]) == 0 {
+			// orphaned ownership records are deleted through the plan, unless their last record is deleted
+			if sharedDeleted[key] {
+				changes.Delete = append(changes.Delete, txt)
+			}
+			continue
+		}
+
+		complete := true
+		for _, r := range remaining[key] {
+			versionedKey := versionedOwnershipKey(r)
+			if migrated[versionedKey] {
+				continue
+			}
+			complete = false
+			if updating[versionedKey] {
+				continue
+			}
+			log.Debugf("Migrating the ownership record of %s %s to the versioned format", r.RecordType, r.DNSName)
+			migratedTXT, err := im.generateTXTRecord(r)
+			if err != nil {
+				return err
+			}
+			changes.Create = append(changes.Create, migratedTXT)
+			migrated[versionedKey] = true
+		}
+		if complete {
+			log.Debugf("Deleting the ownership record %s in the previous format", txt.DNSName)
+			changes.Delete = append(changes.Delete, txt)
+		}
+	}
+	return nil
+}
+
+// refresh adds the changes replacing the owned versioned ownership records which are stale, unless they're replaced
+// or deleted anyway. Rewriting the ones which aren't protected with the current key and mode of the keyring completes
+// the rotation of the keys, rewriting the ones owned by a previous owner id completes their adoption.
+func (im *TXTRegistry) refresh(changes *plan.Changes, updated, deleted []*endpoint.Endpoint, shared *plan.Changes) error {
+	replaced := map[string]bool{}
+	for _, records := range [][]*endpoint.Endpoint{updated, deleted, shared.UpdateNew, shared.Delete} {
+		for _, r := range records {
+			replaced[versionedOwnershipKey(r)] = true
+		}
+	}
+
+	keys := make([]string, 0, len(im.staleTXTs))
+	for key := range im.staleTXTs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		txt := im.staleTXTs[key]
+		labels, ok := im.ownershipLabels(txt)
+		if replaced[key] || !ok {
+			continue
+		}
+		if contributions := im.sharedContributions(labels); im.shared && contributions != nil {
+			if _, ok := contributions[im.ownerID]; !ok {
+				continue
+			}
+			labels = im.sharedLabels(labels, contributions)
+		} else if im.isOwner(labels[endpoint.OwnerLabelKey]) {
+			labels[endpoint.OwnerLabelKey] = im.ownerID
+		} else {
+			continue
+		}
+		log.Debugf("Rewriting the ownership record %s", txt.DNSName)
+		value, err := im.txtValue(txt.DNSName, labels)
+		if err != nil {
+			return err
+		}
+		rewritten := endpoint.NewEndpoint(txt.DNSName, endpoint.RecordTypeTXT, value).WithSetIdentifier(txt.SetIdentifier)
+		rewritten.ProviderSpecific = txt.ProviderSpecific
+		changes.UpdateOld = append(changes.UpdateOld, txt)
+		changes.UpdateNew = append(changes.UpdateNew, rewritten)
+	}
+	return nil
+}
+
+// updateOwnershipRecords keeps the known ownership records in line with the applied changes,
+// which is required when the records are cached.
+func (im *TXTRegistry) updateOwnershipRecords(changes *plan.Changes) {
+	if im.previousTXTs == nil {
+		im.previousTXTs = map[string]*endpoint.Endpoint{}
+	}
+	if im.versionedTXTs == nil {
+		im.versionedTXTs = map[string]*endpoint.Endpoint{}
+	}
+	for _, txts := range [][]*endpoint.Endpoint{changes.Delete, changes.UpdateOld} {
+		for _, txt := range txts {
+			if labels, ok := im.ownershipLabels(txt); ok {
+				key, versioned := im.ownedRecordKey(txt, labels)
+				if versioned {
+					delete(im.versionedTXTs, key)
+					delete(im.staleTXTs, key)
+				} else {
+					delete(im.previousTXTs, key)
+				}
+			}
+		}
+	}
+	for _, txts := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateNew} {
+		for _, txt := range txts {
+			if labels, ok := im.ownershipLabels(txt); ok {
+				key, versioned := im.ownedRecordKey(txt, labels)
+				if versioned {
+					im.versionedTXTs[key] = txt
+				} else {
+					im.previousTXTs[key] = txt
+				}
+			}
+		}
+	}
+}
+
+// ownershipLabels returns the labels held by an ownership record, or false if the record isn't one.
+func (im *TXTRegistry) ownershipLabels(txt *endpoint.Endpoint) (endpoint.Labels, bool) {
+	if txt.RecordType != endpoint.RecordTypeTXT || len(txt.Targets) == 0 {
+		return nil, false
+	}
+	labels, err := endpoint.NewLabelsFromString(txt.Targets[0])
+	if err != nil {
+		return nil, false
+	}
+	if im.keyring != nil {
+		labels, _, err = im.keyring.open(txt.DNSName, labels)
+	}
+	return labels, err == nil
+}
+
+// ownedRecordKey returns the key of the record(s) owned by an ownership record, and whether it's in the versioned format.
+func (im *TXTRegistry) ownedRecordKey(txt *endpoint.Endpoint, labels endpoint.Labels) (string, bool) {
+	if labels[txtFormatLabelKey] == txtFormatVersion {
+		name, recordType := im.mapper.toEndpointNameAndType(txt.DNSName)
+		return fmt.Sprintf("%s::%s::%s", name, recordType, txt.SetIdentifier), true
+	}
+	return fmt.Sprintf("%s::%s", im.mapper.toEndpointName(txt.DNSName), txt.SetIdentifier), false
+}
+
+// generateTXTRecord returns the ownership record of the given record in the versioned format.
+func (im *TXTRegistry) generateTXTRecord(r *endpoint.Endpoint) (*endpoint.Endpoint, error) {
+	labels := ownershipLabels(r.Labels)
+	labels[txtFormatLabelKey] = txtFormatVersion
+	if im.shared && isShareable(r) {
+		labels = im.sharedLabels(labels, map[string]endpoint.Targets{im.ownerID: mergeTargets(r.Targets, nil)})
+	}
+
+	name := im.mapper.toVersionedTXTName(r.DNSName, r.RecordType)
+	value, err := im.txtValue(name, labels)
+	if err != nil {
+		return nil, err
+	}
+	txt := endpoint.NewEndpoint(name, endpoint.RecordTypeTXT, value).WithSetIdentifier(r.SetIdentifier)
+	txt.ProviderSpecific = r.ProviderSpecific
+	return txt, nil
+}
+
+// txtOwnership is an ownership record found by Check along with the record it owns.
+type txtOwnership struct {
+	txt           *endpoint.Endpoint
+	owner         string
+	dnsName       string
+	recordType    string
+	setIdentifier string
+	matched       bool
+}
+
+// Check classifies the records and the ownership records of the DNS provider, including the ownership records
+// which can't be matched to the records they own, without applying any change.
+func (im *TXTRegistry) Check(ctx context.Context) ([]CheckedRecord, error) {
+	records, err := im.provider.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	checked := []CheckedRecord{}
+	owned := []*endpoint.Endpoint{}
+	ownerships := []*txtOwnership{}
+	versioned := map[string]*txtOwnership{}
+	previous := map[string]*txtOwnership{}
+	for _, record := range records {
+		if record.RecordType != endpoint.RecordTypeTXT {
+			owned = append(owned, record)
+			continue
+		}
+		labels, err := endpoint.NewLabelsFromString(record.Targets[0])
+		if err == endpoint.ErrInvalidHeritage {
+			owned = append(owned, record)
+			continue
+		}
+		if err != nil {
+			checked = append(checked, newCheckedRecord(record, "", RecordInconsistent, err.Error()))
+			continue
+		}
+		if im.keyring != nil {
+			var protection txtProtection
+			labels, protection, err = im.keyring.open(record.DNSName, labels)
+			if err != nil {
+				checked = append(checked, newCheckedRecord(record, "", RecordInconsistent, err.Error()))
+				continue
+			}
+			if protection == txtUnprotected && !im.keyring.allowUnprotected {
+				checked = append(checked, newCheckedRecord(record, labels[endpoint.OwnerLabelKey], RecordInconsistent, "neither signed nor encrypted"))
+				continue
+			}
+		}
+		if im.mapper.toEndpointName(record.DNSName) == "" {
+			checked = append(checked, newCheckedRecord(record, labels[endpoint.OwnerLabelKey], RecordInconsistent, "the name doesn't match the TXT prefix, suffix or name template"))
+			continue
+		}
+
+		ownership := &txtOwnership{txt: record, owner: labels[endpoint.OwnerLabelKey], setIdentifier: record.SetIdentifier}
+		if contributions := im.sharedContributions(labels); im.shared && contributions != nil {
+			if _, ok := contributions[im.ownerID]; ok {
+				// the shared record is owned by the current instance along with the other owners
+				ownership.owner = im.ownerID
+			}
+		}
+		key, isVersioned := im.ownedRecordKey(record, labels)
+		if isVersioned {
+			ownership.dnsName, ownership.recordType = im.mapper.toEndpointNameAndType(record.DNSName)
+			versioned[key] = ownership
+		} else {
+			ownership.dnsName = im.mapper.toEndpointName(record.DNSName)
+			previous[key] = ownership
+		}
+		ownerships = append(ownerships, ownership)
+	}
+
+	// the set identifiers of the records and of the ownership records by name and type,
+	// and by name only for the previous format
+	setIdentifiers := map[string][]string{}
+	for _, r := range owned {
+		setIdentifiers[r.DNSName+"::"+r.RecordType] = append(setIdentifiers[r.DNSName+"::"+r.RecordType], r.SetIdentifier)
+		setIdentifiers[r.DNSName] = append(setIdentifiers[r.DNSName], r.SetIdentifier)
+	}
+	ownershipSetIdentifiers := map[string][]string{}
+	for _, ownership := range ownerships {
+		key := ownership.dnsName
+		if ownership.recordType != "" {
+			key += "::" + ownership.recordType
+		}
+		ownershipSetIdentifiers[key] = append(ownershipSetIdentifiers[key], ownership.setIdentifier)
+	}
+
+	for _, r := range owned {
+		v, hasVersioned := versioned[versionedOwnershipKey(r)]
+		p, hasPrevious := previous[ownershipKey(r)]
+		switch {
+		case hasVersioned && hasPrevious && v.owner != p.owner:
+			v.matched, p.matched = true, true
+			checked = append(checked, newCheckedRecord(r, v.owner, RecordInconsistent, fmt.Sprintf("the ownership records disagree on the owner: %q and %q", v.owner, p.owner)))
+		case hasVersioned:
+			v.matched = true
+			if hasPrevious {
+				p.matched = true
+			}
+			checked = append(checked, newCheckedRecord(r, v.owner, classifyOwner(v.owner, im.isOwner), ""))
+		case hasPrevious:
+			p.matched = true
+			checked = append(checked, newCheckedRecord(r, p.owner, classifyOwner(p.owner, im.isOwner), ""))
+		default:
+			others := []string{}
+			others = append(others, ownershipSetIdentifiers[r.DNSName+"::"+r.RecordType]...)
+			others = append(others, ownershipSetIdentifiers[r.DNSName]...)
+			if len(others) > 0 {
+				checked = append(checked, newCheckedRecord(r, "", RecordInconsistent, fmt.Sprintf("the set identifier %q differs from the ownership records': %q", r.SetIdentifier, others)))
+				continue
+			}
+			checked = append(checked, newCheckedRecord(r, "", RecordUnowned, ""))
+		}
+	}
+
+	for _, ownership := range ownerships {
+		status := classifyOwner(ownership.owner, im.isOwner)
+		reason := ""
+		if !ownership.matched {
+			status = RecordOrphaned
+			key := ownership.dnsName
+			if ownership.recordType != "" {
+				key += "::" + ownership.recordType
+			}
+			if others := setIdentifiers[key]; len(others) > 0 {
+				status = RecordInconsistent
+				reason = fmt.Sprintf("the set identifier %q differs from the owned records': %q", ownership.setIdentifier, others)
+			}
+		}
+		checked = append(checked, newCheckedRecord(ownership.txt, ownership.owner, status, reason))
 	}
-	return im.provider.ApplyChanges(ctx, filteredChanges)
+	return checked, nil
+}
+
+// txtValue returns the value of the ownership record with the given name holding the given labels. The resource label,
+// which isn't required for the ownership, is left out when the value would exceed the maximum length of a TXT string,
+// e.g. when the labels are signed or encrypted.
+func (im *TXTRegistry) txtValue(txtName string, labels endpoint.Labels) (string, error) {
+	value, err := im.sealTXTValue(txtName, labels)
+	if err != nil {
+		return "", err
+	}
+	if len(value) > txtMaxValueLength {
+		log.Warnf("The value of the ownership record %s exceeds %d characters and may be rejected by the DNS provider", txtName, txtMaxValueLength)
+	}
+	return value, nil
+}
+
+// sealTXTValue returns the value of the ownership record like txtValue, without warning about its length.
+func (im *TXTRegistry) sealTXTValue(txtName string, labels endpoint.Labels) (string, error) {
+	value, err := im.serializeLabels(txtName, labels)
+	if err != nil || len(value) <= txtMaxValueLength {
+		return value, err
+	}
+	if _, ok := labels[endpoint.ResourceLabelKey]; ok {
+		log.Debugf("Leaving the resource label out of the ownership record %s, as its value exceeds %d characters", txtName, txtMaxValueLength)
+		compact := endpoint.NewLabels()
+		for k, v := range labels {
+			if k != endpoint.ResourceLabelKey {
+				compact[k] = v
+			}
+		}
+		return im.serializeLabels(txtName, compact)
+	}
+	return value, nil
+}
+
+func (im *TXTRegistry) serializeLabels(txtName string, labels endpoint.Labels) (string, error) {
+	if im.keyring != nil {
+		return im.keyring.seal(txtName, labels)
+	}
+	return labels.Serialize(true), nil
+}
+
+// isOwner returns true if the records of the given owner id are owned by the current instance, possibly after adopting them.
+func (im *TXTRegistry) isOwner(ownerID string) bool {
+	if ownerID == im.ownerID {
+		return true
+	}
+	for _, previous := range im.previousOwnerIDs {
+		if ownerID == previous {
+			return true
+		}
+	}
+	return false
 }
 
 // PropertyValuesEqual compares two attribute values for equality
@@ -200,6 +823,21 @@ func (im *TXTRegistry) PropertyValuesEqual(name string, previous string, current
   TXT registry specific private methods
 */
 
+// ownershipKey returns the key identifying the TXT record in the previous format which holds the ownership of the given record
+func ownershipKey(ep *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s::%s", ep.DNSName, ep.SetIdentifier)
+}
+
+// isOrphaned returns true if the record is an ownership record whose owned record is gone
+func isOrphaned(ep *endpoint.Endpoint) bool {
+	return ep.Labels[endpoint.OrphanedLabelKey] == "true"
+}
+
+// versionedOwnershipKey returns the key identifying the TXT record in the versioned format which holds the ownership of the given record
+func versionedOwnershipKey(ep *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s::%s::%s", ep.DNSName, ep.RecordType, ep.SetIdentifier)
+}
+
 /**
   nameMapper defines interface which maps the dns name defined for the source
   to the dns name which TXT record will be created with
@@ -207,18 +845,20 @@ func (im *TXTRegistry) PropertyValuesEqual(name string, previous string, current
 
 type nameMapper interface {
 	toEndpointName(string) string
-	toTXTName(string) string
+	toEndpointNameAndType(string) (string, string)
+	toVersionedTXTName(string, string) string
 }
 
 type affixNameMapper struct {
 	prefix string
 	suffix string
+	zones  txtZones
 }
 
 var _ nameMapper = affixNameMapper{}
 
-func newaffixNameMapper(prefix string, suffix string) affixNameMapper {
-	return affixNameMapper{prefix: strings.ToLower(prefix), suffix: strings.ToLower(suffix)}
+func newaffixNameMapper(prefix string, suffix string, zones []string) affixNameMapper {
+	return affixNameMapper{prefix: strings.ToLower(prefix), suffix: strings.ToLower(suffix), zones: zones}
 }
 
 func (pr affixNameMapper) toEndpointName(txtDNSName string) string {
@@ -236,28 +876,61 @@ func (pr affixNameMapper) toEndpointName(txtDNSName string) string {
 	return ""
 }
 
-func (pr affixNameMapper) toTXTName(endpointDNSName string) string {
-	DNSName := strings.SplitN(endpointDNSName, ".", 2)
-	return pr.prefix + DNSName[0] + pr.suffix + "." + DNSName[1]
-}
-
-func (im *TXTRegistry) addToCache(ep *endpoint.Endpoint) {
-	if im.recordsCache != nil {
-		im.recordsCache = append(im.recordsCache, ep)
+// toEndpointNameAndType returns the name and type of the record owned by a TXT record in the versioned format.
+func (pr affixNameMapper) toEndpointNameAndType(txtDNSName string) (string, string) {
+	name := pr.toEndpointName(txtDNSName)
+	labels := strings.SplitN(name, ".", 2)
+	i := strings.Index(labels[0], "-")
+	if i < 0 || len(labels) < 2 {
+		return "", ""
+	}
+	recordType := strings.ToUpper(labels[0][:i])
+	switch label := labels[0][i+1:]; label {
+	case txtApexLabel:
+		// the ownership record of a zone apex
+		return labels[1], recordType
+	case txtWildcardLabel:
+		return "*." + labels[1], recordType
+	default:
+		return label + "." + labels[1], recordType
 	}
 }
 
-func (im *TXTRegistry) removeFromCache(ep *endpoint.Endpoint) {
-	if im.recordsCache == nil || ep == nil {
-		// return early.
-		return
+// toVersionedTXTName returns the name of the TXT record in the versioned format holding the ownership
+// of a record with the given name and type, e.g. a-foo.example.org. As the ownership record can't
+// be located above a zone apex, in another zone, it's located below the apex, e.g. a-_apex.example.org.
+// The ownership record of a wildcard replaces its label, e.g. a-_wildcard.example.org, as a-*.example.org
+// would be a wildcard itself.
+func (pr affixNameMapper) toVersionedTXTName(endpointDNSName, recordType string) string {
+	typePrefix := strings.ToLower(recordType) + "-"
+	DNSName := strings.SplitN(endpointDNSName, ".", 2)
+	if len(DNSName) < 2 || pr.zones.isApex(endpointDNSName) {
+		return pr.prefix + typePrefix + txtApexLabel + pr.suffix + "." + endpointDNSName
 	}
+	if DNSName[0] == "*" {
+		DNSName[0] = txtWildcardLabel
+	}
+	return pr.prefix + typePrefix + DNSName[0] + pr.suffix + "." + DNSName[1]
+}
 
-	for i, e := range im.recordsCache {
-		if e.DNSName == ep.DNSName && e.RecordType == ep.RecordType && e.SetIdentifier == ep.SetIdentifier && e.Targets.Same(ep.Targets) {
-			// We found a match delete the endpoint from the cache.
-			im.recordsCache = append(im.recordsCache[:i], im.recordsCache[i+1:]...)
-			return
+// txtZones are the zones of the DNS provider, e.g. the domain filters, which tell the zone apexes from the other names.
+type txtZones []string
+
+// isApex returns true if the DNS name is the apex of a zone: one of the zones, or, if the name isn't in any
+// of the zones, a name directly below a public suffix, like example.org or example.co.uk.
+func (z txtZones) isApex(dnsName string) bool {
+	dnsName = strings.ToLower(strings.TrimSuffix(dnsName, "."))
+	zone := ""
+	for _, name := range z {
+		name = strings.ToLower(strings.Trim(name, "."))
+		if name != "" && len(name) > len(zone) && (dnsName == name || strings.HasSuffix(dnsName, "."+name)) {
+			zone = name
 		}
 	}
+	if zone != "" {
+		// the name is in the most specific zone containing it
+		return zone == dnsName
+	}
+	apex, err := publicsuffix.EffectiveTLDPlusOne(dnsName)
+	return err == nil && apex == dnsName
 }
@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+var (
+	recordsCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "registry",
+			Name:      "records_cache_hits_total",
+			Help:      "Number of times the records were served from the cache of the TXT registry.",
+		},
+	)
+	recordsCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "registry",
+			Name:      "records_cache_misses_total",
+			Help:      "Number of times the records were listed from the provider because the cache of the TXT registry was empty, expired or invalidated.",
+		},
+	)
+	recordsCacheInvalidations = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "registry",
+			Name:      "records_cache_invalidations_total",
+			Help:      "Number of times the cache of the TXT registry was invalidated because changes failed to apply.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(recordsCacheHits)
+	prometheus.MustRegister(recordsCacheMisses)
+	prometheus.MustRegister(recordsCacheInvalidations)
+}
+
+// recordsCache holds the records of the TXT registry for an interval, indexed by name, type and set identifier,
+// so that the applied changes can be reflected without listing the records again. It's safe for concurrent use.
+type recordsCache struct {
+	mu          sync.Mutex
+	interval    time.Duration
+	records     map[string]*endpoint.Endpoint
+	refreshTime time.Time
+}
+
+func newRecordsCache(interval time.Duration) *recordsCache {
+	return &recordsCache{interval: interval}
+}
+
+// get returns the cached records, or false if the cache is empty, expired or invalidated.
+func (c *recordsCache) get() ([]*endpoint.Endpoint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.records == nil || time.Since(c.refreshTime) >= c.interval {
+		recordsCacheMisses.Inc()
+		return nil, false
+	}
+	recordsCacheHits.Inc()
+
+	keys := make([]string, 0, len(c.records))
+	for key := range c.records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	records := make([]*endpoint.Endpoint, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, c.records[key])
+	}
+	return records, true
+}
+
+// set replaces the cached records and starts a new interval.
+func (c *recordsCache) set(records []*endpoint.Endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.records = make(map[string]*endpoint.Endpoint, len(records))
+	for _, record := range records {
+		c.records[versionedOwnershipKey(record)] = record
+	}
+	c.refreshTime = time.Now()
+}
+
+// add adds or replaces a record, unless the cache is empty.
+func (c *recordsCache) add(record *endpoint.Endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.records != nil {
+		c.records[versionedOwnershipKey(record)] = record
+	}
+}
+
+// remove removes the record with the same name, type and set identifier, regardless of its targets.
+func (c *recordsCache) remove(record *endpoint.Endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.records, versionedOwnershipKey(record))
+}
+
+// reset empties the cache, so that the records are listed again.
+func (c *recordsCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.records = nil
+}
+
+// invalidate empties the cache, so that the records are listed again, e.g. when changes failed to apply partially.
+func (c *recordsCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.records != nil {
+		recordsCacheInvalidations.Inc()
+	}
+	c.records = nil
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func TestRecordsCache(t *testing.T) {
+	cache := newRecordsCache(time.Hour)
+
+	// nothing is added to an empty cache, as it would be incomplete
+	cache.add(newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"))
+	_, ok := cache.get()
+	assert.False(t, ok)
+
+	cache.set([]*endpoint.Endpoint{
+		newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"),
+		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
+		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
+		newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner").WithSetIdentifier("set-1"),
+		newEndpointWithOwner("thing3.com", "1.2.3.5", "A", "owner").WithSetIdentifier("set-2"),
+		endpoint.NewEndpoint("thing4.com", "A", "1.2.3.4", "1.2.3.5"),
+	})
+
+	// test add cache
+	cache.add(newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"))
+	records, ok := cache.get()
+	require.True(t, ok)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"),
+		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
+		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
+		newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner").WithSetIdentifier("set-1"),
+		newEndpointWithOwner("thing3.com", "1.2.3.5", "A", "owner").WithSetIdentifier("set-2"),
+		endpoint.NewEndpoint("thing4.com", "A", "1.2.3.4", "1.2.3.5"),
+		newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"),
+	}))
+
+	// test update cache
+	cache.remove(newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"))
+	cache.add(newEndpointWithOwner("thing.com", "1.2.3.6", "A", "owner2"))
+	// records are matched by name, type and set identifier regardless of the order of their targets
+	thing4 := endpoint.NewEndpoint("thing4.com", "A", "1.2.3.5", "1.2.3.4")
+	cache.remove(thing4)
+	cache.remove(newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner").WithSetIdentifier("set-1"))
+	records, ok = cache.get()
+	require.True(t, ok)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwner("thing.com", "1.2.3.6", "A", "owner2"),
+		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
+		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
+		newEndpointWithOwner("thing3.com", "1.2.3.5", "A", "owner").WithSetIdentifier("set-2"),
+		newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"),
+	}))
+
+	// test invalidating the cache
+	invalidations := testutil.ToFloat64(recordsCacheInvalidations)
+	cache.invalidate()
+	_, ok = cache.get()
+	assert.False(t, ok)
+	assert.Equal(t, invalidations+1, testutil.ToFloat64(recordsCacheInvalidations))
+}
+
+func TestRecordsCacheExpiry(t *testing.T) {
+	cache := newRecordsCache(time.Hour)
+	hits, misses := testutil.ToFloat64(recordsCacheHits), testutil.ToFloat64(recordsCacheMisses)
+
+	_, ok := cache.get()
+	assert.False(t, ok)
+	cache.set([]*endpoint.Endpoint{newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner")})
+	_, ok = cache.get()
+	assert.True(t, ok)
+	cache.refreshTime = time.Now().Add(-time.Hour)
+	_, ok = cache.get()
+	assert.False(t, ok)
+
+	assert.Equal(t, hits+1, testutil.ToFloat64(recordsCacheHits))
+	assert.Equal(t, misses+2, testutil.ToFloat64(recordsCacheMisses))
+}
+
+func TestRecordsCacheConcurrency(t *testing.T) {
+	cache := newRecordsCache(time.Hour)
+	cache.set([]*endpoint.Endpoint{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ep := newEndpointWithOwner(fmt.Sprintf("thing%d.com", i), "1.2.3.4", "A", "owner")
+			for j := 0; j < 100; j++ {
+				cache.add(ep)
+				cache.get()
+				cache.remove(ep)
+			}
+			cache.add(ep)
+		}(i)
+	}
+	wg.Wait()
+
+	records, ok := cache.get()
+	require.True(t, ok)
+	assert.Len(t, records, 10)
+}
+
+func TestTXTRegistryCacheInvalidation(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+	}))
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", CacheInterval: time.Hour})
+
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
+
+	// the applied changes are reflected by the cache
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, "")},
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, "owner"),
+		},
+	}))
+	hits := testutil.ToFloat64(recordsCacheHits)
+	records, err = r.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, hits+1, testutil.ToFloat64(recordsCacheHits))
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, "owner"),
+		newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, "owner"),
+	}))
+
+	// the cache is invalidated when changes fail to apply, as they may have been applied partially
+	err = r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, "")},
+	})
+	require.Error(t, err)
+	_, ok := r.cache.get()
+	assert.False(t, ok)
+
+	records, err = r.Records(context.Background())
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, "owner"),
+		newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, "owner"),
+	}))
+}
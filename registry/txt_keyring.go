@@ -0,0 +1,235 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// txtEncryptedLabelKey holds the encrypted labels of an ownership record, as <key id>:<nonce and ciphertext>
+	txtEncryptedLabelKey = "encrypted"
+	// txtSignatureLabelKey holds the signature of the labels of an ownership record, as <key id>:<HMAC>
+	txtSignatureLabelKey = "signature"
+
+	// txtKeySize is the size of the keys, which are used for AES-256 and HMAC-SHA256
+	txtKeySize = 32
+)
+
+var (
+	errUnknownTXTKey    = errors.New("unknown key")
+	errInvalidTXTLabels = errors.New("invalid signature or ciphertext")
+)
+
+// txtProtection is how the labels of an ownership record are protected.
+type txtProtection int
+
+const (
+	// txtUnprotected labels are neither signed nor encrypted
+	txtUnprotected txtProtection = iota
+	// txtOutdated labels are protected with a previous key, or signed instead of encrypted or vice versa
+	txtOutdated
+	// txtCurrent labels are protected with the first key and in the mode of the keyring
+	txtCurrent
+)
+
+// txtKey is a key of a TXTKeyring, from which separate encryption and signing keys are derived.
+type txtKey struct {
+	id         string
+	encryption cipher.AEAD
+	signing    []byte
+}
+
+// TXTKeyring protects the labels of the TXT ownership records, which are public, against tampering and optionally
+// against disclosure. The labels are either signed with HMAC-SHA256, or encrypted with AES-256-GCM, whose
+// authentication tag protects them as well. Both bind the labels to the name of the ownership record, so that
+// they can't be copied to claim the ownership of another record.
+//
+// Keys are rotated by adding a new key in front of the previous ones: the first key protects the ownership records,
+// all keys are accepted when reading them. The registry rewrites the ownership records protected with a previous key,
+// once they all have been rewritten the previous keys can be removed.
+type TXTKeyring struct {
+	keys    []txtKey
+	encrypt bool
+	// accept the ownership records which are neither signed nor encrypted, which is required to take over
+	// the ownership of the records created before the keyring was configured
+	allowUnprotected bool
+	// the source of the nonces of the encrypted labels
+	random io.Reader
+}
+
+// NewTXTKeyring returns a keyring protecting the labels with the first key and accepting all keys. Keys must have 32 bytes.
+func NewTXTKeyring(keys [][]byte, encrypt, allowUnprotected bool) (*TXTKeyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+
+	keyring := &TXTKeyring{encrypt: encrypt, allowUnprotected: allowUnprotected, random: rand.Reader}
+	for i, key := range keys {
+		if len(key) != txtKeySize {
+			return nil, fmt.Errorf("key %d has %d bytes instead of %d", i+1, len(key), txtKeySize)
+		}
+		block, err := aes.NewCipher(deriveTXTKey(key, "encryption"))
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		keyring.keys = append(keyring.keys, txtKey{
+			id:         hex.EncodeToString(sum[:4]),
+			encryption: aead,
+			signing:    deriveTXTKey(key, "signing"),
+		})
+	}
+	return keyring, nil
+}
+
+// LoadTXTKeyring returns a keyring with the base64 encoded keys read from the given files, e.g. mounted from a Secret.
+func LoadTXTKeyring(files []string, encrypt, allowUnprotected bool) (*TXTKeyring, error) {
+	keys := make([][]byte, 0, len(files))
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the key in %s: %v", file, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewTXTKeyring(keys, encrypt, allowUnprotected)
+}
+
+// deriveTXTKey derives a key for the given purpose, so that the same key is never used for encrypting and signing.
+func deriveTXTKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("external-dns txt " + purpose))
+	return mac.Sum(nil)
+}
+
+// seal returns the value of the ownership record with the given name holding the given labels.
+// It fails if no nonce can be read from the system's random number generator, rather than using a predictable one.
+func (k *TXTKeyring) seal(txtName string, labels endpoint.Labels) (string, error) {
+	key := k.keys[0]
+	if k.encrypt {
+		nonce := make([]byte, key.encryption.NonceSize())
+		if _, err := io.ReadFull(k.random, nonce); err != nil {
+			return "", fmt.Errorf("failed to generate a nonce to encrypt the ownership record %s: %w", txtName, err)
+		}
+		ciphertext := key.encryption.Seal(nonce, nonce, []byte(labels.Serialize(false)), []byte(normalizeTXTName(txtName)))
+		return endpoint.Labels{txtEncryptedLabelKey: key.id + ":" + base64.RawURLEncoding.EncodeToString(ciphertext)}.Serialize(true), nil
+	}
+
+	signed := endpoint.NewLabels()
+	for name, value := range labels {
+		signed[name] = value
+	}
+	signed[txtSignatureLabelKey] = key.id + ":" + base64.RawURLEncoding.EncodeToString(key.sign(txtName, labels))
+	return signed.Serialize(true), nil
+}
+
+// open returns the labels held by the ownership record with the given name, and how they were protected.
+// It fails if the labels can't be decrypted or their signature is invalid.
+func (k *TXTKeyring) open(txtName string, labels endpoint.Labels) (endpoint.Labels, txtProtection, error) {
+	if value, ok := labels[txtEncryptedLabelKey]; ok {
+		key, data, err := k.lookup(value)
+		if err != nil {
+			return nil, txtOutdated, err
+		}
+		nonceSize := key.encryption.NonceSize()
+		if len(data) < nonceSize {
+			return nil, txtOutdated, errInvalidTXTLabels
+		}
+		plaintext, err := key.encryption.Open(nil, data[:nonceSize], data[nonceSize:], []byte(normalizeTXTName(txtName)))
+		if err != nil {
+			return nil, txtOutdated, errInvalidTXTLabels
+		}
+		decrypted, err := endpoint.NewLabelsFromString(string(plaintext))
+		return decrypted, k.protection(key, true), err
+	}
+
+	if value, ok := labels[txtSignatureLabelKey]; ok {
+		key, signature, err := k.lookup(value)
+		if err != nil {
+			return nil, txtOutdated, err
+		}
+		verified := endpoint.NewLabels()
+		for name, value := range labels {
+			if name != txtSignatureLabelKey {
+				verified[name] = value
+			}
+		}
+		if !hmac.Equal(signature, key.sign(txtName, verified)) {
+			return nil, txtOutdated, errInvalidTXTLabels
+		}
+		return verified, k.protection(key, false), nil
+	}
+
+	return labels, txtUnprotected, nil
+}
+
+func (k *TXTKeyring) protection(key txtKey, encrypted bool) txtProtection {
+	if key.id == k.keys[0].id && encrypted == k.encrypt {
+		return txtCurrent
+	}
+	return txtOutdated
+}
+
+// lookup returns the key and the decoded data of a protected label value.
+func (k *TXTKeyring) lookup(value string) (txtKey, []byte, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return txtKey{}, nil, errInvalidTXTLabels
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return txtKey{}, nil, errInvalidTXTLabels
+	}
+	for _, key := range k.keys {
+		if key.id == parts[0] {
+			return key, data, nil
+		}
+	}
+	return txtKey{}, nil, errUnknownTXTKey
+}
+
+func (key txtKey) sign(txtName string, labels endpoint.Labels) []byte {
+	mac := hmac.New(sha256.New, key.signing)
+	mac.Write([]byte(normalizeTXTName(txtName) + "\n" + labels.Serialize(false)))
+	return mac.Sum(nil)
+}
+
+func normalizeTXTName(txtName string) string {
+	return strings.TrimSuffix(strings.ToLower(txtName), ".")
+}
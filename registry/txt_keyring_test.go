@@ -0,0 +1,267 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+var (
+	testTXTKey      = bytes.Repeat([]byte{1}, txtKeySize)
+	testTXTKeyOther = bytes.Repeat([]byte{2}, txtKeySize)
+)
+
+func newTestTXTKeyring(t *testing.T, encrypt, allowUnprotected bool, keys ...[]byte) *TXTKeyring {
+	keyring, err := NewTXTKeyring(keys, encrypt, allowUnprotected)
+	require.NoError(t, err)
+	return keyring
+}
+
+// sealTXTValue returns the value of an ownership record sealed with the keyring.
+func sealTXTValue(t *testing.T, keyring *TXTKeyring, txtName string, labels endpoint.Labels) string {
+	value, err := keyring.seal(txtName, labels)
+	require.NoError(t, err)
+	return value
+}
+
+// openTXTValue parses and opens the value of an ownership record like the registry does.
+func openTXTValue(keyring *TXTKeyring, txtName, value string) (endpoint.Labels, txtProtection, error) {
+	labels, err := endpoint.NewLabelsFromString(value)
+	if err != nil {
+		return nil, txtUnprotected, err
+	}
+	return keyring.open(txtName, labels)
+}
+
+func TestNewTXTKeyring(t *testing.T) {
+	_, err := NewTXTKeyring(nil, false, false)
+	assert.Error(t, err)
+
+	_, err = NewTXTKeyring([][]byte{testTXTKey, []byte("too short")}, false, false)
+	assert.Error(t, err)
+
+	keyring, err := NewTXTKeyring([][]byte{testTXTKey, testTXTKeyOther}, true, true)
+	require.NoError(t, err)
+	assert.Len(t, keyring.keys, 2)
+	assert.NotEqual(t, keyring.keys[0].id, keyring.keys[1].id)
+	assert.True(t, keyring.encrypt)
+	assert.True(t, keyring.allowUnprotected)
+}
+
+func TestTXTKeyringSeal(t *testing.T) {
+	labels := endpoint.Labels{
+		endpoint.OwnerLabelKey:    "owner",
+		endpoint.ResourceLabelKey: "ingress/secret-namespace/secret-ingress",
+	}
+
+	for _, encrypt := range []bool{false, true} {
+		keyring := newTestTXTKeyring(t, encrypt, false, testTXTKey)
+		value := sealTXTValue(t, keyring, "a-foo.example.org", labels)
+
+		assert.True(t, strings.HasPrefix(value, "\"heritage=external-dns,"))
+		if encrypt {
+			assert.NotContains(t, value, "secret-namespace")
+			assert.NotContains(t, value, "owner=owner")
+			// a random nonce is used for every encryption
+			assert.NotEqual(t, value, sealTXTValue(t, keyring, "a-foo.example.org", labels))
+		} else {
+			assert.Contains(t, value, "external-dns/resource=ingress/secret-namespace/secret-ingress")
+		}
+
+		opened, protection, err := openTXTValue(keyring, "a-foo.example.org", value)
+		require.NoError(t, err)
+		assert.Equal(t, txtCurrent, protection)
+		assert.Equal(t, labels, opened)
+
+		// names are compared case insensitively
+		_, _, err = openTXTValue(keyring, "A-Foo.Example.Org.", value)
+		assert.NoError(t, err)
+
+		// the value can't be copied to claim the ownership of another record
+		_, _, err = openTXTValue(keyring, "a-bar.example.org", value)
+		assert.Error(t, err)
+
+		// the value is outdated when switching between signing and encrypting
+		_, protection, err = openTXTValue(newTestTXTKeyring(t, !encrypt, false, testTXTKey), "a-foo.example.org", value)
+		require.NoError(t, err)
+		assert.Equal(t, txtOutdated, protection)
+
+		// the value can't be read without the key
+		_, _, err = openTXTValue(newTestTXTKeyring(t, encrypt, false, testTXTKeyOther), "a-foo.example.org", value)
+		assert.Equal(t, errUnknownTXTKey, err)
+	}
+}
+
+// failingReader fails to read, like a broken random number generator.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("no entropy")
+}
+
+func TestTXTKeyringSealFailure(t *testing.T) {
+	keyring := newTestTXTKeyring(t, true, false, testTXTKey)
+	keyring.random = failingReader{}
+
+	_, err := keyring.seal("a-foo.example.org", endpoint.Labels{endpoint.OwnerLabelKey: "owner"})
+	assert.Error(t, err, "should not encrypt with a predictable nonce")
+
+	// signing doesn't require any nonce
+	keyring.encrypt = false
+	_, err = keyring.seal("a-foo.example.org", endpoint.Labels{endpoint.OwnerLabelKey: "owner"})
+	assert.NoError(t, err)
+}
+
+func TestTXTKeyringOpenForged(t *testing.T) {
+	keyring := newTestTXTKeyring(t, false, false, testTXTKey)
+	signed := sealTXTValue(t, keyring, "a-foo.example.org", endpoint.Labels{endpoint.OwnerLabelKey: "owner"})
+	encrypted := sealTXTValue(t, newTestTXTKeyring(t, true, false, testTXTKey), "a-foo.example.org", endpoint.Labels{endpoint.OwnerLabelKey: "owner"})
+
+	for _, tc := range []struct {
+		title string
+		value string
+	}{
+		{
+			title: "changed owner",
+			value: strings.Replace(signed, "owner=owner", "owner=attacker", 1),
+		},
+		{
+			title: "added label",
+			value: strings.Replace(signed, "heritage=external-dns,", "heritage=external-dns,external-dns/resource=ingress/default/foo,", 1),
+		},
+		{
+			title: "invalid signature",
+			value: "\"heritage=external-dns,external-dns/owner=owner,external-dns/signature=" + keyring.keys[0].id + ":invalid\"",
+		},
+		{
+			title: "missing key id",
+			value: "\"heritage=external-dns,external-dns/owner=owner,external-dns/signature=invalid\"",
+		},
+		{
+			title: "truncated ciphertext",
+			value: "\"heritage=external-dns,external-dns/encrypted=" + keyring.keys[0].id + ":AAAA\"",
+		},
+		{
+			title: "changed ciphertext",
+			value: strings.Replace(encrypted, ":", ":A", 1),
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			_, _, err := openTXTValue(keyring, "a-foo.example.org", tc.value)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTXTKeyringOpenUnprotected(t *testing.T) {
+	keyring := newTestTXTKeyring(t, false, false, testTXTKey)
+	labels, protection, err := openTXTValue(keyring, "a-foo.example.org", "\"heritage=external-dns,external-dns/owner=owner\"")
+	require.NoError(t, err)
+	assert.Equal(t, txtUnprotected, protection)
+	assert.Equal(t, endpoint.Labels{endpoint.OwnerLabelKey: "owner"}, labels)
+}
+
+func TestTXTKeyringRotation(t *testing.T) {
+	labels := endpoint.Labels{endpoint.OwnerLabelKey: "owner"}
+	previous := newTestTXTKeyring(t, true, false, testTXTKey)
+	value := sealTXTValue(t, previous, "a-foo.example.org", labels)
+
+	// the new key protects the new values, the previous key is still accepted
+	rotated := newTestTXTKeyring(t, true, false, testTXTKeyOther, testTXTKey)
+	opened, protection, err := openTXTValue(rotated, "a-foo.example.org", value)
+	require.NoError(t, err)
+	assert.Equal(t, labels, opened)
+	assert.Equal(t, txtOutdated, protection)
+
+	_, _, err = openTXTValue(previous, "a-foo.example.org", sealTXTValue(t, rotated, "a-foo.example.org", labels))
+	assert.Equal(t, errUnknownTXTKey, err)
+}
+
+func TestTXTKeyringValueLength(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		encrypt  bool
+		resource string
+		expected endpoint.Labels
+	}{
+		{
+			title:    "short resource label is kept",
+			resource: "ingress/default/foo",
+			expected: endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "ingress/default/foo"},
+		},
+		{
+			title:    "long resource label is left out of the signed value",
+			resource: "ingress/" + strings.Repeat("n", 63) + "/" + strings.Repeat("i", 150),
+			expected: endpoint.Labels{endpoint.OwnerLabelKey: "owner"},
+		},
+		{
+			title:    "long resource label is left out of the encrypted value",
+			encrypt:  true,
+			resource: "ingress/" + strings.Repeat("n", 63) + "/" + strings.Repeat("i", 120),
+			expected: endpoint.Labels{endpoint.OwnerLabelKey: "owner"},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			keyring := newTestTXTKeyring(t, tc.encrypt, false, testTXTKey)
+			im := &TXTRegistry{keyring: keyring}
+			value, err := im.txtValue("a-foo.example.org", endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: tc.resource})
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(value), txtMaxValueLength)
+
+			opened, protection, err := openTXTValue(keyring, "a-foo.example.org", value)
+			require.NoError(t, err)
+			assert.Equal(t, txtCurrent, protection)
+			assert.Equal(t, tc.expected, opened)
+		})
+	}
+}
+
+func TestLoadTXTKeyring(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txt-keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	current := filepath.Join(dir, "current")
+	require.NoError(t, ioutil.WriteFile(current, []byte(base64.StdEncoding.EncodeToString(testTXTKey)+"\n"), 0600))
+	previous := filepath.Join(dir, "previous")
+	require.NoError(t, ioutil.WriteFile(previous, []byte(base64.StdEncoding.EncodeToString(testTXTKeyOther)), 0600))
+	invalid := filepath.Join(dir, "invalid")
+	require.NoError(t, ioutil.WriteFile(invalid, []byte("not base64!"), 0600))
+
+	keyring, err := LoadTXTKeyring([]string{current, previous}, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, newTestTXTKeyring(t, true, false, testTXTKey).keys[0].id, keyring.keys[0].id)
+	assert.Len(t, keyring.keys, 2)
+
+	_, err = LoadTXTKeyring([]string{invalid}, true, false)
+	assert.Error(t, err)
+
+	_, err = LoadTXTKeyring([]string{filepath.Join(dir, "missing")}, true, false)
+	assert.Error(t, err)
+}
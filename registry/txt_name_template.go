@@ -0,0 +1,166 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const (
+	// txtApexLabel replaces the label of a zone apex in the names of the ownership records,
+	// which can't be located above the apex
+	txtApexLabel = "_apex"
+	// txtWildcardLabel replaces the wildcard label, as an ownership record named *.example.org
+	// would be a wildcard record itself and many providers reject names like txt-*.example.org
+	txtWildcardLabel = "_wildcard"
+)
+
+// txtNameData holds the values of a name template of the ownership records.
+type txtNameData struct {
+	// RecordType is the type of the owned record in lower case, e.g. a or cname
+	RecordType string
+	// Label is the leftmost label of the owned record, or _apex for a zone apex and _wildcard for a wildcard
+	Label string
+	// Zone is the remainder of the name of the owned record, or the whole name for a zone apex,
+	// as told by the zones of the registry
+	Zone string
+}
+
+// the markers rendered in place of the values to derive the pattern matching the names generated by a template
+var txtNameMarkers = txtNameData{RecordType: "\x00recordtype\x00", Label: "\x00label\x00", Zone: "\x00zone\x00"}
+
+// templateNameMapper maps the records to the names of their ownership records in the versioned format with
+// a template like {{.RecordType}}-{{.Label}}.{{.Zone}}. The template is reversed into a pattern matching
+// the names it generates, so that the owned record can be told for each ownership record.
+type templateNameMapper struct {
+	tmpl    *template.Template
+	pattern *regexp.Regexp
+	// the indexes of the submatches of the pattern
+	recordType, label, zone int
+	zones                   txtZones
+}
+
+var _ nameMapper = templateNameMapper{}
+
+func newTemplateNameMapper(text string, zones []string) (templateNameMapper, error) {
+	tmpl, err := template.New("txt-name-template").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return templateNameMapper{}, fmt.Errorf("failed to parse the txt name template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, txtNameMarkers); err != nil {
+		return templateNameMapper{}, fmt.Errorf("failed to execute the txt name template: %v", err)
+	}
+	rendered := strings.ToLower(buf.String())
+
+	// the ownership records have to be located in the zone of the records they own
+	if !strings.HasSuffix(rendered, "."+txtNameMarkers.Zone) {
+		return templateNameMapper{}, fmt.Errorf("the txt name template %q must end with .{{.Zone}}", text)
+	}
+
+	mapper := templateNameMapper{tmpl: tmpl, zones: zones}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	// the literal text and the values alternate, starting and ending with a literal text
+	parts := strings.Split(rendered, "\x00")
+	for i, part := range parts {
+		if i%2 == 0 {
+			if part == "" && i > 0 && i < len(parts)-1 {
+				return templateNameMapper{}, fmt.Errorf("the values of the txt name template %q must be separated by some text", text)
+			}
+			pattern.WriteString(regexp.QuoteMeta(part))
+			continue
+		}
+		group := (i + 1) / 2
+		switch part {
+		case "recordtype":
+			if mapper.recordType != 0 {
+				return templateNameMapper{}, fmt.Errorf("the txt name template %q must use {{.RecordType}} once", text)
+			}
+			mapper.recordType = group
+			pattern.WriteString("([a-z0-9]+)")
+		case "label":
+			if mapper.label != 0 {
+				return templateNameMapper{}, fmt.Errorf("the txt name template %q must use {{.Label}} once", text)
+			}
+			mapper.label = group
+			pattern.WriteString("([^.]+)")
+		case "zone":
+			if mapper.zone != 0 {
+				return templateNameMapper{}, fmt.Errorf("the txt name template %q must use {{.Zone}} once", text)
+			}
+			mapper.zone = group
+			pattern.WriteString("(.+)")
+		default:
+			return templateNameMapper{}, fmt.Errorf("the txt name template %q must use the values as they are", text)
+		}
+	}
+	pattern.WriteString("$")
+	if mapper.recordType == 0 || mapper.label == 0 {
+		return templateNameMapper{}, fmt.Errorf("the txt name template %q must use {{.RecordType}} and {{.Label}}", text)
+	}
+
+	mapper.pattern, err = regexp.Compile(pattern.String())
+	if err != nil {
+		return templateNameMapper{}, fmt.Errorf("failed to reverse the txt name template %q: %v", text, err)
+	}
+	return mapper, nil
+}
+
+// toEndpointName returns the name of the record owned by an ownership record, or an empty string
+// if the name wasn't generated by the template.
+func (pr templateNameMapper) toEndpointName(txtDNSName string) string {
+	name, _ := pr.toEndpointNameAndType(txtDNSName)
+	return name
+}
+
+// toEndpointNameAndType returns the name and type of the record owned by an ownership record.
+func (pr templateNameMapper) toEndpointNameAndType(txtDNSName string) (string, string) {
+	match := pr.pattern.FindStringSubmatch(strings.ToLower(txtDNSName))
+	if match == nil {
+		return "", ""
+	}
+	recordType := strings.ToUpper(match[pr.recordType])
+	switch label := match[pr.label]; label {
+	case txtApexLabel:
+		return match[pr.zone], recordType
+	case txtWildcardLabel:
+		return "*." + match[pr.zone], recordType
+	default:
+		return label + "." + match[pr.zone], recordType
+	}
+}
+
+// toVersionedTXTName returns the name of the ownership record of a record with the given name and type.
+func (pr templateNameMapper) toVersionedTXTName(endpointDNSName, recordType string) string {
+	data := txtNameData{RecordType: strings.ToLower(recordType), Label: txtApexLabel, Zone: strings.ToLower(endpointDNSName)}
+	if labels := strings.SplitN(data.Zone, ".", 2); len(labels) == 2 && !pr.zones.isApex(data.Zone) {
+		data.Label, data.Zone = labels[0], labels[1]
+		if data.Label == "*" {
+			data.Label = txtWildcardLabel
+		}
+	}
+
+	var buf bytes.Buffer
+	// the template was executed successfully with the same fields when it was parsed
+	_ = pr.tmpl.Execute(&buf, data)
+	return strings.ToLower(buf.String())
+}
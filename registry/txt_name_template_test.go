@@ -0,0 +1,150 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func TestTemplateNameMapper(t *testing.T) {
+	for _, tc := range []struct {
+		template   string
+		dnsName    string
+		recordType string
+		txtName    string
+	}{
+		{"{{.RecordType}}-{{.Label}}.{{.Zone}}", "foo.example.org", endpoint.RecordTypeA, "a-foo.example.org"},
+		{"{{.RecordType}}-{{.Label}}.{{.Zone}}", "foo.bar.example.org", endpoint.RecordTypeCNAME, "cname-foo.bar.example.org"},
+		{"{{.RecordType}}-{{.Label}}.{{.Zone}}", "example.org", endpoint.RecordTypeA, "a-_apex.example.org"},
+		{"{{.RecordType}}-{{.Label}}.{{.Zone}}", "example.co.uk", endpoint.RecordTypeAAAA, "aaaa-_apex.example.co.uk"},
+		{"{{.RecordType}}-{{.Label}}.{{.Zone}}", "*.example.org", endpoint.RecordTypeA, "a-_wildcard.example.org"},
+		{"{{.RecordType}}-{{.Label}}.{{.Zone}}", "*.foo.example.org", endpoint.RecordTypeCNAME, "cname-_wildcard.foo.example.org"},
+		{"{{.RecordType}}-{{.Label}}.{{.Zone}}", "foo-bar.example.org", endpoint.RecordTypeTXT, "txt-foo-bar.example.org"},
+		{"{{.RecordType}}-{{.Label}}.{{.Zone}}", "localhost", endpoint.RecordTypeA, "a-_apex.localhost"},
+		{"txt.{{.RecordType}}.{{.Label}}.{{.Zone}}", "foo.example.org", endpoint.RecordTypeA, "txt.a.foo.example.org"},
+		{"txt.{{.RecordType}}.{{.Label}}.{{.Zone}}", "example.org", endpoint.RecordTypeA, "txt.a._apex.example.org"},
+		{"txt.{{.RecordType}}.{{.Label}}.{{.Zone}}", "*.example.org", endpoint.RecordTypeA, "txt.a._wildcard.example.org"},
+		{"{{.Label}}-{{.RecordType}}-TXT.{{.Zone}}", "foo-txt.example.org", endpoint.RecordTypeSRV, "foo-txt-srv-txt.example.org"},
+		{"{{.Label}}-{{.RecordType}}-TXT.{{.Zone}}", "*.example.org", endpoint.RecordTypeSRV, "_wildcard-srv-txt.example.org"},
+		{"_owner.{{.Label}}.{{.RecordType}}.{{.Zone}}", "a.example.org", endpoint.RecordTypeTXT, "_owner.a.txt.example.org"},
+	} {
+		t.Run(tc.template+" "+tc.dnsName, func(t *testing.T) {
+			mapper, err := newTemplateNameMapper(tc.template, nil)
+			require.NoError(t, err)
+
+			txtName := mapper.toVersionedTXTName(tc.dnsName, tc.recordType)
+			assert.Equal(t, tc.txtName, txtName)
+
+			// the names round-trip
+			dnsName, recordType := mapper.toEndpointNameAndType(txtName)
+			assert.Equal(t, tc.dnsName, dnsName)
+			assert.Equal(t, tc.recordType, recordType)
+			assert.Equal(t, tc.dnsName, mapper.toEndpointName(txtName))
+		})
+	}
+}
+
+func TestTemplateNameMapperZones(t *testing.T) {
+	mapper, err := newTemplateNameMapper("txt.{{.RecordType}}-{{.Label}}.{{.Zone}}", []string{"example.org", "sub.example.org"})
+	require.NoError(t, err)
+
+	for dnsName, txtName := range map[string]string{
+		// the apex of a delegated zone isn't below a public suffix
+		"sub.example.org":     "txt.a-_apex.sub.example.org",
+		"foo.sub.example.org": "txt.a-foo.sub.example.org",
+		"example.org":         "txt.a-_apex.example.org",
+		"bar.example.org":     "txt.a-bar.example.org",
+		// the names outside of the zones fall back to the public suffixes
+		"example.com":     "txt.a-_apex.example.com",
+		"foo.example.com": "txt.a-foo.example.com",
+	} {
+		assert.Equal(t, txtName, mapper.toVersionedTXTName(dnsName, endpoint.RecordTypeA), dnsName)
+
+		// the names round-trip
+		name, recordType := mapper.toEndpointNameAndType(txtName)
+		assert.Equal(t, dnsName, name)
+		assert.Equal(t, endpoint.RecordTypeA, recordType)
+	}
+}
+
+func TestTemplateNameMapperMismatch(t *testing.T) {
+	mapper, err := newTemplateNameMapper("txt.{{.RecordType}}-{{.Label}}.{{.Zone}}", nil)
+	require.NoError(t, err)
+
+	for _, txtName := range []string{"foo.example.org", "txt.foo.example.org", "a-foo.example.org", "txt.-foo.example.org"} {
+		dnsName, recordType := mapper.toEndpointNameAndType(txtName)
+		assert.Empty(t, dnsName, txtName)
+		assert.Empty(t, recordType, txtName)
+		assert.Empty(t, mapper.toEndpointName(txtName), txtName)
+	}
+}
+
+func TestTemplateNameMapperInvalid(t *testing.T) {
+	for _, template := range []string{
+		"{{.RecordType}}-{{.Label",
+		"{{.RecordType}}-{{.Name}}.{{.Zone}}",
+		"{{.RecordType}}-{{.Label}}",
+		"{{.Zone}}.{{.RecordType}}-{{.Label}}",
+		"{{.Label}}.{{.Zone}}",
+		"{{.RecordType}}.{{.Zone}}",
+		"{{.RecordType}}{{.Label}}.{{.Zone}}",
+		"{{.RecordType}}-{{.Label}}-{{.Label}}.{{.Zone}}",
+		"{{.RecordType}}-{{.Label}}.{{.Zone}}.{{.Zone}}",
+		"{{.RecordType}}-{{slice .Label 1}}.{{.Zone}}",
+	} {
+		_, err := newTemplateNameMapper(template, nil)
+		assert.Error(t, err, template)
+	}
+}
+
+func TestTXTRegistryNameTemplate(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone("example.org")
+	r, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", NameTemplate: "txt.{{.RecordType}}-{{.Label}}.{{.Zone}}"})
+	require.NoError(t, err)
+
+	records := []*endpoint.Endpoint{
+		newEndpointWithOwner("example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+		newEndpointWithOwner("*.example.org", "1.2.3.5", endpoint.RecordTypeA, "owner"),
+		newEndpointWithOwner("foo.example.org", "bar.example.org", endpoint.RecordTypeCNAME, "owner"),
+	}
+	require.NoError(t, r.ApplyChanges(context.Background(), &plan.Changes{Create: records}))
+
+	current, err := p.Records(context.Background())
+	require.NoError(t, err)
+	names := []string{}
+	for _, record := range current {
+		if record.RecordType == endpoint.RecordTypeTXT {
+			names = append(names, record.DNSName)
+		}
+	}
+	assert.ElementsMatch(t, []string{"txt.a-_apex.example.org", "txt.a-_wildcard.example.org", "txt.cname-foo.example.org"}, names)
+
+	// the ownership records are matched to the records they own
+	got, err := r.Records(context.Background())
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(got, records))
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const (
+	// txtSharedOwnerLabelPrefix prefixes the labels of the ownership record of a shared record which hold
+	// the targets contributed by each owner, e.g. shared-owner/cluster-1=1.2.3.4;1.2.3.5
+	txtSharedOwnerLabelPrefix = "shared-owner/"
+	txtSharedTargetSeparator  = ";"
+)
+
+// sharedRecord is a record shared by several owners, each of them contributing its own targets.
+type sharedRecord struct {
+	// the record of the DNS provider, holding the targets of all owners
+	record *endpoint.Endpoint
+	// its ownership record and the labels it holds
+	txt    *endpoint.Endpoint
+	labels endpoint.Labels
+	// the targets contributed by each owner
+	contributions map[string]endpoint.Targets
+}
+
+// isShareable returns true if the records of the given type can be shared, which requires them to hold several targets.
+func isShareable(ep *endpoint.Endpoint) bool {
+	return ep.RecordType == endpoint.RecordTypeA || ep.RecordType == endpoint.RecordTypeAAAA
+}
+
+// shareRecords returns the records as seen by the current instance when the records are shared: a shared record
+// only holds the targets contributed by the current instance, and is left out if it doesn't contribute any,
+// so that joining it is planned as a creation. The shared records are kept to apply the changes of the views.
+func (im *TXTRegistry) shareRecords(records []*endpoint.Endpoint, txts map[string]*endpoint.Endpoint, labels map[string]endpoint.Labels) []*endpoint.Endpoint {
+	shared := map[string]*sharedRecord{}
+	views := []*endpoint.Endpoint{}
+	for _, r := range records {
+		key := versionedOwnershipKey(r)
+		contributions := im.sharedContributions(labels[key])
+		if !isShareable(r) || contributions == nil {
+			views = append(views, r)
+			continue
+		}
+		shared[key] = &sharedRecord{record: r, txt: txts[key], labels: labels[key], contributions: contributions}
+
+		contributed := map[string]bool{}
+		for _, target := range contributions[im.ownerID] {
+			contributed[target] = true
+		}
+		targets := endpoint.Targets{}
+		for _, target := range r.Targets {
+			if contributed[target] {
+				targets = append(targets, target)
+			}
+		}
+		if len(targets) == 0 {
+			continue
+		}
+
+		view := r.DeepCopy()
+		view.Targets = targets
+		view.Labels = endpoint.NewLabels()
+		for k, v := range r.Labels {
+			if !strings.HasPrefix(k, txtSharedOwnerLabelPrefix) {
+				view.Labels[k] = v
+			}
+		}
+		view.Labels[endpoint.OwnerLabelKey] = im.ownerID
+		views = append(views, view)
+	}
+	im.sharedRecords = shared
+	return views
+}
+
+// shareChanges takes the changes of the shared records out of the given changes, and returns the changes
+// of the shared records and of their ownership records adding, replacing or removing the targets contributed
+// by the current instance. A shared record is deleted along with its ownership record once its last owner leaves.
+func (im *TXTRegistry) shareChanges(changes *plan.Changes) (*plan.Changes, error) {
+	shared := &plan.Changes{}
+
+	create := []*endpoint.Endpoint{}
+	for _, r := range changes.Create {
+		if s, ok := im.sharedRecords[versionedOwnershipKey(r)]; ok {
+			if err := im.contribute(shared, s, r, r.Targets); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		create = append(create, r)
+	}
+	changes.Create = create
+
+	updated := map[string]bool{}
+	updateNew := []*endpoint.Endpoint{}
+	for _, r := range changes.UpdateNew {
+		if s, ok := im.sharedRecords[versionedOwnershipKey(r)]; ok {
+			if err := im.contribute(shared, s, r, r.Targets); err != nil {
+				return nil, err
+			}
+			updated[versionedOwnershipKey(r)] = true
+			continue
+		}
+		updateNew = append(updateNew, r)
+	}
+	changes.UpdateNew = updateNew
+	updateOld := []*endpoint.Endpoint{}
+	for _, r := range changes.UpdateOld {
+		if !updated[versionedOwnershipKey(r)] {
+			updateOld = append(updateOld, r)
+		}
+	}
+	changes.UpdateOld = updateOld
+
+	remaining := []*endpoint.Endpoint{}
+	for _, r := range changes.Delete {
+		if s, ok := im.sharedRecords[versionedOwnershipKey(r)]; ok {
+			if err := im.contribute(shared, s, r, nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		remaining = append(remaining, r)
+	}
+	changes.Delete = remaining
+
+	return shared, nil
+}
+
+// contribute adds the changes replacing the targets contributed by the current instance to the shared record
+// with the given ones, no targets remove the current instance from the owners. The TTL and provider specific
+// properties of the shared record are the ones of the owner in its owner label, the other owners leave them alone
+// so that the owners don't overwrite each other's properties.
+func (im *TXTRegistry) contribute(changes *plan.Changes, s *sharedRecord, r *endpoint.Endpoint, targets endpoint.Targets) error {
+	contributions := map[string]endpoint.Targets{}
+	for owner, contributed := range s.contributions {
+		if owner != im.ownerID {
+			contributions[owner] = contributed
+		}
+	}
+	if len(targets) > 0 {
+		contributions[im.ownerID] = mergeTargets(targets, nil)
+	}
+
+	if len(contributions) == 0 {
+		log.Debugf("Deleting the shared record %s %s, which has no owner left", s.record.RecordType, s.record.DNSName)
+		changes.Delete = append(changes.Delete, s.record, s.txt)
+		return nil
+	}
+
+	labels := im.sharedLabels(s.labels, contributions)
+	labels[txtFormatLabelKey] = txtFormatVersion
+	owned := labels[endpoint.OwnerLabelKey] == im.ownerID
+	if len(targets) > 0 && !owned && contributions[im.ownerID].Same(s.contributions[im.ownerID]) {
+		log.Debugf("Leaving the properties of the shared record %s %s to its owner %s", s.record.RecordType, s.record.DNSName, labels[endpoint.OwnerLabelKey])
+		return nil
+	}
+	value, err := im.sealTXTValue(s.txt.DNSName, labels)
+	if err != nil {
+		return err
+	}
+	if len(targets) > 0 && len(value) > txtMaxValueLength {
+		log.Warnf("Not sharing the targets %v of the record %s %s, its ownership record would exceed %d characters", targets, s.record.RecordType, s.record.DNSName, txtMaxValueLength)
+		return nil
+	}
+
+	record := s.record.DeepCopy()
+	record.Targets = endpoint.Targets{}
+	for _, contributed := range contributions {
+		record.Targets = mergeTargets(record.Targets, contributed)
+	}
+	if len(targets) > 0 && owned {
+		record.RecordTTL = r.RecordTTL
+		record.ProviderSpecific = r.ProviderSpecific
+	}
+	log.Debugf("Sharing the record %s %s with the owners %v", record.RecordType, record.DNSName, sortedOwners(contributions))
+	changes.UpdateOld = append(changes.UpdateOld, s.record)
+	changes.UpdateNew = append(changes.UpdateNew, record)
+
+	txt := endpoint.NewEndpoint(s.txt.DNSName, endpoint.RecordTypeTXT, value).WithSetIdentifier(s.txt.SetIdentifier)
+	txt.ProviderSpecific = s.txt.ProviderSpecific
+	changes.UpdateOld = append(changes.UpdateOld, s.txt)
+	changes.UpdateNew = append(changes.UpdateNew, txt)
+	return nil
+}
+
+// sharedContributions returns the targets contributed by each owner held by the labels of an ownership record,
+// or nil if the record isn't shared. The contributions of the previous owner ids are adopted by the current instance.
+func (im *TXTRegistry) sharedContributions(labels endpoint.Labels) map[string]endpoint.Targets {
+	var contributions map[string]endpoint.Targets
+	for key, value := range labels {
+		if !strings.HasPrefix(key, txtSharedOwnerLabelPrefix) {
+			continue
+		}
+		if contributions == nil {
+			contributions = map[string]endpoint.Targets{}
+		}
+		owner := strings.TrimPrefix(key, txtSharedOwnerLabelPrefix)
+		if im.isOwner(owner) {
+			owner = im.ownerID
+		}
+		targets := endpoint.Targets{}
+		for _, target := range strings.Split(value, txtSharedTargetSeparator) {
+			if target != "" {
+				targets = append(targets, target)
+			}
+		}
+		contributions[owner] = mergeTargets(contributions[owner], targets)
+	}
+	return contributions
+}
+
+// adoptsContributions returns true if the labels of an ownership record hold the contributions of a previous owner id.
+func (im *TXTRegistry) adoptsContributions(labels endpoint.Labels) bool {
+	for key := range labels {
+		if owner := strings.TrimPrefix(key, txtSharedOwnerLabelPrefix); owner != key && owner != im.ownerID && im.isOwner(owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedLabels returns the labels of the ownership record of a shared record with the given contributions.
+// The owner label is kept as long as its owner contributes, so that the instances which don't share
+// the records leave the record alone.
+func (im *TXTRegistry) sharedLabels(labels endpoint.Labels, contributions map[string]endpoint.Targets) endpoint.Labels {
+	shared := endpoint.NewLabels()
+	for k, v := range ownershipLabels(labels) {
+		if !strings.HasPrefix(k, txtSharedOwnerLabelPrefix) {
+			shared[k] = v
+		}
+	}
+	owners := sortedOwners(contributions)
+	for _, owner := range owners {
+		shared[txtSharedOwnerLabelPrefix+owner] = strings.Join(contributions[owner], txtSharedTargetSeparator)
+	}
+	if im.isOwner(shared[endpoint.OwnerLabelKey]) {
+		shared[endpoint.OwnerLabelKey] = im.ownerID
+	}
+	if _, ok := contributions[shared[endpoint.OwnerLabelKey]]; !ok && len(owners) > 0 {
+		shared[endpoint.OwnerLabelKey] = owners[0]
+	}
+	return shared
+}
+
+// mergeTargets returns the sorted union of the given targets.
+func mergeTargets(a, b endpoint.Targets) endpoint.Targets {
+	seen := map[string]bool{}
+	merged := endpoint.Targets{}
+	for _, targets := range []endpoint.Targets{a, b} {
+		for _, target := range targets {
+			if !seen[target] {
+				seen[target] = true
+				merged = append(merged, target)
+			}
+		}
+	}
+	sort.Sort(merged)
+	return merged
+}
+
+func sortedOwners(contributions map[string]endpoint.Targets) []string {
+	owners := make([]string, 0, len(contributions))
+	for owner := range contributions {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	return owners
+}
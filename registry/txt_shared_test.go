@@ -0,0 +1,276 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// multiTargetProvider keeps records with several targets, and rejects the changes which don't match its records.
+type multiTargetProvider struct {
+	provider.BaseProvider
+	records map[string]*endpoint.Endpoint
+}
+
+func newMultiTargetProvider() *multiTargetProvider {
+	return &multiTargetProvider{records: map[string]*endpoint.Endpoint{}}
+}
+
+func (p *multiTargetProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	keys := make([]string, 0, len(p.records))
+	for key := range p.records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	records := []*endpoint.Endpoint{}
+	for _, key := range keys {
+		r := p.records[key]
+		records = append(records, endpoint.NewEndpointWithTTL(r.DNSName, r.RecordType, r.RecordTTL, r.Targets...).WithSetIdentifier(r.SetIdentifier))
+	}
+	return records, nil
+}
+
+func (p *multiTargetProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	for _, r := range changes.Create {
+		if _, ok := p.records[versionedOwnershipKey(r)]; ok {
+			return fmt.Errorf("%s %s already exists", r.RecordType, r.DNSName)
+		}
+	}
+	for _, records := range [][]*endpoint.Endpoint{changes.UpdateOld, changes.Delete} {
+		for _, r := range records {
+			if existing, ok := p.records[versionedOwnershipKey(r)]; !ok || !existing.Targets.Same(r.Targets) {
+				return fmt.Errorf("%s %s %v not found", r.RecordType, r.DNSName, r.Targets)
+			}
+		}
+	}
+	for _, r := range changes.Delete {
+		delete(p.records, versionedOwnershipKey(r))
+	}
+	for _, records := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateNew} {
+		for _, r := range records {
+			p.records[versionedOwnershipKey(r)] = r
+		}
+	}
+	return nil
+}
+
+// target returns the targets of the record with the given name and type, or nil if there's none.
+func (p *multiTargetProvider) targets(dnsName, recordType string) endpoint.Targets {
+	if r, ok := p.records[dnsName+"::"+recordType+"::"]; ok {
+		return r.Targets
+	}
+	return nil
+}
+
+// syncShared synchronizes the desired records of an owner like the controller does.
+func syncShared(t *testing.T, r *TXTRegistry, desired ...*endpoint.Endpoint) {
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	changes := (&plan.Plan{
+		Policies: []plan.Policy{&plan.SyncPolicy{}},
+		Current:  records,
+		Desired:  desired,
+		OwnerID:  r.ownerID,
+	}).Calculate().Changes
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
+	require.NoError(t, r.ApplyChanges(ctx, changes))
+}
+
+func TestTXTRegistrySharedRecords(t *testing.T) {
+	p := newMultiTargetProvider()
+	r1, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner-1", SharedRecords: true})
+	require.NoError(t, err)
+	r2, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner-2", SharedRecords: true})
+	require.NoError(t, err)
+
+	// the first owner creates the record
+	syncShared(t, r1, endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.1"))
+	assert.Equal(t, endpoint.Targets{"1.1.1.1"}, p.targets("foo.test-zone.example.org", endpoint.RecordTypeA))
+	assert.Equal(t, endpoint.Targets{"\"heritage=external-dns,external-dns/owner=owner-1,external-dns/shared-owner/owner-1=1.1.1.1,external-dns/txt-format=2\""},
+		p.targets("a-foo.test-zone.example.org", endpoint.RecordTypeTXT))
+
+	// the second owner joins the record with its own targets
+	syncShared(t, r2, endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "2.2.2.2", "2.2.2.3"))
+	assert.Equal(t, endpoint.Targets{"1.1.1.1", "2.2.2.2", "2.2.2.3"}, p.targets("foo.test-zone.example.org", endpoint.RecordTypeA))
+	assert.Equal(t, endpoint.Targets{"\"heritage=external-dns,external-dns/owner=owner-1,external-dns/shared-owner/owner-1=1.1.1.1,external-dns/shared-owner/owner-2=2.2.2.2;2.2.2.3,external-dns/txt-format=2\""},
+		p.targets("a-foo.test-zone.example.org", endpoint.RecordTypeTXT))
+
+	// each owner only sees its own targets
+	for owner, targets := range map[*TXTRegistry]endpoint.Targets{r1: {"1.1.1.1"}, r2: {"2.2.2.2", "2.2.2.3"}} {
+		records, err := owner.Records(context.Background())
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, targets, records[0].Targets)
+		assert.Equal(t, endpoint.Labels{endpoint.OwnerLabelKey: owner.ownerID}, records[0].Labels)
+	}
+
+	// nothing changes when the owners synchronize again
+	for _, sync := range []struct {
+		r       *TXTRegistry
+		targets []string
+	}{{r1, []string{"1.1.1.1"}}, {r2, []string{"2.2.2.3", "2.2.2.2"}}} {
+		records, err := sync.r.Records(context.Background())
+		require.NoError(t, err)
+		changes := (&plan.Plan{
+			Policies: []plan.Policy{&plan.SyncPolicy{}},
+			Current:  records,
+			Desired:  []*endpoint.Endpoint{endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, sync.targets...)},
+			OwnerID:  sync.r.ownerID,
+		}).Calculate().Changes
+		assert.Empty(t, changes.Create)
+		assert.Empty(t, changes.UpdateNew)
+		assert.Empty(t, changes.Delete)
+	}
+
+	// an owner replaces its targets only
+	syncShared(t, r1, endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.3"))
+	assert.Equal(t, endpoint.Targets{"1.1.1.3", "2.2.2.2", "2.2.2.3"}, p.targets("foo.test-zone.example.org", endpoint.RecordTypeA))
+
+	// the record is kept as long as an owner is left, which takes over the owner label
+	syncShared(t, r1)
+	assert.Equal(t, endpoint.Targets{"2.2.2.2", "2.2.2.3"}, p.targets("foo.test-zone.example.org", endpoint.RecordTypeA))
+	assert.Equal(t, endpoint.Targets{"\"heritage=external-dns,external-dns/owner=owner-2,external-dns/shared-owner/owner-2=2.2.2.2;2.2.2.3,external-dns/txt-format=2\""},
+		p.targets("a-foo.test-zone.example.org", endpoint.RecordTypeTXT))
+
+	// the record is deleted once the last owner leaves
+	syncShared(t, r2)
+	assert.Empty(t, p.records)
+}
+
+func TestTXTRegistrySharedRecordsProperties(t *testing.T) {
+	p := newMultiTargetProvider()
+	r1, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner-1", SharedRecords: true})
+	require.NoError(t, err)
+	r2, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner-2", SharedRecords: true})
+	require.NoError(t, err)
+	ttl := func() endpoint.TTL {
+		return p.records["foo.test-zone.example.org::A::"].RecordTTL
+	}
+
+	// the TTL is the one of the owner in the owner label, whichever owner synchronizes last
+	syncShared(t, r1, endpoint.NewEndpointWithTTL("foo.test-zone.example.org", endpoint.RecordTypeA, 300, "1.1.1.1"))
+	syncShared(t, r2, endpoint.NewEndpointWithTTL("foo.test-zone.example.org", endpoint.RecordTypeA, 600, "2.2.2.2"))
+	assert.Equal(t, endpoint.Targets{"1.1.1.1", "2.2.2.2"}, p.targets("foo.test-zone.example.org", endpoint.RecordTypeA))
+	assert.Equal(t, endpoint.TTL(300), ttl())
+	syncShared(t, r2, endpoint.NewEndpointWithTTL("foo.test-zone.example.org", endpoint.RecordTypeA, 600, "2.2.2.2"))
+	assert.Equal(t, endpoint.TTL(300), ttl())
+	syncShared(t, r1, endpoint.NewEndpointWithTTL("foo.test-zone.example.org", endpoint.RecordTypeA, 60, "1.1.1.1"))
+	assert.Equal(t, endpoint.TTL(60), ttl())
+
+	// the other owner changing its targets keeps the TTL
+	syncShared(t, r2, endpoint.NewEndpointWithTTL("foo.test-zone.example.org", endpoint.RecordTypeA, 600, "2.2.2.3"))
+	assert.Equal(t, endpoint.Targets{"1.1.1.1", "2.2.2.3"}, p.targets("foo.test-zone.example.org", endpoint.RecordTypeA))
+	assert.Equal(t, endpoint.TTL(60), ttl())
+
+	// the next owner takes over the TTL once the owner leaves
+	syncShared(t, r1)
+	syncShared(t, r2, endpoint.NewEndpointWithTTL("foo.test-zone.example.org", endpoint.RecordTypeA, 600, "2.2.2.3"))
+	assert.Equal(t, endpoint.TTL(600), ttl())
+}
+
+func TestTXTRegistrySharedRecordsLength(t *testing.T) {
+	p := newMultiTargetProvider()
+	r1, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner-1", SharedRecords: true})
+	require.NoError(t, err)
+	r2, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner-2", SharedRecords: true})
+	require.NoError(t, err)
+
+	syncShared(t, r1, endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.1"))
+	txt := p.targets("a-foo.test-zone.example.org", endpoint.RecordTypeTXT)
+
+	// the targets which don't fit in the ownership record aren't shared
+	targets := []string{}
+	for i := 0; i < 32; i++ {
+		targets = append(targets, fmt.Sprintf("2.2.2.%d", i))
+	}
+	syncShared(t, r2, endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, targets...))
+	assert.Equal(t, endpoint.Targets{"1.1.1.1"}, p.targets("foo.test-zone.example.org", endpoint.RecordTypeA))
+	assert.Equal(t, txt, p.targets("a-foo.test-zone.example.org", endpoint.RecordTypeTXT))
+
+	syncShared(t, r2, endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, targets[:4]...))
+	assert.Equal(t, endpoint.Targets{"1.1.1.1", "2.2.2.0", "2.2.2.1", "2.2.2.2", "2.2.2.3"}, p.targets("foo.test-zone.example.org", endpoint.RecordTypeA))
+}
+
+func TestTXTRegistrySharedRecordsConversion(t *testing.T) {
+	p := newMultiTargetProvider()
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.1"),
+			endpoint.NewEndpoint("a-foo.test-zone.example.org", endpoint.RecordTypeTXT, "\"heritage=external-dns,external-dns/owner=owner-0,external-dns/txt-format=2\""),
+			endpoint.NewEndpoint("bar.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.2"),
+			endpoint.NewEndpoint("a-bar.test-zone.example.org", endpoint.RecordTypeTXT, "\"heritage=external-dns,external-dns/owner=owner-3,external-dns/txt-format=2\""),
+			endpoint.NewEndpoint("baz.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.3"),
+			endpoint.NewEndpoint("a-baz.test-zone.example.org", endpoint.RecordTypeTXT, "\"heritage=external-dns,external-dns/owner=owner-0,external-dns/shared-owner/owner-0=1.1.1.3,external-dns/shared-owner/owner-3=3.3.3.3,external-dns/txt-format=2\""),
+		},
+	}))
+	r, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner-1", PreviousOwnerIDs: []string{"owner-0"}, SharedRecords: true})
+	require.NoError(t, err)
+
+	// the records of the previous owner are shared once they're updated, a record which isn't shared isn't joined,
+	// the contributions of the previous owner are adopted
+	syncShared(t, r,
+		endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.4"),
+		endpoint.NewEndpoint("bar.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.5"),
+		endpoint.NewEndpoint("baz.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.3"),
+	)
+	assert.Equal(t, endpoint.Targets{"1.1.1.4"}, p.targets("foo.test-zone.example.org", endpoint.RecordTypeA))
+	assert.Equal(t, endpoint.Targets{"\"heritage=external-dns,external-dns/owner=owner-1,external-dns/shared-owner/owner-1=1.1.1.4,external-dns/txt-format=2\""},
+		p.targets("a-foo.test-zone.example.org", endpoint.RecordTypeTXT))
+	assert.Equal(t, endpoint.Targets{"1.1.1.2"}, p.targets("bar.test-zone.example.org", endpoint.RecordTypeA))
+	assert.Equal(t, endpoint.Targets{"1.1.1.3"}, p.targets("baz.test-zone.example.org", endpoint.RecordTypeA))
+	assert.Equal(t, endpoint.Targets{"\"heritage=external-dns,external-dns/owner=owner-1,external-dns/shared-owner/owner-1=1.1.1.3,external-dns/shared-owner/owner-3=3.3.3.3,external-dns/txt-format=2\""},
+		p.targets("a-baz.test-zone.example.org", endpoint.RecordTypeTXT))
+
+	// the missing targets of the other owners are restored along with a change
+	syncShared(t, r,
+		endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.4"),
+		endpoint.NewEndpoint("bar.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.5"),
+		endpoint.NewEndpoint("baz.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.6"),
+	)
+	assert.Equal(t, endpoint.Targets{"1.1.1.6", "3.3.3.3"}, p.targets("baz.test-zone.example.org", endpoint.RecordTypeA))
+}
+
+func TestTXTRegistrySharedRecordsCheck(t *testing.T) {
+	p := newMultiTargetProvider()
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.test-zone.example.org", endpoint.RecordTypeA, "1.1.1.1", "2.2.2.2"),
+			endpoint.NewEndpoint("a-foo.test-zone.example.org", endpoint.RecordTypeTXT, "\"heritage=external-dns,external-dns/owner=owner-2,external-dns/shared-owner/owner-1=1.1.1.1,external-dns/shared-owner/owner-2=2.2.2.2,external-dns/txt-format=2\""),
+		},
+	}))
+
+	for _, tc := range []struct {
+		shared bool
+		status RecordStatus
+	}{{true, RecordOwned}, {false, RecordForeign}} {
+		r, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner-1", SharedRecords: tc.shared})
+		require.NoError(t, err)
+		report, err := Check(context.Background(), r, "owner-1")
+		require.NoError(t, err)
+		assert.Equal(t, map[RecordStatus]int{tc.status: 2}, report.Summary)
+	}
+}
@@ -18,10 +18,10 @@ package registry
 
 import (
 	"context"
-	"reflect"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -40,24 +40,28 @@ func TestTXTRegistry(t *testing.T) {
 	t.Run("TestNewTXTRegistry", testTXTRegistryNew)
 	t.Run("TestRecords", testTXTRegistryRecords)
 	t.Run("TestApplyChanges", testTXTRegistryApplyChanges)
+	t.Run("TestOrphans", testTXTRegistryOrphans)
+	t.Run("TestKeyring", testTXTRegistryKeyring)
+	t.Run("TestKeyringAllowUnprotected", testTXTRegistryKeyringAllowUnprotected)
+	t.Run("TestAdoption", testTXTRegistryAdoption)
 }
 
 func testTXTRegistryNew(t *testing.T) {
 	p := inmemory.NewInMemoryProvider()
-	_, err := NewTXTRegistry(p, "txt", "", "", time.Hour)
+	_, err := NewTXTRegistry(p, TXTRegistryConfig{Prefix: "txt", CacheInterval: time.Hour})
 	require.Error(t, err)
 
-	_, err = NewTXTRegistry(p, "", "txt", "", time.Hour)
+	_, err = NewTXTRegistry(p, TXTRegistryConfig{Suffix: "txt", CacheInterval: time.Hour})
 	require.Error(t, err)
 
-	r, err := NewTXTRegistry(p, "txt", "", "owner", time.Hour)
+	r, err := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Prefix: "txt", CacheInterval: time.Hour})
 	require.NoError(t, err)
 	assert.Equal(t, p, r.provider)
 
-	r, err = NewTXTRegistry(p, "", "txt", "owner", time.Hour)
+	r, err = NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Suffix: "txt", CacheInterval: time.Hour})
 	require.NoError(t, err)
 
-	_, err = NewTXTRegistry(p, "txt", "txt", "owner", time.Hour)
+	_, err = NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Prefix: "txt", Suffix: "txt", CacheInterval: time.Hour})
 	require.Error(t, err)
 
 	_, ok := r.mapper.(affixNameMapper)
@@ -65,17 +69,29 @@ func testTXTRegistryNew(t *testing.T) {
 	assert.Equal(t, "owner", r.ownerID)
 	assert.Equal(t, p, r.provider)
 
-	r, err = NewTXTRegistry(p, "", "", "owner", time.Hour)
+	r, err = NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", CacheInterval: time.Hour})
 	require.NoError(t, err)
 
 	_, ok = r.mapper.(affixNameMapper)
 	assert.True(t, ok)
+
+	r, err = NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", NameTemplate: "{{.RecordType}}-{{.Label}}.{{.Zone}}", CacheInterval: time.Hour})
+	require.NoError(t, err)
+	_, ok = r.mapper.(templateNameMapper)
+	assert.True(t, ok)
+
+	_, err = NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Prefix: "txt", NameTemplate: "{{.RecordType}}-{{.Label}}.{{.Zone}}", CacheInterval: time.Hour})
+	require.Error(t, err)
+
+	_, err = NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", NameTemplate: "{{.Label}}.{{.Zone}}", CacheInterval: time.Hour})
+	require.Error(t, err)
 }
 
 func testTXTRegistryRecords(t *testing.T) {
 	t.Run("With prefix", testTXTRegistryRecordsPrefixed)
 	t.Run("With suffix", testTXTRegistryRecordsSuffixed)
 	t.Run("No prefix", testTXTRegistryRecordsNoPrefix)
+	t.Run("Versioned format", testTXTRegistryRecordsVersioned)
 }
 
 func testTXTRegistryRecordsPrefixed(t *testing.T) {
@@ -171,13 +187,13 @@ func testTXTRegistryRecordsPrefixed(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "txt.", "", "owner", time.Hour)
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Prefix: "txt.", CacheInterval: time.Hour})
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
 
 	// Ensure prefix is case-insensitive
-	r, _ = NewTXTRegistry(p, "TxT.", "", "owner", time.Hour)
+	r, _ = NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Prefix: "TxT.", CacheInterval: time.Hour})
 	records, _ = r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpointLabels(records, expectedRecords))
@@ -276,13 +292,13 @@ func testTXTRegistryRecordsSuffixed(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "", "-txt", "owner", time.Hour)
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Suffix: "-txt", CacheInterval: time.Hour})
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
 
 	// Ensure prefix is case-insensitive
-	r, _ = NewTXTRegistry(p, "", "-TxT", "owner", time.Hour)
+	r, _ = NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Suffix: "-TxT", CacheInterval: time.Hour})
 	records, _ = r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpointLabels(records, expectedRecords))
@@ -357,27 +373,76 @@ func testTXTRegistryRecordsNoPrefix(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour)
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", CacheInterval: time.Hour})
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
 }
 
+func testTXTRegistryRecordsVersioned(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	ctx := context.Background()
+	p.CreateZone(testZone)
+	p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "2001:db8::4", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "my-domain.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("cname-bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner-2,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+	})
+	expectedRecords := []*endpoint.Endpoint{
+		{
+			DNSName:    "foo.test-zone.example.org",
+			Targets:    endpoint.Targets{"1.2.3.4"},
+			RecordType: endpoint.RecordTypeA,
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey:    "owner",
+				endpoint.ResourceLabelKey: "ingress/default/my-ingress",
+			},
+		},
+		{
+			// not migrated yet
+			DNSName:    "foo.test-zone.example.org",
+			Targets:    endpoint.Targets{"2001:db8::4"},
+			RecordType: endpoint.RecordTypeAAAA,
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey: "owner",
+			},
+		},
+		{
+			DNSName:    "bar.test-zone.example.org",
+			Targets:    endpoint.Targets{"my-domain.com"},
+			RecordType: endpoint.RecordTypeCNAME,
+			Labels: map[string]string{
+				endpoint.OwnerLabelKey: "owner-2",
+			},
+		},
+	}
+
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", CacheInterval: time.Hour})
+	records, _ := r.Records(ctx)
+
+	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
+	assert.True(t, testutils.SameEndpointLabels(records, expectedRecords))
+}
+
 func testTXTRegistryApplyChanges(t *testing.T) {
 	t.Run("With Prefix", testTXTRegistryApplyChangesWithPrefix)
 	t.Run("With Suffix", testTXTRegistryApplyChangesWithSuffix)
 	t.Run("No prefix", testTXTRegistryApplyChangesNoPrefix)
+	t.Run("Dual stack", testTXTRegistryApplyChangesDualStack)
+	t.Run("Record type change", testTXTRegistryApplyChangesTypeChange)
+	t.Run("Versioned format", testTXTRegistryApplyChangesVersioned)
+	t.Run("Migration", testTXTRegistryMigration)
 }
 
 func testTXTRegistryApplyChangesWithPrefix(t *testing.T) {
 	p := inmemory.NewInMemoryProvider()
 	p.CreateZone(testZone)
-	ctxEndpoints := []*endpoint.Endpoint{}
-	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, ctxEndpoints)
-	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
-		assert.Equal(t, ctxEndpoints, ctx.Value(provider.RecordsContextKey))
-	}
-	p.ApplyChanges(ctx, &plan.Changes{
+	p.ApplyChanges(context.Background(), &plan.Changes{
 		Create: []*endpoint.Endpoint{
 			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
 			newEndpointWithOwner("bar.test-zone.example.org", "my-domain.com", endpoint.RecordTypeCNAME, ""),
@@ -394,12 +459,15 @@ func testTXTRegistryApplyChangesWithPrefix(t *testing.T) {
 			newEndpointWithOwner("txt.multiple.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "txt.", "", "owner", time.Hour)
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Prefix: "txt.", CacheInterval: time.Hour})
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
-			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", "", "", "ingress/default/my-ingress"),
-			newEndpointWithOwnerResource("multiple.test-zone.example.org", "lb3.loadbalancer.com", "", "", "ingress/default/my-ingress").WithSetIdentifier("test-set-3"),
+			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", endpoint.RecordTypeCNAME, "", "ingress/default/my-ingress"),
+			newEndpointWithOwnerResource("multiple.test-zone.example.org", "lb3.loadbalancer.com", endpoint.RecordTypeCNAME, "", "ingress/default/my-ingress").WithSetIdentifier("test-set-3"),
 		},
 		Delete: []*endpoint.Endpoint{
 			newEndpointWithOwner("foobar.test-zone.example.org", "foobar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
@@ -416,10 +484,15 @@ func testTXTRegistryApplyChangesWithPrefix(t *testing.T) {
 	}
 	expected := &plan.Changes{
 		Create: []*endpoint.Endpoint{
-			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", "", "owner", "ingress/default/my-ingress"),
-			newEndpointWithOwner("txt.new-record-1.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress\"", endpoint.RecordTypeTXT, ""),
-			newEndpointWithOwnerResource("multiple.test-zone.example.org", "lb3.loadbalancer.com", "", "owner", "ingress/default/my-ingress").WithSetIdentifier("test-set-3"),
-			newEndpointWithOwner("txt.multiple.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-3"),
+			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress"),
+			newEndpointWithOwner("txt.cname-new-record-1.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwnerResource("multiple.test-zone.example.org", "lb3.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress").WithSetIdentifier("test-set-3"),
+			newEndpointWithOwner("txt.cname-multiple.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-3"),
+			// the updated records get their ownership records in the versioned format
+			newEndpointWithOwner("txt.cname-tar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress-2,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("txt.cname-multiple.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress-2,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
+			// the unchanged records are migrated as well
+			newEndpointWithOwner("txt.cname-bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
 		},
 		Delete: []*endpoint.Endpoint{
 			newEndpointWithOwner("foobar.test-zone.example.org", "foobar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
@@ -429,15 +502,11 @@ func testTXTRegistryApplyChangesWithPrefix(t *testing.T) {
 		},
 		UpdateNew: []*endpoint.Endpoint{
 			newEndpointWithOwnerResource("tar.test-zone.example.org", "new-tar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress-2"),
-			newEndpointWithOwner("txt.tar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress-2\"", endpoint.RecordTypeTXT, ""),
 			newEndpointWithOwnerResource("multiple.test-zone.example.org", "new.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress-2").WithSetIdentifier("test-set-2"),
-			newEndpointWithOwner("txt.multiple.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress-2\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 		UpdateOld: []*endpoint.Endpoint{
 			newEndpointWithOwner("tar.test-zone.example.org", "tar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
-			newEndpointWithOwner("txt.tar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
 			newEndpointWithOwner("multiple.test-zone.example.org", "lb2.loadbalancer.com", endpoint.RecordTypeCNAME, "owner").WithSetIdentifier("test-set-2"),
-			newEndpointWithOwner("txt.multiple.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 	}
 	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
@@ -456,19 +525,14 @@ func testTXTRegistryApplyChangesWithPrefix(t *testing.T) {
 		assert.True(t, testutils.SamePlanChanges(mGot, mExpected))
 		assert.Equal(t, nil, ctx.Value(provider.RecordsContextKey))
 	}
-	err := r.ApplyChanges(ctx, changes)
+	err = r.ApplyChanges(ctx, changes)
 	require.NoError(t, err)
 }
 
 func testTXTRegistryApplyChangesWithSuffix(t *testing.T) {
 	p := inmemory.NewInMemoryProvider()
 	p.CreateZone(testZone)
-	ctxEndpoints := []*endpoint.Endpoint{}
-	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, ctxEndpoints)
-	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
-		assert.Equal(t, ctxEndpoints, ctx.Value(provider.RecordsContextKey))
-	}
-	p.ApplyChanges(ctx, &plan.Changes{
+	p.ApplyChanges(context.Background(), &plan.Changes{
 		Create: []*endpoint.Endpoint{
 			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
 			newEndpointWithOwner("bar.test-zone.example.org", "my-domain.com", endpoint.RecordTypeCNAME, ""),
@@ -485,12 +549,15 @@ func testTXTRegistryApplyChangesWithSuffix(t *testing.T) {
 			newEndpointWithOwner("multiple-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "", "-txt", "owner", time.Hour)
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Suffix: "-txt", CacheInterval: time.Hour})
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
-			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", "", "", "ingress/default/my-ingress"),
-			newEndpointWithOwnerResource("multiple.test-zone.example.org", "lb3.loadbalancer.com", "", "", "ingress/default/my-ingress").WithSetIdentifier("test-set-3"),
+			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", endpoint.RecordTypeCNAME, "", "ingress/default/my-ingress"),
+			newEndpointWithOwnerResource("multiple.test-zone.example.org", "lb3.loadbalancer.com", endpoint.RecordTypeCNAME, "", "ingress/default/my-ingress").WithSetIdentifier("test-set-3"),
 		},
 		Delete: []*endpoint.Endpoint{
 			newEndpointWithOwner("foobar.test-zone.example.org", "foobar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
@@ -507,10 +574,13 @@ func testTXTRegistryApplyChangesWithSuffix(t *testing.T) {
 	}
 	expected := &plan.Changes{
 		Create: []*endpoint.Endpoint{
-			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", "", "owner", "ingress/default/my-ingress"),
-			newEndpointWithOwner("new-record-1-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress\"", endpoint.RecordTypeTXT, ""),
-			newEndpointWithOwnerResource("multiple.test-zone.example.org", "lb3.loadbalancer.com", "", "owner", "ingress/default/my-ingress").WithSetIdentifier("test-set-3"),
-			newEndpointWithOwner("multiple-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-3"),
+			newEndpointWithOwnerResource("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress"),
+			newEndpointWithOwner("cname-new-record-1-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwnerResource("multiple.test-zone.example.org", "lb3.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress").WithSetIdentifier("test-set-3"),
+			newEndpointWithOwner("cname-multiple-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-3"),
+			newEndpointWithOwner("cname-tar-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress-2,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("cname-multiple-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress-2,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
+			newEndpointWithOwner("cname-bar-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
 		},
 		Delete: []*endpoint.Endpoint{
 			newEndpointWithOwner("foobar.test-zone.example.org", "foobar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
@@ -520,15 +590,11 @@ func testTXTRegistryApplyChangesWithSuffix(t *testing.T) {
 		},
 		UpdateNew: []*endpoint.Endpoint{
 			newEndpointWithOwnerResource("tar.test-zone.example.org", "new-tar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress-2"),
-			newEndpointWithOwner("tar-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress-2\"", endpoint.RecordTypeTXT, ""),
 			newEndpointWithOwnerResource("multiple.test-zone.example.org", "new.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/my-ingress-2").WithSetIdentifier("test-set-2"),
-			newEndpointWithOwner("multiple-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/my-ingress-2\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 		UpdateOld: []*endpoint.Endpoint{
 			newEndpointWithOwner("tar.test-zone.example.org", "tar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
-			newEndpointWithOwner("tar-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
 			newEndpointWithOwner("multiple.test-zone.example.org", "lb2.loadbalancer.com", endpoint.RecordTypeCNAME, "owner").WithSetIdentifier("test-set-2"),
-			newEndpointWithOwner("multiple-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 	}
 	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
@@ -547,19 +613,14 @@ func testTXTRegistryApplyChangesWithSuffix(t *testing.T) {
 		assert.True(t, testutils.SamePlanChanges(mGot, mExpected))
 		assert.Equal(t, nil, ctx.Value(provider.RecordsContextKey))
 	}
-	err := r.ApplyChanges(ctx, changes)
+	err = r.ApplyChanges(ctx, changes)
 	require.NoError(t, err)
 }
 
 func testTXTRegistryApplyChangesNoPrefix(t *testing.T) {
 	p := inmemory.NewInMemoryProvider()
 	p.CreateZone(testZone)
-	ctxEndpoints := []*endpoint.Endpoint{}
-	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, ctxEndpoints)
-	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
-		assert.Equal(t, ctxEndpoints, ctx.Value(provider.RecordsContextKey))
-	}
-	p.ApplyChanges(ctx, &plan.Changes{
+	p.ApplyChanges(context.Background(), &plan.Changes{
 		Create: []*endpoint.Endpoint{
 			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
 			newEndpointWithOwner("bar.test-zone.example.org", "my-domain.com", endpoint.RecordTypeCNAME, ""),
@@ -572,7 +633,10 @@ func testTXTRegistryApplyChangesNoPrefix(t *testing.T) {
 			newEndpointWithOwner("foobar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour)
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", CacheInterval: time.Hour})
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
@@ -591,7 +655,9 @@ func testTXTRegistryApplyChangesNoPrefix(t *testing.T) {
 	expected := &plan.Changes{
 		Create: []*endpoint.Endpoint{
 			newEndpointWithOwner("new-record-1.test-zone.example.org", "new-loadbalancer-1.lb.com", endpoint.RecordTypeCNAME, "owner"),
-			newEndpointWithOwner("new-record-1.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("cname-new-record-1.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			// txt.bar is owned through the ownership record at txt.bar, txt.tar doesn't own any record
+			newEndpointWithOwner("cname-txt.bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
 		},
 		Delete: []*endpoint.Endpoint{
 			newEndpointWithOwner("foobar.test-zone.example.org", "foobar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
@@ -616,68 +682,651 @@ func testTXTRegistryApplyChangesNoPrefix(t *testing.T) {
 		assert.True(t, testutils.SamePlanChanges(mGot, mExpected))
 		assert.Equal(t, nil, ctx.Value(provider.RecordsContextKey))
 	}
-	err := r.ApplyChanges(ctx, changes)
+	err = r.ApplyChanges(ctx, changes)
+	require.NoError(t, err)
+}
+
+func testTXTRegistryApplyChangesDualStack(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("ipv4.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("ipv4.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("dual.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("dual.test-zone.example.org", "2001:db8::5", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("dual.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("gone.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("gone.test-zone.example.org", "2001:db8::6", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("gone.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		},
+	})
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner"})
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("ipv4.test-zone.example.org", "2001:db8::4", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("new.test-zone.example.org", "1.2.3.7", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("new.test-zone.example.org", "2001:db8::7", endpoint.RecordTypeAAAA, ""),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("dual.test-zone.example.org", "2001:db8::5", endpoint.RecordTypeAAAA, "owner"),
+			newEndpointWithOwner("gone.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("gone.test-zone.example.org", "2001:db8::6", endpoint.RecordTypeAAAA, "owner"),
+		},
+	}
+	expected := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("ipv4.test-zone.example.org", "2001:db8::4", endpoint.RecordTypeAAAA, "owner"),
+			newEndpointWithOwner("aaaa-ipv4.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("a-ipv4.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("new.test-zone.example.org", "1.2.3.7", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("a-new.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("new.test-zone.example.org", "2001:db8::7", endpoint.RecordTypeAAAA, "owner"),
+			newEndpointWithOwner("aaaa-new.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("a-dual.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("dual.test-zone.example.org", "2001:db8::5", endpoint.RecordTypeAAAA, "owner"),
+			newEndpointWithOwner("gone.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("gone.test-zone.example.org", "2001:db8::6", endpoint.RecordTypeAAAA, "owner"),
+			newEndpointWithOwner("gone.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		},
+	}
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		mExpected := map[string][]*endpoint.Endpoint{
+			"Create":    expected.Create,
+			"UpdateNew": expected.UpdateNew,
+			"UpdateOld": expected.UpdateOld,
+			"Delete":    expected.Delete,
+		}
+		mGot := map[string][]*endpoint.Endpoint{
+			"Create":    got.Create,
+			"UpdateNew": got.UpdateNew,
+			"UpdateOld": got.UpdateOld,
+			"Delete":    got.Delete,
+		}
+		assert.True(t, testutils.SamePlanChanges(mGot, mExpected))
+	}
+	err = r.ApplyChanges(ctx, changes)
+	require.NoError(t, err)
+}
+
+func testTXTRegistryApplyChangesTypeChange(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("switch.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("txt.switch.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		},
+	})
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Prefix: "txt."})
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("switch.test-zone.example.org", "lb.example.com", endpoint.RecordTypeCNAME, ""),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("switch.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+		},
+	}
+	expected := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("switch.test-zone.example.org", "lb.example.com", endpoint.RecordTypeCNAME, "owner"),
+			newEndpointWithOwner("txt.cname-switch.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("switch.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("txt.switch.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		},
+	}
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		mExpected := map[string][]*endpoint.Endpoint{
+			"Create":    expected.Create,
+			"UpdateNew": expected.UpdateNew,
+			"UpdateOld": expected.UpdateOld,
+			"Delete":    expected.Delete,
+		}
+		mGot := map[string][]*endpoint.Endpoint{
+			"Create":    got.Create,
+			"UpdateNew": got.UpdateNew,
+			"UpdateOld": got.UpdateOld,
+			"Delete":    got.Delete,
+		}
+		assert.True(t, testutils.SamePlanChanges(mGot, mExpected))
+	}
+	err = r.ApplyChanges(ctx, changes)
+	require.NoError(t, err)
+}
+
+func testTXTRegistryApplyChangesVersioned(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "2001:db8::4", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("aaaa-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+	})
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner"})
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
+
+	changes := &plan.Changes{
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, "owner", "ingress/default/foo"),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "2001:db8::4", endpoint.RecordTypeAAAA, "owner"),
+		},
+	}
+	expected := &plan.Changes{
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, "owner", "ingress/default/foo"),
+			newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/foo,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "2001:db8::4", endpoint.RecordTypeAAAA, "owner"),
+			newEndpointWithOwner("aaaa-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+	}
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		mExpected := map[string][]*endpoint.Endpoint{
+			"Create":    expected.Create,
+			"UpdateNew": expected.UpdateNew,
+			"UpdateOld": expected.UpdateOld,
+			"Delete":    expected.Delete,
+		}
+		mGot := map[string][]*endpoint.Endpoint{
+			"Create":    got.Create,
+			"UpdateNew": got.UpdateNew,
+			"UpdateOld": got.UpdateOld,
+			"Delete":    got.Delete,
+		}
+		assert.True(t, testutils.SamePlanChanges(mGot, mExpected))
+	}
+	err = r.ApplyChanges(ctx, changes)
 	require.NoError(t, err)
 }
 
-func TestCacheMethods(t *testing.T) {
-	cache := []*endpoint.Endpoint{
-		newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
-		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
-		newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing4.com", "1.2.3.4", "A", "owner"),
+// testTXTRegistryMigration tests that the ownership records in the previous format are replaced
+// over two synchronizations without losing the ownership of any record.
+func testTXTRegistryMigration(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("dual.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("dual.test-zone.example.org", "2001:db8::4", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("txt.dual.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=service/default/dual\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("other.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("txt.other.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=other-owner\"", endpoint.RecordTypeTXT, ""),
+		},
+	})
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Prefix: "txt."})
+
+	sync := func() []*endpoint.Endpoint {
+		records, err := r.Records(context.Background())
+		require.NoError(t, err)
+		for _, record := range records {
+			if record.DNSName == "dual.test-zone.example.org" {
+				assert.Equal(t, "owner", record.Labels[endpoint.OwnerLabelKey])
+				assert.Equal(t, "service/default/dual", record.Labels[endpoint.ResourceLabelKey])
+				assert.NotContains(t, record.Labels, txtFormatLabelKey)
+			}
+		}
+		ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
+		require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{}))
+		txts, err := p.Records(context.Background())
+		require.NoError(t, err)
+		return txts
+	}
+
+	ownershipRecords := func(records []*endpoint.Endpoint) []string {
+		names := []string{}
+		for _, record := range records {
+			if record.RecordType == endpoint.RecordTypeTXT {
+				names = append(names, record.DNSName)
+			}
+		}
+		return names
 	}
-	registry := &TXTRegistry{
-		recordsCache:  cache,
-		cacheInterval: time.Hour,
+
+	// the versioned ownership records are created first
+	assert.ElementsMatch(t, []string{
+		"txt.dual.test-zone.example.org",
+		"txt.a-dual.test-zone.example.org",
+		"txt.aaaa-dual.test-zone.example.org",
+		"txt.other.test-zone.example.org",
+	}, ownershipRecords(sync()))
+
+	// then the previous ownership record is deleted, records of other owners are left alone
+	assert.ElementsMatch(t, []string{
+		"txt.a-dual.test-zone.example.org",
+		"txt.aaaa-dual.test-zone.example.org",
+		"txt.other.test-zone.example.org",
+	}, ownershipRecords(sync()))
+
+	// the migration is complete
+	assert.ElementsMatch(t, []string{
+		"txt.a-dual.test-zone.example.org",
+		"txt.aaaa-dual.test-zone.example.org",
+		"txt.other.test-zone.example.org",
+	}, ownershipRecords(sync()))
+}
+
+func testTXTRegistryOrphans(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			// the owned record was deleted out-of-band
+			newEndpointWithOwner("a-gone.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/gone,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("gone.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+			// the ownership record of another owner is left alone
+			newEndpointWithOwner("a-other.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner-2,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			// the ownership record of a recreated record is replaced
+			newEndpointWithOwner("cname-back.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+	})
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner"})
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, float64(3), testutil.ToFloat64(orphanedOwnershipRecords))
+
+	orphans := map[string]*endpoint.Endpoint{}
+	for _, record := range records {
+		if isOrphaned(record) {
+			assert.Equal(t, endpoint.RecordTypeTXT, record.RecordType)
+			assert.Equal(t, endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.OrphanedLabelKey: "true"}, record.Labels)
+			orphans[record.DNSName] = record
+		}
+	}
+	require.Len(t, orphans, 3)
+	assert.Contains(t, orphans, "a-gone.test-zone.example.org")
+	assert.Contains(t, orphans, "gone.test-zone.example.org")
+	assert.Contains(t, orphans, "cname-back.test-zone.example.org")
+
+	// the plan deletes the orphaned ownership records, except under the upsert-only policy
+	desired := []*endpoint.Endpoint{
+		newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("back.test-zone.example.org", "back.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+	}
+	upsertOnly := (&plan.Plan{Policies: []plan.Policy{&plan.UpsertOnlyPolicy{}}, Current: records, Desired: desired}).Calculate()
+	assert.Empty(t, upsertOnly.Changes.Delete)
+	changes := (&plan.Plan{Policies: []plan.Policy{&plan.SyncPolicy{}}, Current: records, Desired: desired}).Calculate().Changes
+	require.Len(t, changes.Delete, 3)
+
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		mExpected := map[string][]*endpoint.Endpoint{
+			"Create": {
+				newEndpointWithOwner("back.test-zone.example.org", "back.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+			},
+			"UpdateNew": {
+				newEndpointWithOwner("cname-back.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			},
+			"UpdateOld": {
+				orphans["cname-back.test-zone.example.org"],
+			},
+			"Delete": {
+				orphans["a-gone.test-zone.example.org"],
+				orphans["gone.test-zone.example.org"],
+			},
+		}
+		mGot := map[string][]*endpoint.Endpoint{
+			"Create":    got.Create,
+			"UpdateNew": got.UpdateNew,
+			"UpdateOld": got.UpdateOld,
+			"Delete":    got.Delete,
+		}
+		assert.True(t, testutils.SamePlanChanges(mGot, mExpected))
+	}
+	require.NoError(t, r.ApplyChanges(ctx, changes))
+
+	p.OnApplyChanges = nil
+	records, err = r.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, float64(0), testutil.ToFloat64(orphanedOwnershipRecords))
+	for _, record := range records {
+		assert.False(t, isOrphaned(record), record.DNSName)
+	}
+}
+
+func testTXTRegistryKeyring(t *testing.T) {
+	keyring := newTestTXTKeyring(t, true, false, testTXTKey)
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			// forged by someone who can write TXT records
+			newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("a-bar.test-zone.example.org", sealTXTValue(t, keyring, "a-bar.test-zone.example.org", endpoint.Labels{
+				endpoint.OwnerLabelKey:    "owner",
+				endpoint.ResourceLabelKey: "ingress/default/bar",
+				txtFormatLabelKey:         txtFormatVersion,
+			}), endpoint.RecordTypeTXT, ""),
+		},
+	})
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Keyring: keyring})
+
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		newEndpointWithOwnerResource("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, "owner", "ingress/default/bar"),
+	}))
+
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		require.Len(t, got.Create, 2)
+		txt := got.Create[1]
+		assert.Equal(t, "a-new.test-zone.example.org", txt.DNSName)
+		assert.NotContains(t, txt.Targets[0], "owner=owner")
+		labels, protection, err := openTXTValue(keyring, txt.DNSName, txt.Targets[0])
+		require.NoError(t, err)
+		assert.Equal(t, txtCurrent, protection)
+		assert.Equal(t, "owner", labels[endpoint.OwnerLabelKey])
+	}
+	err = r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("new.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, ""),
+		},
+	})
+	require.NoError(t, err)
+
+	// after a rotation, the ownership records protected with the previous key are rewritten
+	rotated := newTestTXTKeyring(t, true, false, testTXTKeyOther, testTXTKey)
+	r, _ = NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Keyring: rotated})
+	records, err = r.Records(context.Background())
+	require.NoError(t, err)
+	ctx = context.WithValue(context.Background(), provider.RecordsContextKey, records)
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		require.Len(t, got.UpdateOld, 2)
+		require.Len(t, got.UpdateNew, 2)
+		for _, e := range got.UpdateNew {
+			_, protection, err := openTXTValue(rotated, e.DNSName, e.Targets[0])
+			require.NoError(t, err)
+			assert.Equal(t, txtCurrent, protection)
+		}
 	}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{}))
 
-	expectedCacheAfterAdd := []*endpoint.Endpoint{
-		newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
-		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
-		newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing4.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"),
+	// nothing is changed when the labels can't be encrypted
+	rotated.random = failingReader{}
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		t.Error("should not apply any change")
 	}
+	err = r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("other.test-zone.example.org", "1.2.3.7", endpoint.RecordTypeA, ""),
+		},
+	})
+	assert.Error(t, err)
+}
 
-	expectedCacheAfterUpdate := []*endpoint.Endpoint{
-		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
-		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
-		newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing4.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"),
-		newEndpointWithOwner("thing.com", "1.2.3.6", "A", "owner2"),
+func testTXTRegistryKeyringAllowUnprotected(t *testing.T) {
+	keyring := newTestTXTKeyring(t, false, true, testTXTKey)
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("a-bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner-2,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+	})
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", Keyring: keyring})
+
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+		newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, "owner-2"),
+	}))
+
+	// the owned ownership record is signed, the one of the other owner is left alone
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{}))
+
+	txts, err := p.Records(context.Background())
+	require.NoError(t, err)
+	values := map[string]string{}
+	for _, txt := range txts {
+		if txt.RecordType == endpoint.RecordTypeTXT {
+			values[txt.DNSName] = txt.Targets[0]
+		}
 	}
+	_, protection, err := openTXTValue(keyring, "a-foo.test-zone.example.org", values["a-foo.test-zone.example.org"])
+	require.NoError(t, err)
+	assert.Equal(t, txtCurrent, protection)
+	assert.Equal(t, "\"heritage=external-dns,external-dns/owner=owner-2,external-dns/txt-format=2\"", values["a-bar.test-zone.example.org"])
 
-	expectedCacheAfterDelete := []*endpoint.Endpoint{
-		newEndpointWithOwner("thing1.com", "1.2.3.6", "A", "owner"),
-		newEndpointWithOwner("thing2.com", "1.2.3.4", "CNAME", "owner"),
-		newEndpointWithOwner("thing3.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing4.com", "1.2.3.4", "A", "owner"),
-		newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"),
+	// nothing left to protect
+	records, err = r.Records(context.Background())
+	require.NoError(t, err)
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.Empty(t, got.UpdateNew)
 	}
-	// test add cache
-	registry.addToCache(newEndpointWithOwner("thing5.com", "1.2.3.5", "A", "owner"))
+	ctx = context.WithValue(context.Background(), provider.RecordsContextKey, records)
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{}))
+}
+
+func testTXTRegistryAdoption(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner-blue,external-dns/resource=ingress/default/foo,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner-blue\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("baz.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("qux.test-zone.example.org", "1.2.3.7", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("a-qux.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner-2,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+		},
+	})
+	r, _ := NewTXTRegistry(p, TXTRegistryConfig{OwnerID: "owner", PreviousOwnerIDs: []string{"owner-blue"}})
+
+	// the records of the previous owner are owned right away
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "ingress/default/foo"),
+		newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, "owner"),
+		newEndpointWithOwner("baz.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("qux.test-zone.example.org", "1.2.3.7", endpoint.RecordTypeA, "owner-2"),
+	}))
+
+	// the unowned record is claimed, the record of another owner can't be claimed
+	changes := (&plan.Plan{
+		Policies: []plan.Policy{&plan.SyncPolicy{}},
+		Current:  records,
+		Desired: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "ingress/default/foo"),
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.5", endpoint.RecordTypeA, ""),
+			newEndpointWithOwnerAndLabels("baz.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, "", endpoint.Labels{endpoint.ClaimLabelKey: "true", endpoint.PriorityLabelKey: "10"}),
+			newEndpointWithOwnerAndLabels("qux.test-zone.example.org", "1.2.3.7", endpoint.RecordTypeA, "", endpoint.Labels{endpoint.ClaimLabelKey: "true"}),
+		},
+		OwnerID: "owner",
+	}).Calculate().Changes
 
-	if !reflect.DeepEqual(expectedCacheAfterAdd, registry.recordsCache) {
-		t.Fatalf("expected endpoints should match endpoints from cache: expected %v, but got %v", expectedCacheAfterAdd, registry.recordsCache)
+	ctx := context.WithValue(context.Background(), provider.RecordsContextKey, records)
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		mExpected := map[string][]*endpoint.Endpoint{
+			"Create": {
+				newEndpointWithOwner("a-baz.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+				// the ownership record in the previous format is migrated
+				newEndpointWithOwner("a-bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			},
+			"UpdateNew": {
+				newEndpointWithOwner("baz.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, "owner"),
+				newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=ingress/default/foo,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			},
+			"UpdateOld": {
+				newEndpointWithOwner("baz.test-zone.example.org", "1.2.3.6", endpoint.RecordTypeA, "owner"),
+				newEndpointWithOwner("a-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner-blue,external-dns/resource=ingress/default/foo,external-dns/txt-format=2\"", endpoint.RecordTypeTXT, ""),
+			},
+			"Delete": {},
+		}
+		mGot := map[string][]*endpoint.Endpoint{
+			"Create":    got.Create,
+			"UpdateNew": got.UpdateNew,
+			"UpdateOld": got.UpdateOld,
+			"Delete":    got.Delete,
+		}
+		assert.True(t, testutils.SamePlanChanges(mGot, mExpected))
 	}
+	require.NoError(t, r.ApplyChanges(ctx, changes))
 
-	// test update cache
-	registry.removeFromCache(newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"))
-	registry.addToCache(newEndpointWithOwner("thing.com", "1.2.3.6", "A", "owner2"))
-	// ensure it was updated
-	if !reflect.DeepEqual(expectedCacheAfterUpdate, registry.recordsCache) {
-		t.Fatalf("expected endpoints should match endpoints from cache: expected %v, but got %v", expectedCacheAfterUpdate, registry.recordsCache)
+	// the claimed and adopted records are owned, the previous ownership record is deleted once migrated
+	p.OnApplyChanges = nil
+	records, err = r.Records(context.Background())
+	require.NoError(t, err)
+	for _, record := range records {
+		if record.DNSName != "qux.test-zone.example.org" {
+			assert.Equal(t, "owner", record.Labels[endpoint.OwnerLabelKey], record.DNSName)
+		}
 	}
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.Empty(t, got.Create)
+		assert.Empty(t, got.UpdateNew)
+		assert.True(t, testutils.SameEndpoints(got.Delete, []*endpoint.Endpoint{
+			newEndpointWithOwner("bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner-blue\"", endpoint.RecordTypeTXT, ""),
+		}))
+	}
+	ctx = context.WithValue(context.Background(), provider.RecordsContextKey, records)
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{}))
+}
 
-	// test deleting a record
-	registry.removeFromCache(newEndpointWithOwner("thing.com", "1.2.3.6", "A", "owner2"))
-	// ensure it was deleted
-	if !reflect.DeepEqual(expectedCacheAfterDelete, registry.recordsCache) {
-		t.Fatalf("expected endpoints should match endpoints from cache: expected %v, but got %v", expectedCacheAfterDelete, registry.recordsCache)
+func TestAffixNameMapperVersioned(t *testing.T) {
+	for _, tc := range []struct {
+		title      string
+		prefix     string
+		suffix     string
+		zones      []string
+		dnsName    string
+		recordType string
+		txtName    string
+	}{
+		{
+			title:      "no affix",
+			dnsName:    "foo.example.org",
+			recordType: endpoint.RecordTypeA,
+			txtName:    "a-foo.example.org",
+		},
+		{
+			title:      "prefix",
+			prefix:     "txt.",
+			dnsName:    "foo.bar.example.org",
+			recordType: endpoint.RecordTypeCNAME,
+			txtName:    "txt.cname-foo.bar.example.org",
+		},
+		{
+			title:      "suffix",
+			suffix:     "-txt",
+			dnsName:    "foo.example.org",
+			recordType: endpoint.RecordTypeAAAA,
+			txtName:    "aaaa-foo-txt.example.org",
+		},
+		{
+			title:      "apex",
+			dnsName:    "example.org",
+			recordType: endpoint.RecordTypeA,
+			txtName:    "a-_apex.example.org",
+		},
+		{
+			title:      "apex with prefix",
+			prefix:     "txt-",
+			dnsName:    "example.co.uk",
+			recordType: endpoint.RecordTypeSRV,
+			txtName:    "txt-srv-_apex.example.co.uk",
+		},
+		{
+			title:      "apex with suffix",
+			suffix:     "-txt",
+			dnsName:    "example.org",
+			recordType: endpoint.RecordTypeTXT,
+			txtName:    "txt-_apex-txt.example.org",
+		},
+		{
+			title:      "apex of a delegated zone",
+			zones:      []string{"example.org", "sub.example.org"},
+			dnsName:    "sub.example.org",
+			recordType: endpoint.RecordTypeA,
+			txtName:    "a-_apex.sub.example.org",
+		},
+		{
+			title:      "name in a delegated zone",
+			zones:      []string{"example.org", ".sub.example.org"},
+			dnsName:    "foo.sub.example.org",
+			recordType: endpoint.RecordTypeA,
+			txtName:    "a-foo.sub.example.org",
+		},
+		{
+			title:      "subdomain which isn't delegated",
+			zones:      []string{"example.org"},
+			dnsName:    "sub.example.org",
+			recordType: endpoint.RecordTypeA,
+			txtName:    "a-sub.example.org",
+		},
+		{
+			title:      "wildcard",
+			dnsName:    "*.example.org",
+			recordType: endpoint.RecordTypeA,
+			txtName:    "a-_wildcard.example.org",
+		},
+		{
+			title:      "wildcard with prefix",
+			prefix:     "txt.",
+			dnsName:    "*.sub.example.org",
+			recordType: endpoint.RecordTypeCNAME,
+			txtName:    "txt.cname-_wildcard.sub.example.org",
+		},
+		{
+			title:      "wildcard with suffix",
+			suffix:     "-txt",
+			dnsName:    "*.example.org",
+			recordType: endpoint.RecordTypeAAAA,
+			txtName:    "aaaa-_wildcard-txt.example.org",
+		},
+		{
+			title:      "name outside of the zones",
+			zones:      []string{"example.org"},
+			dnsName:    "example.com",
+			recordType: endpoint.RecordTypeA,
+			txtName:    "a-_apex.example.com",
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			mapper := newaffixNameMapper(tc.prefix, tc.suffix, tc.zones)
+			assert.Equal(t, tc.txtName, mapper.toVersionedTXTName(tc.dnsName, tc.recordType))
+			dnsName, recordType := mapper.toEndpointNameAndType(tc.txtName)
+			assert.Equal(t, tc.dnsName, dnsName)
+			assert.Equal(t, tc.recordType, recordType)
+		})
 	}
 }
 
@@ -19,24 +19,32 @@ package source
 import (
 	"context"
 	"net/url"
+	"time"
 
 	cfclient "github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
 type cloudfoundrySource struct {
-	client *cfclient.Client
+	client       *cfclient.Client
+	pollInterval time.Duration
 }
 
 // NewCloudFoundrySource creates a new cloudfoundrySource with the given config
-func NewCloudFoundrySource(cfClient *cfclient.Client) (Source, error) {
+func NewCloudFoundrySource(cfClient *cfclient.Client, pollInterval time.Duration) (Source, error) {
 	return &cloudfoundrySource{
-		client: cfClient,
+		client:       cfClient,
+		pollInterval: pollInterval,
 	}, nil
 }
 
+// AddEventHandler polls the applications for changes, because the Cloud Foundry API can't be watched.
 func (rs *cloudfoundrySource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for cloudfoundry")
+
+	pollForChanges(ctx, rs, rs.pollInterval, handler)
 }
 
 // Endpoints returns endpoint objects
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -28,8 +29,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 
 	"sigs.k8s.io/external-dns/endpoint"
@@ -40,9 +43,11 @@ import (
 type crdSource struct {
 	crdClient        rest.Interface
 	namespace        string
+	namespaceFilter  *namespaceFilter
 	crdResource      string
 	codec            runtime.ParameterCodec
 	annotationFilter string
+	labelFilter      string
 }
 
 func addKnownTypes(scheme *runtime.Scheme, groupVersion schema.GroupVersion) error {
@@ -102,32 +107,95 @@ func NewCRDClientForAPIVersionKind(client kubernetes.Interface, kubeConfig, apiS
 }
 
 // NewCRDSource creates a new crdSource with the given config.
-func NewCRDSource(crdClient rest.Interface, namespace, kind string, annotationFilter string, scheme *runtime.Scheme) (Source, error) {
+func NewCRDSource(crdClient rest.Interface, kubeClient kubernetes.Interface, namespaces []string, namespaceSelector, kind string, annotationFilter string, scheme *runtime.Scheme, labelFilter string) (Source, error) {
+	if _, err := labels.Parse(labelFilter); err != nil {
+		return nil, err
+	}
+
+	namespaceFilter, err := newNamespaceFilter(kubeClient, namespaces, namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	namespace := namespaceFilter.informerNamespace()
+
 	return &crdSource{
 		crdResource:      strings.ToLower(kind) + "s",
 		namespace:        namespace,
+		namespaceFilter:  namespaceFilter,
 		annotationFilter: annotationFilter,
+		labelFilter:      labelFilter,
 		crdClient:        crdClient,
 		codec:            runtime.NewParameterCodec(scheme),
 	}, nil
 }
 
 func (cs *crdSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for CRD")
+
+	cs.namespaceFilter.AddEventHandler(handler)
+
+	// The endpoints are still listed from the API server, the informer only watches for their changes.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informer := cache.NewSharedInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = cs.labelFilter
+				return cs.List(ctx, &options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = cs.labelFilter
+				return cs.watch(ctx, &options)
+			},
+		},
+		&endpoint.DNSEndpoint{},
+		0,
+	)
+	informer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				handler()
+			},
+			UpdateFunc: func(old interface{}, new interface{}) {
+				// the status updated by Endpoints doesn't change the endpoints
+				if dnsEndpointChanged(old.(*endpoint.DNSEndpoint), new.(*endpoint.DNSEndpoint)) {
+					handler()
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				handler()
+			},
+		},
+	)
+	go informer.Run(ctx.Done())
+}
+
+// dnsEndpointChanged returns whether the DNSEndpoint changed in a way which may change its endpoints:
+// its spec, or the labels and annotations used by the filters.
+func dnsEndpointChanged(old, new *endpoint.DNSEndpoint) bool {
+	return !reflect.DeepEqual(old.Spec, new.Spec) ||
+		!labels.Equals(old.Labels, new.Labels) ||
+		!labels.Equals(old.Annotations, new.Annotations)
 }
 
 // Endpoints returns endpoint objects.
 func (cs *crdSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
 	endpoints := []*endpoint.Endpoint{}
 
-	result, err := cs.List(ctx, &metav1.ListOptions{})
+	result, err := cs.List(ctx, &metav1.ListOptions{LabelSelector: cs.labelFilter})
 	if err != nil {
 		return nil, err
 	}
-
-	result, err = cs.filterByAnnotations(result)
+	matchesAnnotations, err := annotationFilterFunc(cs.annotationFilter)
 	if err != nil {
 		return nil, err
 	}
+	filtered := result.Items[:0]
+	for _, dnsEndpoint := range result.Items {
+		if cs.namespaceFilter.matches(dnsEndpoint.Namespace) && matchesAnnotations(dnsEndpoint.Annotations) {
+			filtered = append(filtered, dnsEndpoint)
+		}
+	}
+	result.Items = filtered
 
 	for _, dnsEndpoint := range result.Items {
 		// Make sure that all endpoints have targets for A or CNAME type
@@ -179,6 +247,8 @@ func (cs *crdSource) setResourceLabel(crd *endpoint.DNSEndpoint, endpoints []*en
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("crd/%s/%s", crd.ObjectMeta.Namespace, crd.ObjectMeta.Name)
 	}
+	setPriorityLabel(crd.ObjectMeta.Annotations, endpoints)
+	setClaimLabel(crd.ObjectMeta.Annotations, endpoints)
 }
 
 func (cs *crdSource) List(ctx context.Context, opts *metav1.ListOptions) (result *endpoint.DNSEndpointList, err error) {
@@ -192,6 +262,15 @@ func (cs *crdSource) List(ctx context.Context, opts *metav1.ListOptions) (result
 	return
 }
 
+func (cs *crdSource) watch(ctx context.Context, opts *metav1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return cs.crdClient.Get().
+		Namespace(cs.namespace).
+		Resource(cs.crdResource).
+		VersionedParams(opts, cs.codec).
+		Watch(ctx)
+}
+
 func (cs *crdSource) UpdateStatus(ctx context.Context, dnsEndpoint *endpoint.DNSEndpoint) (result *endpoint.DNSEndpoint, err error) {
 	result = &endpoint.DNSEndpoint{}
 	err = cs.crdClient.Put().
@@ -204,34 +283,3 @@ func (cs *crdSource) UpdateStatus(ctx context.Context, dnsEndpoint *endpoint.DNS
 		Into(result)
 	return
 }
-
-// filterByAnnotations filters a list of dnsendpoints by a given annotation selector.
-func (cs *crdSource) filterByAnnotations(dnsendpoints *endpoint.DNSEndpointList) (*endpoint.DNSEndpointList, error) {
-	labelSelector, err := metav1.ParseToLabelSelector(cs.annotationFilter)
-	if err != nil {
-		return nil, err
-	}
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
-	if err != nil {
-		return nil, err
-	}
-
-	// empty filter returns original list
-	if selector.Empty() {
-		return dnsendpoints, nil
-	}
-
-	filteredList := endpoint.DNSEndpointList{}
-
-	for _, dnsendpoint := range dnsendpoints.Items {
-		// convert the dnsendpoint' annotations to an equivalent label selector
-		annotations := labels.Set(dnsendpoint.Annotations)
-
-		// include dnsendpoint if its annotations match the selector
-		if selector.Matches(annotations) {
-			filteredList.Items = append(filteredList.Items, dnsendpoint)
-		}
-	}
-
-	return &filteredList, nil
-}
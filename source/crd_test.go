@@ -27,12 +27,14 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	fakeKube "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/rest/fake"
 
@@ -118,6 +120,7 @@ func TestCRDSource(t *testing.T) {
 	suite.Run(t, new(CRDSuite))
 	t.Run("Interface", testCRDSourceImplementsSource)
 	t.Run("Endpoints", testCRDSourceEndpoints)
+	t.Run("EventHandler", testCRDSourceEventHandler)
 }
 
 // testCRDSourceImplementsSource tests that crdSource is a valid Source.
@@ -317,7 +320,7 @@ func testCRDSourceEndpoints(t *testing.T) {
 			scheme := runtime.NewScheme()
 			addKnownTypes(scheme, groupVersion)
 
-			cs, _ := NewCRDSource(restClient, ti.namespace, ti.kind, ti.annotationFilter, scheme)
+			cs, _ := NewCRDSource(restClient, fakeKube.NewSimpleClientset(), []string{ti.namespace}, "", ti.kind, ti.annotationFilter, scheme, "")
 
 			receivedEndpoints, err := cs.Endpoints(context.Background())
 			if ti.expectError {
@@ -340,6 +343,96 @@ func testCRDSourceEndpoints(t *testing.T) {
 	}
 }
 
+// testCRDSourceEventHandler tests that the changes of the DNSEndpoints watched by the CRD source
+// trigger the event handler.
+func testCRDSourceEventHandler(t *testing.T) {
+	apiVersion, kind := "test.k8s.io/v1alpha1", "DNSEndpoint"
+	groupVersion, _ := schema.ParseGroupVersion(apiVersion)
+	scheme := runtime.NewScheme()
+	addKnownTypes(scheme, groupVersion)
+
+	codecFactory := serializer.WithoutConversionCodecFactory{
+		CodecFactory: serializer.NewCodecFactory(scheme),
+	}
+
+	// the informer lists no DNSEndpoints, then receives the events written to the watch stream
+	events, stream := io.Pipe()
+	defer stream.Close()
+	watched := false
+	client := &fake.RESTClient{
+		GroupVersion:         groupVersion,
+		VersionedAPIPath:     "/apis/" + apiVersion,
+		NegotiatedSerializer: codecFactory,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			codec := codecFactory.LegacyCodec(groupVersion)
+			switch p, m, w := req.URL.Path, req.Method, req.URL.Query().Get("watch"); {
+			case p == "/apis/"+apiVersion+"/dnsendpoints" && m == http.MethodGet && w == "true" && !watched:
+				watched = true
+				return &http.Response{StatusCode: http.StatusOK, Header: defaultHeader(), Body: events}, nil
+			case p == "/apis/"+apiVersion+"/dnsendpoints" && m == http.MethodGet && w == "":
+				list := &endpoint.DNSEndpointList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}
+				return &http.Response{StatusCode: http.StatusOK, Header: defaultHeader(), Body: objBody(codec, list)}, nil
+			default:
+				return nil, fmt.Errorf("unexpected request: %#v\n%#v", req.URL, req)
+			}
+		}),
+	}
+
+	cs, err := NewCRDSource(client, fakeKube.NewSimpleClientset(), nil, "", kind, "", scheme, "")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handled := make(chan struct{}, 10)
+	cs.AddEventHandler(ctx, func() { handled <- struct{}{} })
+
+	dnsEndpoint := &endpoint.DNSEndpoint{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiVersion,
+			Kind:       kind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			Namespace:       "default",
+			Generation:      1,
+			ResourceVersion: "2",
+		},
+		Spec: endpoint.DNSEndpointSpec{
+			Endpoints: []*endpoint.Endpoint{endpoint.NewEndpoint("abc.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+		},
+	}
+	send := func(eventType string) {
+		object, err := json.Marshal(dnsEndpoint)
+		require.NoError(t, err)
+		event, err := json.Marshal(&metav1.WatchEvent{Type: eventType, Object: runtime.RawExtension{Raw: object}})
+		require.NoError(t, err)
+		_, err = stream.Write(event)
+		require.NoError(t, err)
+	}
+
+	// an added DNSEndpoint triggers the handler
+	send("ADDED")
+	waitForHandler(t, handled)
+
+	// a status update, e.g. by Endpoints, doesn't
+	dnsEndpoint.ResourceVersion = "3"
+	dnsEndpoint.Status.ObservedGeneration = 1
+	send("MODIFIED")
+
+	// a change of the endpoints does
+	dnsEndpoint.ResourceVersion = "4"
+	dnsEndpoint.Generation = 2
+	dnsEndpoint.Spec.Endpoints[0].Targets = endpoint.Targets{"2.3.4.5"}
+	send("MODIFIED")
+	waitForHandler(t, handled)
+
+	// a deletion does, and is the only change handled since the change of the endpoints
+	dnsEndpoint.ResourceVersion = "5"
+	send("DELETED")
+	waitForHandler(t, handled)
+	assert.Empty(t, handled)
+}
+
 func validateCRDResource(t *testing.T, src Source, expectError bool) {
 	cs := src.(*crdSource)
 	result, err := cs.List(context.Background(), &metav1.ListOptions{})
@@ -46,10 +46,12 @@ type gatewaySource struct {
 	kubeClient               kubernetes.Interface
 	istioClient              istioclient.Interface
 	namespace                string
+	namespaceFilter          *namespaceFilter
 	annotationFilter         string
 	fqdnTemplate             *template.Template
 	combineFQDNAnnotation    bool
 	ignoreHostnameAnnotation bool
+	labelFilter              string
 	serviceInformer          coreinformers.ServiceInformer
 }
 
@@ -57,11 +59,13 @@ type gatewaySource struct {
 func NewIstioGatewaySource(
 	kubeClient kubernetes.Interface,
 	istioClient istioclient.Interface,
-	namespace string,
+	namespaces []string,
+	namespaceSelector string,
 	annotationFilter string,
 	fqdnTemplate string,
 	combineFQDNAnnotation bool,
 	ignoreHostnameAnnotation bool,
+	labelFilter string,
 ) (Source, error) {
 	var (
 		tmpl *template.Template
@@ -77,6 +81,16 @@ func NewIstioGatewaySource(
 		}
 	}
 
+	if _, err := labels.Parse(labelFilter); err != nil {
+		return nil, err
+	}
+
+	namespaceFilter, err := newNamespaceFilter(kubeClient, namespaces, namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	namespace := namespaceFilter.informerNamespace()
+
 	// Use shared informers to listen for add/update/delete of services/pods/nodes in the specified namespace.
 	// Set resync period to 0, to prevent processing when nothing has changed
 	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
@@ -106,10 +120,12 @@ func NewIstioGatewaySource(
 		kubeClient:               kubeClient,
 		istioClient:              istioClient,
 		namespace:                namespace,
+		namespaceFilter:          namespaceFilter,
 		annotationFilter:         annotationFilter,
 		fqdnTemplate:             tmpl,
 		combineFQDNAnnotation:    combineFQDNAnnotation,
 		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		labelFilter:              labelFilter,
 		serviceInformer:          serviceInformer,
 	}, nil
 }
@@ -117,16 +133,23 @@ func NewIstioGatewaySource(
 // Endpoints returns endpoint objects for each host-target combination that should be processed.
 // Retrieves all gateway resources in the source's namespace(s).
 func (sc *gatewaySource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
-	gwList, err := sc.istioClient.NetworkingV1alpha3().Gateways(sc.namespace).List(ctx, metav1.ListOptions{})
+	gwList, err := sc.istioClient.NetworkingV1alpha3().Gateways(sc.namespace).List(ctx, metav1.ListOptions{LabelSelector: sc.labelFilter})
 	if err != nil {
 		return nil, err
 	}
 
 	gateways := gwList.Items
-	gateways, err = sc.filterByAnnotations(gateways)
+	matchesAnnotations, err := annotationFilterFunc(sc.annotationFilter)
 	if err != nil {
 		return nil, err
 	}
+	filtered := gateways[:0]
+	for _, gateway := range gateways {
+		if sc.namespaceFilter.matches(gateway.Namespace) && matchesAnnotations(gateway.Annotations) {
+			filtered = append(filtered, gateway)
+		}
+	}
+	gateways = filtered
 
 	var endpoints []*endpoint.Endpoint
 
@@ -183,43 +206,15 @@ func (sc *gatewaySource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, e
 // TODO(tariq1890): Implement this once we have evaluated and tested GatewayInformers
 // AddEventHandler adds an event handler that should be triggered if the watched Istio Gateway changes.
 func (sc *gatewaySource) AddEventHandler(ctx context.Context, handler func()) {
-}
-
-// filterByAnnotations filters a list of configs by a given annotation selector.
-func (sc *gatewaySource) filterByAnnotations(gateways []networkingv1alpha3.Gateway) ([]networkingv1alpha3.Gateway, error) {
-	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
-	if err != nil {
-		return nil, err
-	}
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
-	if err != nil {
-		return nil, err
-	}
-
-	// empty filter returns original list
-	if selector.Empty() {
-		return gateways, nil
-	}
-
-	var filteredList []networkingv1alpha3.Gateway
-
-	for _, gw := range gateways {
-		// convert the annotations to an equivalent label selector
-		annotations := labels.Set(gw.Annotations)
-
-		// include if the annotations match the selector
-		if selector.Matches(annotations) {
-			filteredList = append(filteredList, gw)
-		}
-	}
-
-	return filteredList, nil
+	sc.namespaceFilter.AddEventHandler(handler)
 }
 
 func (sc *gatewaySource) setResourceLabel(gateway networkingv1alpha3.Gateway, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("gateway/%s/%s", gateway.Namespace, gateway.Name)
 	}
+	setPriorityLabel(gateway.Annotations, endpoints)
+	setClaimLabel(gateway.Annotations, endpoints)
 }
 
 func (sc *gatewaySource) targetsFromGateway(gateway networkingv1alpha3.Gateway) (targets endpoint.Targets, err error) {
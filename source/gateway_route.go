@@ -0,0 +1,636 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	kubeinformers "k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// GatewayGroup is the API group of the Kubernetes Gateway API
+	GatewayGroup = "gateway.networking.k8s.io"
+	// GatewayVersion is the version of the Kubernetes Gateway API used by the sources
+	GatewayVersion = "v1alpha2"
+
+	// GatewayHTTPRouteKind is the kind of the HTTP routes of the Gateway API
+	GatewayHTTPRouteKind = "HTTPRoute"
+	// GatewayTLSRouteKind is the kind of the TLS routes of the Gateway API
+	GatewayTLSRouteKind = "TLSRoute"
+	// GatewayTCPRouteKind is the kind of the TCP routes of the Gateway API
+	GatewayTCPRouteKind = "TCPRoute"
+
+	gatewayKind = "Gateway"
+)
+
+var (
+	// GatewayGVR is the resource of the gateways of the Gateway API
+	GatewayGVR = schema.GroupVersionResource{Group: GatewayGroup, Version: GatewayVersion, Resource: "gateways"}
+	// GatewayHTTPRouteGVR is the resource of the HTTP routes of the Gateway API
+	GatewayHTTPRouteGVR = schema.GroupVersionResource{Group: GatewayGroup, Version: GatewayVersion, Resource: "httproutes"}
+	// GatewayTLSRouteGVR is the resource of the TLS routes of the Gateway API
+	GatewayTLSRouteGVR = schema.GroupVersionResource{Group: GatewayGroup, Version: GatewayVersion, Resource: "tlsroutes"}
+	// GatewayTCPRouteGVR is the resource of the TCP routes of the Gateway API
+	GatewayTCPRouteGVR = schema.GroupVersionResource{Group: GatewayGroup, Version: GatewayVersion, Resource: "tcproutes"}
+)
+
+// gatewayRouteKind describes a kind of route: its resource and the protocols of the gateway listeners it attaches to.
+type gatewayRouteKind struct {
+	resource  schema.GroupVersionResource
+	protocols []string
+}
+
+var gatewayRouteKinds = map[string]gatewayRouteKind{
+	GatewayHTTPRouteKind: {resource: GatewayHTTPRouteGVR, protocols: []string{"HTTP", "HTTPS"}},
+	GatewayTLSRouteKind:  {resource: GatewayTLSRouteGVR, protocols: []string{"TLS"}},
+	GatewayTCPRouteKind:  {resource: GatewayTCPRouteGVR, protocols: []string{"TCP"}},
+}
+
+// gatewayParentReference references the gateway, and optionally its listener, a route attaches to.
+type gatewayParentReference struct {
+	Group       string `json:"group,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// gatewayCondition is a condition of the status of a gateway resource.
+type gatewayCondition struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+// gatewayRoute holds the fields shared by the HTTPRoute, TLSRoute and TCPRoute resources which are used by the source.
+type gatewayRoute struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              struct {
+		ParentRefs []gatewayParentReference `json:"parentRefs,omitempty"`
+		Hostnames  []string                 `json:"hostnames,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Parents []struct {
+			ParentRef  gatewayParentReference `json:"parentRef"`
+			Conditions []gatewayCondition     `json:"conditions,omitempty"`
+		} `json:"parents,omitempty"`
+	} `json:"status,omitempty"`
+}
+
+// gatewayListener holds the fields of a gateway listener which are used by the source.
+type gatewayListener struct {
+	Name          string `json:"name"`
+	Hostname      string `json:"hostname,omitempty"`
+	Protocol      string `json:"protocol"`
+	AllowedRoutes *struct {
+		Namespaces *struct {
+			From     string                `json:"from,omitempty"`
+			Selector *metav1.LabelSelector `json:"selector,omitempty"`
+		} `json:"namespaces,omitempty"`
+		Kinds []struct {
+			Group string `json:"group,omitempty"`
+			Kind  string `json:"kind"`
+		} `json:"kinds,omitempty"`
+	} `json:"allowedRoutes,omitempty"`
+}
+
+// gateway holds the fields of the Gateway resource which are used by the source.
+type gateway struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              struct {
+		Listeners []gatewayListener `json:"listeners"`
+	} `json:"spec"`
+	Status struct {
+		Addresses []struct {
+			Type  string `json:"type,omitempty"`
+			Value string `json:"value"`
+		} `json:"addresses,omitempty"`
+	} `json:"status,omitempty"`
+}
+
+// gatewayRouteSource is an implementation of Source for the routes of the Kubernetes Gateway API.
+// The hostnames are the spec.hostnames of the routes intersected with the hostnames of the listeners
+// of their parent gateways, and the targets are the status.addresses of these gateways.
+// Use targetAnnotationKey to explicitly set Endpoint.
+type gatewayRouteSource struct {
+	kind                     string
+	namespace                string
+	namespaceFilter          *namespaceFilter
+	annotationFilter         string
+	fqdnTemplate             *template.Template
+	combineFQDNAnnotation    bool
+	ignoreHostnameAnnotation bool
+	routeInformer            kubeinformers.GenericInformer
+	gatewayInformer          kubeinformers.GenericInformer
+	namespaceInformer        coreinformers.NamespaceInformer
+}
+
+// NewGatewayRouteSource creates a new gatewayRouteSource for the routes of the given kind with the given config.
+func NewGatewayRouteSource(
+	dynamicKubeClient dynamic.Interface,
+	kubeClient kubernetes.Interface,
+	kind string,
+	namespaces []string,
+	namespaceSelector string,
+	annotationFilter string,
+	fqdnTemplate string,
+	combineFqdnAnnotation bool,
+	ignoreHostnameAnnotation bool,
+	labelFilter string,
+) (Source, error) {
+	routeKind, ok := gatewayRouteKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported gateway route kind: %s", kind)
+	}
+
+	var (
+		tmpl *template.Template
+		err  error
+	)
+	if fqdnTemplate != "" {
+		tmpl, err = template.New("endpoint").Funcs(template.FuncMap{
+			"trimPrefix": strings.TrimPrefix,
+		}).Parse(fqdnTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	labelFilterOptions, err := labelFilterListOptions(labelFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceFilter, err := newNamespaceFilter(kubeClient, namespaces, namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	namespace := namespaceFilter.informerNamespace()
+
+	// Use shared informers to listen for add/update/delete of routes in the specified namespace,
+	// and of the gateways they may attach to in all namespaces.
+	// Only the routes are filtered by their labels, the gateways and namespaces aren't labeled alike.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	routeInformerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicKubeClient, 0, namespace, labelFilterOptions)
+	routeInformer := routeInformerFactory.ForResource(routeKind.resource)
+	gatewayInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicKubeClient, 0)
+	gatewayInformer := gatewayInformerFactory.ForResource(GatewayGVR)
+
+	// Add default resource event handlers to properly initialize informers.
+	for _, informer := range []cache.SharedIndexInformer{routeInformer.Informer(), gatewayInformer.Informer()} {
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+	}
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	routeInformerFactory.Start(wait.NeverStop)
+	gatewayInformerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err = poll(time.Second, 60*time.Second, func() (bool, error) {
+		return routeInformer.Informer().HasSynced() &&
+			gatewayInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	// the namespaces of the routes are matched against the namespace selectors of the gateway listeners
+	namespaceInformer, err := sharedNamespaceInformer(kubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gatewayRouteSource{
+		kind:                     kind,
+		namespace:                namespace,
+		namespaceFilter:          namespaceFilter,
+		annotationFilter:         annotationFilter,
+		fqdnTemplate:             tmpl,
+		combineFQDNAnnotation:    combineFqdnAnnotation,
+		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		routeInformer:            routeInformer,
+		gatewayInformer:          gatewayInformer,
+		namespaceInformer:        namespaceInformer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for each host-target combination that should be processed.
+// Retrieves all routes of the source's kind in the source's namespace(s).
+func (sc *gatewayRouteSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	objects, err := sc.routeInformer.Lister().ByNamespace(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []*gatewayRoute
+	for _, object := range objects {
+		route := &gatewayRoute{}
+		if err := fromUnstructured(object, route); err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+
+	matchesAnnotations, err := annotationFilterFunc(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	filtered := routes[:0]
+	for _, route := range routes {
+		if sc.namespaceFilter.matches(route.Namespace) && matchesAnnotations(route.Annotations) {
+			filtered = append(filtered, route)
+		}
+	}
+	routes = filtered
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, route := range routes {
+		// Check controller annotation to see if we are responsible.
+		controller, ok := route.Annotations[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping %s %s/%s because controller value does not match, found: %s, required: %s",
+				sc.kind, route.Namespace, route.Name, controller, controllerAnnotationValue)
+			continue
+		}
+
+		routeEndpoints, err := sc.endpointsFromRoute(route)
+		if err != nil {
+			return nil, err
+		}
+
+		// apply template if no hostname could be derived from the route
+		if (sc.combineFQDNAnnotation || len(routeEndpoints) == 0) && sc.fqdnTemplate != nil {
+			tmplEndpoints, err := sc.endpointsFromTemplate(route)
+			if err != nil {
+				return nil, err
+			}
+
+			if sc.combineFQDNAnnotation {
+				routeEndpoints = append(routeEndpoints, tmplEndpoints...)
+			} else {
+				routeEndpoints = tmplEndpoints
+			}
+		}
+
+		if len(routeEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from %s %s/%s", sc.kind, route.Namespace, route.Name)
+			continue
+		}
+
+		log.Debugf("Endpoints generated from %s: %s/%s: %v", sc.kind, route.Namespace, route.Name, routeEndpoints)
+		sc.setResourceLabel(route, routeEndpoints)
+		endpoints = append(endpoints, routeEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+// endpointsFromRoute extracts the endpoints of the hostnames of a route and of its hostname annotation.
+func (sc *gatewayRouteSource) endpointsFromRoute(route *gatewayRoute) ([]*endpoint.Endpoint, error) {
+	hostTargets, gatewayTargets, err := sc.hostTargets(route)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := getTTLFromAnnotations(route.Annotations)
+	if err != nil {
+		log.Warn(err)
+	}
+
+	annotationTargets := getTargetsFromTargetAnnotation(route.Annotations)
+	providerSpecific, setIdentifier := getProviderSpecificAnnotations(route.Annotations)
+
+	hostnames := make([]string, 0, len(hostTargets))
+	for hostname := range hostTargets {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range hostnames {
+		targets := hostTargets[hostname]
+		if len(annotationTargets) > 0 {
+			targets = annotationTargets
+		}
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets, ttl, providerSpecific, setIdentifier)...)
+	}
+
+	// Skip endpoints if we do not want entries from annotations
+	if !sc.ignoreHostnameAnnotation {
+		targets := gatewayTargets
+		if len(annotationTargets) > 0 {
+			targets = annotationTargets
+		}
+		if len(targets) > 0 {
+			for _, hostname := range getHostnamesFromAnnotations(route.Annotations) {
+				endpoints = append(endpoints, endpointsForHostname(hostname, targets, ttl, providerSpecific, setIdentifier)...)
+			}
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (sc *gatewayRouteSource) endpointsFromTemplate(route *gatewayRoute) ([]*endpoint.Endpoint, error) {
+	// Process the whole template string
+	var buf bytes.Buffer
+	err := sc.fqdnTemplate.Execute(&buf, route)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply template on %s %s/%s: %v", sc.kind, route.Namespace, route.Name, err)
+	}
+
+	hostnames := buf.String()
+
+	ttl, err := getTTLFromAnnotations(route.Annotations)
+	if err != nil {
+		log.Warn(err)
+	}
+
+	targets := getTargetsFromTargetAnnotation(route.Annotations)
+
+	if len(targets) == 0 {
+		_, targets, err = sc.hostTargets(route)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	providerSpecific, setIdentifier := getProviderSpecificAnnotations(route.Annotations)
+
+	var endpoints []*endpoint.Endpoint
+	// splits the FQDN template and removes the trailing periods
+	hostnameList := strings.Split(strings.Replace(hostnames, " ", "", -1), ",")
+	for _, hostname := range hostnameList {
+		hostname = strings.TrimSuffix(hostname, ".")
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets, ttl, providerSpecific, setIdentifier)...)
+	}
+	return endpoints, nil
+}
+
+// hostTargets returns the targets of every hostname of the route, which are the addresses of the gateways
+// with listeners accepting the route for this hostname, as well as the addresses of all the gateways accepting the route.
+func (sc *gatewayRouteSource) hostTargets(route *gatewayRoute) (map[string]endpoint.Targets, endpoint.Targets, error) {
+	hostTargets := map[string]endpoint.Targets{}
+	var gatewayTargets endpoint.Targets
+
+	for _, ref := range route.Spec.ParentRefs {
+		if (ref.Group != "" && ref.Group != GatewayGroup) || (ref.Kind != "" && ref.Kind != gatewayKind) {
+			continue
+		}
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = route.Namespace
+		}
+		if !routeAccepted(route, ref, namespace) {
+			log.Debugf("Skipping gateway %s/%s of %s %s/%s because it didn't accept the route", namespace, ref.Name, sc.kind, route.Namespace, route.Name)
+			continue
+		}
+
+		object, err := sc.gatewayInformer.Lister().ByNamespace(namespace).Get(ref.Name)
+		if err != nil {
+			log.Debugf("Failed retrieving gateway %s/%s referenced by %s %s/%s: %v", namespace, ref.Name, sc.kind, route.Namespace, route.Name, err)
+			continue
+		}
+		gw := &gateway{}
+		if err := fromUnstructured(object, gw); err != nil {
+			return nil, nil, err
+		}
+
+		var targets endpoint.Targets
+		for _, address := range gw.Status.Addresses {
+			if address.Value != "" {
+				targets = append(targets, address.Value)
+			}
+		}
+		if len(targets) == 0 {
+			log.Debugf("Skipping gateway %s/%s of %s %s/%s because it has no address", namespace, ref.Name, sc.kind, route.Namespace, route.Name)
+			continue
+		}
+
+		accepted := false
+		for _, listener := range gw.Spec.Listeners {
+			if ref.SectionName != "" && ref.SectionName != listener.Name {
+				continue
+			}
+			if !sc.listenerAllowsRoute(listener, gw, route) {
+				continue
+			}
+			accepted = true
+			for _, hostname := range gatewayHostnames(listener.Hostname, route.Spec.Hostnames) {
+				hostTargets[hostname] = mergeGatewayTargets(hostTargets[hostname], targets)
+			}
+		}
+		if accepted {
+			gatewayTargets = mergeGatewayTargets(gatewayTargets, targets)
+		}
+	}
+
+	return hostTargets, gatewayTargets, nil
+}
+
+// listenerAllowsRoute returns whether a gateway listener accepts routes of the source's kind from the namespace of the route.
+func (sc *gatewayRouteSource) listenerAllowsRoute(listener gatewayListener, gw *gateway, route *gatewayRoute) bool {
+	supported := false
+	for _, protocol := range gatewayRouteKinds[sc.kind].protocols {
+		if listener.Protocol == protocol {
+			supported = true
+		}
+	}
+	if !supported {
+		return false
+	}
+
+	if listener.AllowedRoutes == nil {
+		return gw.Namespace == route.Namespace
+	}
+
+	if len(listener.AllowedRoutes.Kinds) > 0 {
+		allowed := false
+		for _, kind := range listener.AllowedRoutes.Kinds {
+			if (kind.Group == "" || kind.Group == GatewayGroup) && kind.Kind == sc.kind {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	namespaces := listener.AllowedRoutes.Namespaces
+	if namespaces == nil {
+		return gw.Namespace == route.Namespace
+	}
+	switch namespaces.From {
+	case "All":
+		return true
+	case "Selector":
+		if namespaces.Selector == nil {
+			return false
+		}
+		selector, err := metav1.LabelSelectorAsSelector(namespaces.Selector)
+		if err != nil {
+			log.Warnf("Invalid namespace selector of listener %s of gateway %s/%s: %v", listener.Name, gw.Namespace, gw.Name, err)
+			return false
+		}
+		namespace, err := sc.namespaceInformer.Lister().Get(route.Namespace)
+		if err != nil {
+			log.Debugf("Failed retrieving namespace %s: %v", route.Namespace, err)
+			return false
+		}
+		return selector.Matches(labels.Set(namespace.Labels))
+	default:
+		return gw.Namespace == route.Namespace
+	}
+}
+
+func (sc *gatewayRouteSource) setResourceLabel(route *gatewayRoute, endpoints []*endpoint.Endpoint) {
+	for _, ep := range endpoints {
+		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("%s/%s/%s", strings.ToLower(sc.kind), route.Namespace, route.Name)
+	}
+	setPriorityLabel(route.Annotations, endpoints)
+	setClaimLabel(route.Annotations, endpoints)
+}
+
+func (sc *gatewayRouteSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debugf("Adding event handler for %s", sc.kind)
+
+	sc.namespaceFilter.AddEventHandler(handler)
+
+	// Right now there is no way to remove event handler from informer, see:
+	// https://github.com/kubernetes/kubernetes/issues/79610
+	for _, informer := range []kubeinformers.GenericInformer{sc.routeInformer, sc.gatewayInformer} {
+		informer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					handler()
+				},
+				UpdateFunc: func(old interface{}, new interface{}) {
+					handler()
+				},
+				DeleteFunc: func(obj interface{}) {
+					handler()
+				},
+			},
+		)
+	}
+}
+
+// routeAccepted returns whether the status of the route reports that it has been accepted by the given parent.
+func routeAccepted(route *gatewayRoute, ref gatewayParentReference, namespace string) bool {
+	for _, parent := range route.Status.Parents {
+		parentNamespace := parent.ParentRef.Namespace
+		if parentNamespace == "" {
+			parentNamespace = route.Namespace
+		}
+		if parent.ParentRef.Name != ref.Name || parentNamespace != namespace || parent.ParentRef.SectionName != ref.SectionName {
+			continue
+		}
+		for _, condition := range parent.Conditions {
+			if condition.Type == "Accepted" && condition.Status == "True" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gatewayHostnames intersects the hostname of a listener with the hostnames of a route: a hostname matches a wildcard
+// hostname with the same suffix, and the most specific hostname is kept. A missing hostname matches all hostnames.
+func gatewayHostnames(listenerHostname string, routeHostnames []string) []string {
+	if listenerHostname == "" {
+		return routeHostnames
+	}
+	if len(routeHostnames) == 0 {
+		return []string{listenerHostname}
+	}
+
+	var hostnames []string
+	for _, routeHostname := range routeHostnames {
+		switch {
+		case routeHostname == listenerHostname:
+			hostnames = append(hostnames, routeHostname)
+		case matchesWildcardHostname(routeHostname, listenerHostname):
+			hostnames = append(hostnames, routeHostname)
+		case matchesWildcardHostname(listenerHostname, routeHostname):
+			hostnames = append(hostnames, listenerHostname)
+		}
+	}
+	return hostnames
+}
+
+// matchesWildcardHostname returns whether the hostname is a subdomain of the domain of the wildcard hostname.
+func matchesWildcardHostname(hostname, wildcard string) bool {
+	if !strings.HasPrefix(wildcard, "*.") {
+		return false
+	}
+	return strings.HasSuffix(hostname, wildcard[1:]) && len(hostname) > len(wildcard)-1
+}
+
+// mergeGatewayTargets adds the targets which are missing to the given targets.
+func mergeGatewayTargets(targets endpoint.Targets, added endpoint.Targets) endpoint.Targets {
+	for _, target := range added {
+		found := false
+		for _, existing := range targets {
+			if existing == target {
+				found = true
+			}
+		}
+		if !found {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// fromUnstructured converts an object of a dynamic informer to the given type.
+func fromUnstructured(object runtime.Object, converted interface{}) error {
+	u, ok := object.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("could not convert %T", object)
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), converted)
+}
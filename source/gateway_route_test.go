@@ -0,0 +1,606 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+	fakeKube "k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// This is a compile-time validation that gatewayRouteSource is a Source.
+var _ Source = &gatewayRouteSource{}
+
+type fakeGatewayListener struct {
+	name      string
+	hostname  string
+	protocol  string
+	from      string
+	selector  map[string]string
+	kinds     []string
+	noAllowed bool
+}
+
+type fakeGateway struct {
+	namespace string
+	name      string
+	listeners []fakeGatewayListener
+	addresses []string
+}
+
+func (gw fakeGateway) Unstructured() *unstructured.Unstructured {
+	listeners := []interface{}{}
+	for _, l := range gw.listeners {
+		listener := map[string]interface{}{
+			"name":     l.name,
+			"protocol": l.protocol,
+		}
+		if l.hostname != "" {
+			listener["hostname"] = l.hostname
+		}
+		if !l.noAllowed {
+			namespaces := map[string]interface{}{"from": l.from}
+			if l.selector != nil {
+				matchLabels := map[string]interface{}{}
+				for k, v := range l.selector {
+					matchLabels[k] = v
+				}
+				namespaces["selector"] = map[string]interface{}{"matchLabels": matchLabels}
+			}
+			allowedRoutes := map[string]interface{}{"namespaces": namespaces}
+			if len(l.kinds) > 0 {
+				kinds := []interface{}{}
+				for _, kind := range l.kinds {
+					kinds = append(kinds, map[string]interface{}{"kind": kind})
+				}
+				allowedRoutes["kinds"] = kinds
+			}
+			listener["allowedRoutes"] = allowedRoutes
+		}
+		listeners = append(listeners, listener)
+	}
+	addresses := []interface{}{}
+	for _, address := range gw.addresses {
+		addresses = append(addresses, map[string]interface{}{"type": "IPAddress", "value": address})
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GatewayGroup + "/" + GatewayVersion,
+		"kind":       gatewayKind,
+		"metadata": map[string]interface{}{
+			"namespace": gw.namespace,
+			"name":      gw.name,
+		},
+		"spec": map[string]interface{}{
+			"listeners": listeners,
+		},
+		"status": map[string]interface{}{
+			"addresses": addresses,
+		},
+	}}
+}
+
+type fakeGatewayParent struct {
+	namespace   string
+	name        string
+	sectionName string
+	rejected    bool
+}
+
+type fakeGatewayRoute struct {
+	namespace   string
+	name        string
+	annotations map[string]string
+	hostnames   []string
+	parents     []fakeGatewayParent
+}
+
+func (route fakeGatewayRoute) Unstructured(kind string) *unstructured.Unstructured {
+	parentRefs := []interface{}{}
+	parents := []interface{}{}
+	for _, p := range route.parents {
+		ref := map[string]interface{}{"name": p.name}
+		if p.namespace != "" {
+			ref["namespace"] = p.namespace
+		}
+		if p.sectionName != "" {
+			ref["sectionName"] = p.sectionName
+		}
+		parentRefs = append(parentRefs, ref)
+		status := "True"
+		if p.rejected {
+			status = "False"
+		}
+		parents = append(parents, map[string]interface{}{
+			"parentRef":  ref,
+			"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": status}},
+		})
+	}
+	hostnames := []interface{}{}
+	for _, hostname := range route.hostnames {
+		hostnames = append(hostnames, hostname)
+	}
+	annotations := map[string]interface{}{}
+	for k, v := range route.annotations {
+		annotations[k] = v
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GatewayGroup + "/" + GatewayVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"namespace":   route.namespace,
+			"name":        route.name,
+			"annotations": annotations,
+		},
+		"spec": map[string]interface{}{
+			"parentRefs": parentRefs,
+			"hostnames":  hostnames,
+		},
+		"status": map[string]interface{}{
+			"parents": parents,
+		},
+	}}
+}
+
+func newGatewayDynamicKubernetesClient() *fakeDynamic.FakeDynamicClient {
+	s := runtime.NewScheme()
+	for _, kind := range []string{gatewayKind, GatewayHTTPRouteKind, GatewayTLSRouteKind, GatewayTCPRouteKind} {
+		s.AddKnownTypeWithName(schema.GroupVersionKind{Group: GatewayGroup, Version: GatewayVersion, Kind: kind}, &unstructured.Unstructured{})
+		s.AddKnownTypeWithName(schema.GroupVersionKind{Group: GatewayGroup, Version: GatewayVersion, Kind: kind + "List"}, &unstructured.UnstructuredList{})
+	}
+	return fakeDynamic.NewSimpleDynamicClient(s)
+}
+
+func TestNewGatewayRouteSource(t *testing.T) {
+	for _, ti := range []struct {
+		title        string
+		kind         string
+		fqdnTemplate string
+		expectError  bool
+	}{
+		{
+			title: "http routes",
+			kind:  GatewayHTTPRouteKind,
+		},
+		{
+			title:        "valid template",
+			kind:         GatewayTLSRouteKind,
+			fqdnTemplate: "{{.Name}}-{{.Namespace}}.ext-dns.test.com",
+		},
+		{
+			title:        "invalid template",
+			kind:         GatewayTCPRouteKind,
+			fqdnTemplate: "{{.Name",
+			expectError:  true,
+		},
+		{
+			title:       "unsupported kind",
+			kind:        "UDPRoute",
+			expectError: true,
+		},
+	} {
+		t.Run(ti.title, func(t *testing.T) {
+			_, err := NewGatewayRouteSource(
+				newGatewayDynamicKubernetesClient(),
+				fakeKube.NewSimpleClientset(),
+				ti.kind,
+				nil,
+				"",
+				"",
+				ti.fqdnTemplate,
+				false,
+				false,
+				"",
+			)
+			if ti.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGatewayRouteSourceEndpoints(t *testing.T) {
+	gw := fakeGateway{
+		namespace: "default",
+		name:      "gw",
+		listeners: []fakeGatewayListener{
+			{name: "http", protocol: "HTTP", hostname: "*.example.org", from: "Same"},
+		},
+		addresses: []string{"1.2.3.4"},
+	}
+	parent := []fakeGatewayParent{{name: "gw"}}
+
+	for _, ti := range []struct {
+		title                    string
+		kind                     string
+		targetNamespace          string
+		annotationFilter         string
+		fqdnTemplate             string
+		combineFQDNAndAnnotation bool
+		ignoreHostnameAnnotation bool
+		namespaces               []*v1.Namespace
+		gateways                 []fakeGateway
+		routes                   []fakeGatewayRoute
+		expected                 []*endpoint.Endpoint
+	}{
+		{
+			title:    "no route",
+			kind:     GatewayHTTPRouteKind,
+			gateways: []fakeGateway{gw},
+		},
+		{
+			title:    "hostnames intersected with the listener hostname",
+			kind:     GatewayHTTPRouteKind,
+			gateways: []fakeGateway{gw},
+			routes: []fakeGatewayRoute{{
+				namespace: "default",
+				name:      "foo",
+				hostnames: []string{"foo.example.org", "bar.other.org", "*.example.org", "*.bar.example.org"},
+				parents:   parent,
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "*.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "*.bar.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title: "route hostname matching a wildcard route hostname",
+			kind:  GatewayHTTPRouteKind,
+			gateways: []fakeGateway{{
+				namespace: "default",
+				name:      "gw",
+				listeners: []fakeGatewayListener{{name: "http", protocol: "HTTP", hostname: "foo.example.org", from: "Same"}},
+				addresses: []string{"1.2.3.4"},
+			}},
+			routes: []fakeGatewayRoute{{
+				namespace: "default",
+				name:      "foo",
+				hostnames: []string{"*.example.org"},
+				parents:   parent,
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title: "listener hostname without route hostnames",
+			kind:  GatewayTCPRouteKind,
+			gateways: []fakeGateway{{
+				namespace: "default",
+				name:      "gw",
+				listeners: []fakeGatewayListener{{name: "tcp", protocol: "TCP", hostname: "tcp.example.org", from: "Same"}},
+				addresses: []string{"1.2.3.4", "lb.example.com"},
+			}},
+			routes: []fakeGatewayRoute{{
+				namespace: "default",
+				name:      "foo",
+				parents:   parent,
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "tcp.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "tcp.example.org", Targets: endpoint.Targets{"lb.example.com"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title:    "route not accepted by the gateway",
+			kind:     GatewayHTTPRouteKind,
+			gateways: []fakeGateway{gw},
+			routes: []fakeGatewayRoute{{
+				namespace: "default",
+				name:      "foo",
+				hostnames: []string{"foo.example.org"},
+				parents:   []fakeGatewayParent{{name: "gw", rejected: true}},
+			}},
+		},
+		{
+			title:    "missing gateway",
+			kind:     GatewayHTTPRouteKind,
+			gateways: []fakeGateway{gw},
+			routes: []fakeGatewayRoute{{
+				namespace: "default",
+				name:      "foo",
+				hostnames: []string{"foo.example.org"},
+				parents:   []fakeGatewayParent{{name: "other"}},
+			}},
+		},
+		{
+			title:    "listener of another protocol",
+			kind:     GatewayTLSRouteKind,
+			gateways: []fakeGateway{gw},
+			routes: []fakeGatewayRoute{{
+				namespace: "default",
+				name:      "foo",
+				hostnames: []string{"foo.example.org"},
+				parents:   parent,
+			}},
+		},
+		{
+			title: "listener selected by section name",
+			kind:  GatewayHTTPRouteKind,
+			gateways: []fakeGateway{{
+				namespace: "default",
+				name:      "gw",
+				listeners: []fakeGatewayListener{
+					{name: "foo", protocol: "HTTP", hostname: "foo.example.org", from: "Same"},
+					{name: "bar", protocol: "HTTPS", hostname: "bar.example.org", from: "Same"},
+				},
+				addresses: []string{"1.2.3.4"},
+			}},
+			routes: []fakeGatewayRoute{{
+				namespace: "default",
+				name:      "foo",
+				parents:   []fakeGatewayParent{{name: "gw", sectionName: "bar"}},
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "bar.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title: "routes of other namespaces",
+			kind:  GatewayHTTPRouteKind,
+			namespaces: []*v1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"gateway": "shared"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+			},
+			gateways: []fakeGateway{
+				{
+					namespace: "infra",
+					name:      "all",
+					listeners: []fakeGatewayListener{{name: "http", protocol: "HTTP", from: "All"}},
+					addresses: []string{"1.1.1.1"},
+				},
+				{
+					namespace: "infra",
+					name:      "selected",
+					listeners: []fakeGatewayListener{{name: "http", protocol: "HTTP", from: "Selector", selector: map[string]string{"gateway": "shared"}}},
+					addresses: []string{"2.2.2.2"},
+				},
+				{
+					namespace: "infra",
+					name:      "same",
+					listeners: []fakeGatewayListener{{name: "http", protocol: "HTTP", noAllowed: true}},
+					addresses: []string{"3.3.3.3"},
+				},
+			},
+			routes: []fakeGatewayRoute{
+				{
+					namespace: "team-a",
+					name:      "a",
+					hostnames: []string{"a.example.org"},
+					parents:   []fakeGatewayParent{{namespace: "infra", name: "all"}, {namespace: "infra", name: "selected"}, {namespace: "infra", name: "same"}},
+				},
+				{
+					namespace: "team-b",
+					name:      "b",
+					hostnames: []string{"b.example.org"},
+					parents:   []fakeGatewayParent{{namespace: "infra", name: "all"}, {namespace: "infra", name: "selected"}, {namespace: "infra", name: "same"}},
+				},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.example.org", Targets: endpoint.Targets{"1.1.1.1", "2.2.2.2"}},
+				{DNSName: "b.example.org", Targets: endpoint.Targets{"1.1.1.1"}},
+			},
+		},
+		{
+			title: "listener restricted to other kinds",
+			kind:  GatewayHTTPRouteKind,
+			gateways: []fakeGateway{{
+				namespace: "default",
+				name:      "gw",
+				listeners: []fakeGatewayListener{{name: "http", protocol: "HTTP", from: "Same", kinds: []string{"GRPCRoute"}}},
+				addresses: []string{"1.2.3.4"},
+			}},
+			routes: []fakeGatewayRoute{{
+				namespace: "default",
+				name:      "foo",
+				hostnames: []string{"foo.example.org"},
+				parents:   parent,
+			}},
+		},
+		{
+			title:    "annotations",
+			kind:     GatewayHTTPRouteKind,
+			gateways: []fakeGateway{gw},
+			routes: []fakeGatewayRoute{{
+				namespace: "default",
+				name:      "foo",
+				annotations: map[string]string{
+					hostnameAnnotationKey: "foo.other.org",
+					targetAnnotationKey:   "lb.example.com",
+					ttlAnnotationKey:      "60",
+				},
+				hostnames: []string{"foo.example.org"},
+				parents:   parent,
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"lb.example.com"}, RecordTTL: endpoint.TTL(60)},
+				{DNSName: "foo.other.org", Targets: endpoint.Targets{"lb.example.com"}, RecordTTL: endpoint.TTL(60)},
+			},
+		},
+		{
+			title:                    "ignored hostname annotation",
+			kind:                     GatewayHTTPRouteKind,
+			ignoreHostnameAnnotation: true,
+			gateways:                 []fakeGateway{gw},
+			routes: []fakeGatewayRoute{{
+				namespace:   "default",
+				name:        "foo",
+				annotations: map[string]string{hostnameAnnotationKey: "foo.other.org"},
+				hostnames:   []string{"foo.example.org"},
+				parents:     parent,
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title:            "annotation filter and controller annotation",
+			kind:             GatewayHTTPRouteKind,
+			annotationFilter: "team=a",
+			gateways:         []fakeGateway{gw},
+			routes: []fakeGatewayRoute{
+				{
+					namespace:   "default",
+					name:        "a",
+					annotations: map[string]string{"team": "a"},
+					hostnames:   []string{"a.example.org"},
+					parents:     parent,
+				},
+				{
+					namespace:   "default",
+					name:        "b",
+					annotations: map[string]string{"team": "b"},
+					hostnames:   []string{"b.example.org"},
+					parents:     parent,
+				},
+				{
+					namespace:   "default",
+					name:        "c",
+					annotations: map[string]string{"team": "a", controllerAnnotationKey: "other"},
+					hostnames:   []string{"c.example.org"},
+					parents:     parent,
+				},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title:           "routes of the target namespace",
+			kind:            GatewayHTTPRouteKind,
+			targetNamespace: "default",
+			gateways: []fakeGateway{{
+				namespace: "infra",
+				name:      "gw",
+				listeners: []fakeGatewayListener{{name: "http", protocol: "HTTP", from: "All"}},
+				addresses: []string{"1.2.3.4"},
+			}},
+			routes: []fakeGatewayRoute{
+				{
+					namespace: "default",
+					name:      "a",
+					hostnames: []string{"a.example.org"},
+					parents:   []fakeGatewayParent{{namespace: "infra", name: "gw"}},
+				},
+				{
+					namespace: "other",
+					name:      "b",
+					hostnames: []string{"b.example.org"},
+					parents:   []fakeGatewayParent{{namespace: "infra", name: "gw"}},
+				},
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+		{
+			title:                    "fqdn template",
+			kind:                     GatewayTCPRouteKind,
+			fqdnTemplate:             "{{.Name}}.{{.Namespace}}.example.org",
+			combineFQDNAndAnnotation: true,
+			gateways: []fakeGateway{{
+				namespace: "default",
+				name:      "gw",
+				listeners: []fakeGatewayListener{{name: "tcp", protocol: "TCP", hostname: "tcp.example.org", from: "Same"}},
+				addresses: []string{"1.2.3.4"},
+			}},
+			routes: []fakeGatewayRoute{{
+				namespace: "default",
+				name:      "foo",
+				parents:   parent,
+			}},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "tcp.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "foo.default.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+			},
+		},
+	} {
+		t.Run(ti.title, func(t *testing.T) {
+			fakeKubernetesClient := fakeKube.NewSimpleClientset()
+			for _, namespace := range ti.namespaces {
+				_, err := fakeKubernetesClient.CoreV1().Namespaces().Create(context.Background(), namespace, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			fakeDynamicClient := newGatewayDynamicKubernetesClient()
+			for _, gw := range ti.gateways {
+				_, err := fakeDynamicClient.Resource(GatewayGVR).Namespace(gw.namespace).Create(context.Background(), gw.Unstructured(), metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+			for _, route := range ti.routes {
+				_, err := fakeDynamicClient.Resource(gatewayRouteKinds[ti.kind].resource).Namespace(route.namespace).Create(context.Background(), route.Unstructured(ti.kind), metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			src, err := NewGatewayRouteSource(
+				fakeDynamicClient,
+				fakeKubernetesClient,
+				ti.kind,
+				[]string{ti.targetNamespace},
+				"",
+				ti.annotationFilter,
+				ti.fqdnTemplate,
+				ti.combineFQDNAndAnnotation,
+				ti.ignoreHostnameAnnotation,
+				"",
+			)
+			require.NoError(t, err)
+
+			endpoints, err := src.Endpoints(context.Background())
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, ti.expected)
+			for _, ep := range endpoints {
+				assert.True(t, strings.HasPrefix(ep.Labels[endpoint.ResourceLabelKey], strings.ToLower(ti.kind)+"/"), "should set the resource label")
+			}
+		})
+	}
+}
+
+func TestGatewayHostnames(t *testing.T) {
+	for _, ti := range []struct {
+		listener string
+		route    []string
+		expected []string
+	}{
+		{listener: "", route: []string{"foo.example.org"}, expected: []string{"foo.example.org"}},
+		{listener: "foo.example.org", route: nil, expected: []string{"foo.example.org"}},
+		{listener: "foo.example.org", route: []string{"foo.example.org", "bar.example.org"}, expected: []string{"foo.example.org"}},
+		{listener: "*.example.org", route: []string{"foo.example.org", "example.org", "foo.example.com"}, expected: []string{"foo.example.org"}},
+		{listener: "*.example.org", route: []string{"*.foo.example.org", "*.example.org"}, expected: []string{"*.foo.example.org", "*.example.org"}},
+		{listener: "foo.bar.example.org", route: []string{"*.example.org"}, expected: []string{"foo.bar.example.org"}},
+		{listener: "*.bar.example.org", route: []string{"*.example.org"}, expected: []string{"*.bar.example.org"}},
+	} {
+		assert.Equal(t, ti.expected, gatewayHostnames(ti.listener, ti.route), "listener %q, route %v", ti.listener, ti.route)
+	}
+}
@@ -72,11 +72,13 @@ func (suite *GatewaySuite) SetupTest() {
 	suite.source, err = NewIstioGatewaySource(
 		fakeKubernetesClient,
 		fakeIstioClient,
+		nil,
 		"",
 		"",
 		"{{.Name}}",
 		false,
 		false,
+		"",
 	)
 	suite.NoError(err, "should initialize gateway source")
 	suite.NoError(err, "should succeed")
@@ -138,11 +140,13 @@ func TestNewIstioGatewaySource(t *testing.T) {
 			_, err := NewIstioGatewaySource(
 				fake.NewSimpleClientset(),
 				NewFakeConfigStore(),
+				nil,
 				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
 				ti.combineFQDNAndAnnotation,
 				false,
+				"",
 			)
 			if ti.expectError {
 				assert.Error(t, err)
@@ -1093,11 +1097,13 @@ func testGatewayEndpoints(t *testing.T) {
 			gatewaySource, err := NewIstioGatewaySource(
 				fakeKubernetesClient,
 				fakeIstioClient,
-				ti.targetNamespace,
+				[]string{ti.targetNamespace},
+				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
 				ti.combineFQDNAndAnnotation,
 				ti.ignoreHostnameAnnotation,
+				"",
 			)
 			require.NoError(t, err)
 
@@ -1129,11 +1135,13 @@ func newTestGatewaySource(loadBalancerList []fakeIngressGatewayService) (*gatewa
 	src, err := NewIstioGatewaySource(
 		fakeKubernetesClient,
 		fakeIstioClient,
+		nil,
 		"",
 		"",
 		"{{.Name}}",
 		false,
 		false,
+		"",
 	)
 	if err != nil {
 		return nil, err
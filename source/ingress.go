@@ -26,11 +26,15 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
-	"k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
 	extinformers "k8s.io/client-go/informers/extensions/v1beta1"
+	netinformers "k8s.io/client-go/informers/networking/v1"
+	netv1beta1informers "k8s.io/client-go/informers/networking/v1beta1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
@@ -42,24 +46,41 @@ const (
 	ALBDualstackAnnotationKey = "alb.ingress.kubernetes.io/ip-address-type"
 	// ALBDualstackAnnotationValue is the value of the ALB dualstack annotation that indicates it is dualstack
 	ALBDualstackAnnotationValue = "dualstack"
+
+	// The legacy annotation used for defining the class of an ingress
+	ingressClassAnnotationKey = "kubernetes.io/ingress.class"
+	// The annotation marking the default IngressClass of the cluster
+	defaultIngressClassAnnotationKey = "ingressclass.kubernetes.io/is-default-class"
+	// The API group versions of the ingresses and ingress classes
+	networkingGroupVersion     = "networking.k8s.io/v1"
+	networkingBetaGroupVersion = "networking.k8s.io/v1beta1"
 )
 
 // ingressSource is an implementation of Source for Kubernetes ingress objects.
 // Ingress implementation will use the spec.rules.host value for the hostname
 // Use targetAnnotationKey to explicitly set Endpoint. (useful if the ingress
 // controller does not update, or to override with alternative endpoint)
+// The ingresses of the networking.k8s.io/v1 API are used, or those of the networking.k8s.io/v1beta1
+// or extensions/v1beta1 APIs on clusters which don't serve it yet.
 type ingressSource struct {
 	client                   kubernetes.Interface
 	namespace                string
+	namespaceFilter          *namespaceFilter
 	annotationFilter         string
 	fqdnTemplate             *template.Template
 	combineFQDNAnnotation    bool
 	ignoreHostnameAnnotation bool
-	ingressInformer          extinformers.IngressInformer
+	ingressClassNames        []string
+	ingressInformer          netinformers.IngressInformer
+	betaIngressInformer      netv1beta1informers.IngressInformer
+	legacyIngressInformer    extinformers.IngressInformer
+	ingressClassInformer     netinformers.IngressClassInformer
+	betaIngressClassInformer netv1beta1informers.IngressClassInformer
 }
 
 // NewIngressSource creates a new ingressSource with the given config.
-func NewIngressSource(kubeClient kubernetes.Interface, namespace, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, ignoreHostnameAnnotation bool) (Source, error) {
+// When ingress class names are given, only the ingresses of these classes are processed.
+func NewIngressSource(kubeClient kubernetes.Interface, namespaces []string, namespaceSelector, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, ignoreHostnameAnnotation bool, ingressClassNames []string, labelFilter string) (Source, error) {
 	var (
 		tmpl *template.Template
 		err  error
@@ -73,50 +94,108 @@ func NewIngressSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 		}
 	}
 
+	labelFilterOptions, err := labelFilterListOptions(labelFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceFilter, err := newNamespaceFilter(kubeClient, namespaces, namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	namespace := namespaceFilter.informerNamespace()
+
+	sc := &ingressSource{
+		client:                   kubeClient,
+		namespace:                namespace,
+		namespaceFilter:          namespaceFilter,
+		annotationFilter:         annotationFilter,
+		fqdnTemplate:             tmpl,
+		combineFQDNAnnotation:    combineFqdnAnnotation,
+		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		ingressClassNames:        ingressClassNames,
+	}
+
 	// Use shared informer to listen for add/update/delete of ingresses in the specified namespace.
 	// Set resync period to 0, to prevent processing when nothing has changed.
-	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
-	ingressInformer := informerFactory.Extensions().V1beta1().Ingresses()
+	// Only the ingresses are filtered by their labels, the classes of the cluster aren't labeled alike.
+	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace), kubeinformers.WithTweakListOptions(labelFilterOptions))
+	ingressClassInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0)
+	var informers []cache.SharedIndexInformer
+	switch {
+	case servesResource(kubeClient, networkingGroupVersion, "ingresses"):
+		sc.ingressInformer = informerFactory.Networking().V1().Ingresses()
+		informers = append(informers, sc.ingressInformer.Informer())
+	case servesResource(kubeClient, networkingBetaGroupVersion, "ingresses"):
+		log.Infof("Falling back to the %s ingresses as the cluster doesn't serve the %s ingresses", networkingBetaGroupVersion, networkingGroupVersion)
+		sc.betaIngressInformer = informerFactory.Networking().V1beta1().Ingresses()
+		informers = append(informers, sc.betaIngressInformer.Informer())
+	default:
+		log.Infof("Falling back to the extensions/v1beta1 ingresses as the cluster doesn't serve the %s ingresses", networkingGroupVersion)
+		sc.legacyIngressInformer = informerFactory.Extensions().V1beta1().Ingresses()
+		informers = append(informers, sc.legacyIngressInformer.Informer())
+	}
+	// the default class is only known on clusters serving the ingress classes
+	if len(ingressClassNames) > 0 {
+		switch {
+		case servesResource(kubeClient, networkingGroupVersion, "ingressclasses"):
+			sc.ingressClassInformer = ingressClassInformerFactory.Networking().V1().IngressClasses()
+			informers = append(informers, sc.ingressClassInformer.Informer())
+		case servesResource(kubeClient, networkingBetaGroupVersion, "ingressclasses"):
+			sc.betaIngressClassInformer = ingressClassInformerFactory.Networking().V1beta1().IngressClasses()
+			informers = append(informers, sc.betaIngressClassInformer.Informer())
+		}
+	}
 
-	// Add default resource event handlers to properly initialize informer.
-	ingressInformer.Informer().AddEventHandler(
-		cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj interface{}) {
+	// Add default resource event handlers to properly initialize informers.
+	for _, informer := range informers {
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
 			},
-		},
-	)
+		)
+	}
 
 	// TODO informer is not explicitly stopped since controller is not passing in its channel.
 	informerFactory.Start(wait.NeverStop)
+	ingressClassInformerFactory.Start(wait.NeverStop)
 
 	// wait for the local cache to be populated.
 	err = poll(time.Second, 60*time.Second, func() (bool, error) {
-		return ingressInformer.Informer().HasSynced(), nil
+		for _, informer := range informers {
+			if !informer.HasSynced() {
+				return false, nil
+			}
+		}
+		return true, nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to sync cache: %v", err)
 	}
 
-	sc := &ingressSource{
-		client:                   kubeClient,
-		namespace:                namespace,
-		annotationFilter:         annotationFilter,
-		fqdnTemplate:             tmpl,
-		combineFQDNAnnotation:    combineFqdnAnnotation,
-		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
-		ingressInformer:          ingressInformer,
-	}
 	return sc, nil
 }
 
 // Endpoints returns endpoint objects for each host-target combination that should be processed.
 // Retrieves all ingress resources on all namespaces
 func (sc *ingressSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
-	ingresses, err := sc.ingressInformer.Lister().Ingresses(sc.namespace).List(labels.Everything())
+	ingresses, err := sc.ingresses()
+	if err != nil {
+		return nil, err
+	}
+	matchesAnnotations, err := annotationFilterFunc(sc.annotationFilter)
 	if err != nil {
 		return nil, err
 	}
-	ingresses, err = sc.filterByAnnotations(ingresses)
+	filtered := ingresses[:0]
+	for _, ing := range ingresses {
+		if sc.namespaceFilter.matches(ing.Namespace) && matchesAnnotations(ing.Annotations) {
+			filtered = append(filtered, ing)
+		}
+	}
+	ingresses = filtered
+	ingresses, err = sc.filterByIngressClass(ingresses)
 	if err != nil {
 		return nil, err
 	}
@@ -166,7 +245,66 @@ func (sc *ingressSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, e
 	return endpoints, nil
 }
 
-func (sc *ingressSource) endpointsFromTemplate(ing *v1beta1.Ingress) ([]*endpoint.Endpoint, error) {
+// ingresses returns the ingresses of the source's namespace(s), converting those of the previous APIs.
+func (sc *ingressSource) ingresses() ([]*networkingv1.Ingress, error) {
+	if sc.ingressInformer != nil {
+		return sc.ingressInformer.Lister().Ingresses(sc.namespace).List(labels.Everything())
+	}
+
+	var previousIngresses []runtime.Object
+	if sc.betaIngressInformer != nil {
+		betaIngresses, err := sc.betaIngressInformer.Lister().Ingresses(sc.namespace).List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, ingress := range betaIngresses {
+			previousIngresses = append(previousIngresses, ingress)
+		}
+	} else {
+		legacyIngresses, err := sc.legacyIngressInformer.Lister().Ingresses(sc.namespace).List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, ingress := range legacyIngresses {
+			previousIngresses = append(previousIngresses, ingress)
+		}
+	}
+	ingresses := make([]*networkingv1.Ingress, 0, len(previousIngresses))
+	for _, previousIngress := range previousIngresses {
+		ingress := &networkingv1.Ingress{}
+		if err := convertObject(previousIngress, ingress); err != nil {
+			return nil, err
+		}
+		ingresses = append(ingresses, ingress)
+	}
+	return ingresses, nil
+}
+
+// ingressClasses returns the ingress classes of the cluster, converting those of the previous API.
+func (sc *ingressSource) ingressClasses() ([]*networkingv1.IngressClass, error) {
+	if sc.ingressClassInformer != nil {
+		return sc.ingressClassInformer.Lister().List(labels.Everything())
+	}
+	if sc.betaIngressClassInformer == nil {
+		return nil, nil
+	}
+
+	betaClasses, err := sc.betaIngressClassInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	classes := make([]*networkingv1.IngressClass, 0, len(betaClasses))
+	for _, betaClass := range betaClasses {
+		class := &networkingv1.IngressClass{}
+		if err := convertObject(betaClass, class); err != nil {
+			return nil, err
+		}
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
+
+func (sc *ingressSource) endpointsFromTemplate(ing *networkingv1.Ingress) ([]*endpoint.Endpoint, error) {
 	// Process the whole template string
 	var buf bytes.Buffer
 	err := sc.fqdnTemplate.Execute(&buf, ing)
@@ -199,37 +337,50 @@ func (sc *ingressSource) endpointsFromTemplate(ing *v1beta1.Ingress) ([]*endpoin
 	return endpoints, nil
 }
 
-// filterByAnnotations filters a list of ingresses by a given annotation selector.
-func (sc *ingressSource) filterByAnnotations(ingresses []*v1beta1.Ingress) ([]*v1beta1.Ingress, error) {
-	selector, err := getLabelSelector(sc.annotationFilter)
-	if err != nil {
-		return nil, err
+// filterByIngressClass filters a list of ingresses by the names of their classes, which are given by their spec.ingressClassName,
+// their legacy class annotation, or the default IngressClass of the cluster for the ingresses without class.
+func (sc *ingressSource) filterByIngressClass(ingresses []*networkingv1.Ingress) ([]*networkingv1.Ingress, error) {
+	// no class names returns original list
+	if len(sc.ingressClassNames) == 0 {
+		return ingresses, nil
 	}
 
-	// empty filter returns original list
-	if selector.Empty() {
-		return ingresses, nil
+	classes, err := sc.ingressClasses()
+	if err != nil {
+		return nil, err
 	}
+	defaultClassName := defaultIngressClassName(classes)
 
-	filteredList := []*v1beta1.Ingress{}
+	filteredList := []*networkingv1.Ingress{}
 
 	for _, ingress := range ingresses {
-		// include ingress if its annotations match the selector
-		if matchLabelSelector(selector, ingress.Annotations) {
-			filteredList = append(filteredList, ingress)
+		className := ingressClassName(ingress, defaultClassName)
+		selected := false
+		for _, name := range sc.ingressClassNames {
+			if className == name {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			log.Debugf("Skipping ingress %s/%s because its class %q isn't selected", ingress.Namespace, ingress.Name, className)
+			continue
 		}
+		filteredList = append(filteredList, ingress)
 	}
 
 	return filteredList, nil
 }
 
-func (sc *ingressSource) setResourceLabel(ingress *v1beta1.Ingress, endpoints []*endpoint.Endpoint) {
+func (sc *ingressSource) setResourceLabel(ingress *networkingv1.Ingress, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("ingress/%s/%s", ingress.Namespace, ingress.Name)
 	}
+	setPriorityLabel(ingress.Annotations, endpoints)
+	setClaimLabel(ingress.Annotations, endpoints)
 }
 
-func (sc *ingressSource) setDualstackLabel(ingress *v1beta1.Ingress, endpoints []*endpoint.Endpoint) {
+func (sc *ingressSource) setDualstackLabel(ingress *networkingv1.Ingress, endpoints []*endpoint.Endpoint) {
 	val, ok := ingress.Annotations[ALBDualstackAnnotationKey]
 	if ok && val == ALBDualstackAnnotationValue {
 		log.Debugf("Adding dualstack label to ingress %s/%s.", ingress.Namespace, ingress.Name)
@@ -240,7 +391,7 @@ func (sc *ingressSource) setDualstackLabel(ingress *v1beta1.Ingress, endpoints [
 }
 
 // endpointsFromIngress extracts the endpoints from ingress object
-func endpointsFromIngress(ing *v1beta1.Ingress, ignoreHostnameAnnotation bool) []*endpoint.Endpoint {
+func endpointsFromIngress(ing *networkingv1.Ingress, ignoreHostnameAnnotation bool) []*endpoint.Endpoint {
 	var endpoints []*endpoint.Endpoint
 
 	ttl, err := getTTLFromAnnotations(ing.Annotations)
@@ -282,7 +433,7 @@ func endpointsFromIngress(ing *v1beta1.Ingress, ignoreHostnameAnnotation bool) [
 	return endpoints
 }
 
-func targetsFromIngressStatus(status v1beta1.IngressStatus) endpoint.Targets {
+func targetsFromIngressStatus(status networkingv1.IngressStatus) endpoint.Targets {
 	var targets endpoint.Targets
 
 	for _, lb := range status.LoadBalancer.Ingress {
@@ -297,12 +448,84 @@ func targetsFromIngressStatus(status v1beta1.IngressStatus) endpoint.Targets {
 	return targets
 }
 
+// ingressClassName returns the class of an ingress, given by its spec.ingressClassName or by its legacy annotation,
+// or else the given default class.
+func ingressClassName(ingress *networkingv1.Ingress, defaultClassName string) string {
+	if ingress.Spec.IngressClassName != nil && *ingress.Spec.IngressClassName != "" {
+		return *ingress.Spec.IngressClassName
+	}
+	if className, ok := ingress.Annotations[ingressClassAnnotationKey]; ok {
+		return className
+	}
+	return defaultClassName
+}
+
+// defaultIngressClassName returns the name of the IngressClass marked as default, none if several of them are marked,
+// as the ingresses without class aren't admitted then.
+func defaultIngressClassName(classes []*networkingv1.IngressClass) string {
+	defaultClassName := ""
+	for _, class := range classes {
+		if class.Annotations[defaultIngressClassAnnotationKey] != "true" {
+			continue
+		}
+		if defaultClassName != "" {
+			return ""
+		}
+		defaultClassName = class.Name
+	}
+	return defaultClassName
+}
+
+// convertObject converts an ingress or an ingress class of a previous API to the networking.k8s.io/v1 API.
+// The fields used by the source are the same, the backends of the ingresses, which were renamed, are left out.
+func convertObject(previous runtime.Object, object interface{}) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(previous)
+	if err != nil {
+		return err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, object); err != nil {
+		u := &unstructured.Unstructured{Object: content}
+		return fmt.Errorf("failed to convert %s/%s: %v", u.GetNamespace(), u.GetName(), err)
+	}
+	return nil
+}
+
+// servesResource returns whether the cluster serves the resource in the given API group version.
+func servesResource(client kubernetes.Interface, groupVersion, resource string) bool {
+	resources, err := client.Discovery().ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		log.Debugf("Failed to discover the resources of %s: %v", groupVersion, err)
+		return false
+	}
+	if resources == nil {
+		return false
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == resource {
+			return true
+		}
+	}
+	return false
+}
+
 func (sc *ingressSource) AddEventHandler(ctx context.Context, handler func()) {
 	log.Debug("Adding event handler for ingress")
 
+	sc.namespaceFilter.AddEventHandler(handler)
+
+	var informer cache.SharedIndexInformer
+	switch {
+	case sc.ingressInformer != nil:
+		informer = sc.ingressInformer.Informer()
+	case sc.betaIngressInformer != nil:
+		informer = sc.betaIngressInformer.Informer()
+	default:
+		informer = sc.legacyIngressInformer.Informer()
+	}
+
 	// Right now there is no way to remove event handler from informer, see:
 	// https://github.com/kubernetes/kubernetes/issues/79610
-	sc.ingressInformer.Informer().AddEventHandler(
+	informer.AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				handler()
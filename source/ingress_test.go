@@ -25,8 +25,12 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/api/extensions/v1beta1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"sigs.k8s.io/external-dns/endpoint"
@@ -38,20 +42,23 @@ var _ Source = &ingressSource{}
 type IngressSuite struct {
 	suite.Suite
 	sc             Source
-	fooWithTargets *v1beta1.Ingress
+	fooWithTargets *networkingv1.Ingress
 }
 
 func (suite *IngressSuite) SetupTest() {
-	fakeClient := fake.NewSimpleClientset()
+	fakeClient := newIngressFakeClient()
 	var err error
 
 	suite.sc, err = NewIngressSource(
 		fakeClient,
+		nil,
 		"",
 		"",
 		"{{.Name}}",
 		false,
 		false,
+		nil,
+		"",
 	)
 	suite.NoError(err, "should initialize ingress source")
 
@@ -63,7 +70,7 @@ func (suite *IngressSuite) SetupTest() {
 		hostnames:   []string{"v1"},
 		annotations: map[string]string{ALBDualstackAnnotationKey: ALBDualstackAnnotationValue},
 	}).Ingress()
-	_, err = fakeClient.ExtensionsV1beta1().Ingresses(suite.fooWithTargets.Namespace).Create(context.Background(), suite.fooWithTargets, metav1.CreateOptions{})
+	_, err = fakeClient.NetworkingV1().Ingresses(suite.fooWithTargets.Namespace).Create(context.Background(), suite.fooWithTargets, metav1.CreateOptions{})
 	suite.NoError(err, "should succeed")
 }
 
@@ -128,12 +135,15 @@ func TestNewIngressSource(t *testing.T) {
 	} {
 		t.Run(ti.title, func(t *testing.T) {
 			_, err := NewIngressSource(
-				fake.NewSimpleClientset(),
+				newIngressFakeClient(),
+				nil,
 				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
 				ti.combineFQDNAndAnnotation,
 				false,
+				nil,
+				"",
 			)
 			if ti.expectError {
 				assert.Error(t, err)
@@ -995,22 +1005,25 @@ func testIngressEndpoints(t *testing.T) {
 		},
 	} {
 		t.Run(ti.title, func(t *testing.T) {
-			ingresses := make([]*v1beta1.Ingress, 0)
+			ingresses := make([]*networkingv1.Ingress, 0)
 			for _, item := range ti.ingressItems {
 				ingresses = append(ingresses, item.Ingress())
 			}
 
-			fakeClient := fake.NewSimpleClientset()
+			fakeClient := newIngressFakeClient()
 			source, _ := NewIngressSource(
 				fakeClient,
-				ti.targetNamespace,
+				[]string{ti.targetNamespace},
+				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
 				ti.combineFQDNAndAnnotation,
 				ti.ignoreHostnameAnnotation,
+				nil,
+				"",
 			)
 			for _, ingress := range ingresses {
-				_, err := fakeClient.ExtensionsV1beta1().Ingresses(ingress.Namespace).Create(context.Background(), ingress, metav1.CreateOptions{})
+				_, err := fakeClient.NetworkingV1().Ingresses(ingress.Namespace).Create(context.Background(), ingress, metav1.CreateOptions{})
 				require.NoError(t, err)
 			}
 
@@ -1059,31 +1072,35 @@ type fakeIngress struct {
 	namespace   string
 	name        string
 	annotations map[string]string
+	className   string
 }
 
-func (ing fakeIngress) Ingress() *v1beta1.Ingress {
-	ingress := &v1beta1.Ingress{
+func (ing fakeIngress) Ingress() *networkingv1.Ingress {
+	ingress := &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace:   ing.namespace,
 			Name:        ing.name,
 			Annotations: ing.annotations,
 		},
-		Spec: v1beta1.IngressSpec{
-			Rules: []v1beta1.IngressRule{},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{},
 		},
-		Status: v1beta1.IngressStatus{
+		Status: networkingv1.IngressStatus{
 			LoadBalancer: v1.LoadBalancerStatus{
 				Ingress: []v1.LoadBalancerIngress{},
 			},
 		},
 	}
+	if ing.className != "" {
+		ingress.Spec.IngressClassName = &ing.className
+	}
 	for _, dnsname := range ing.dnsnames {
-		ingress.Spec.Rules = append(ingress.Spec.Rules, v1beta1.IngressRule{
+		ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{
 			Host: dnsname,
 		})
 	}
 	for _, hosts := range ing.tlsdnsnames {
-		ingress.Spec.TLS = append(ingress.Spec.TLS, v1beta1.IngressTLS{
+		ingress.Spec.TLS = append(ingress.Spec.TLS, networkingv1.IngressTLS{
 			Hosts: hosts,
 		})
 	}
@@ -1099,3 +1116,195 @@ func (ing fakeIngress) Ingress() *v1beta1.Ingress {
 	}
 	return ingress
 }
+
+// newIngressFakeClient returns a fake client serving the ingresses and ingress classes of the networking API.
+func newIngressFakeClient() *fake.Clientset {
+	fakeClient := fake.NewSimpleClientset()
+	fakeClient.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: networkingGroupVersion,
+			APIResources: []metav1.APIResource{{Name: "ingresses", Namespaced: true}, {Name: "ingressclasses"}},
+		},
+	}
+	return fakeClient
+}
+
+func TestIngressClassFilter(t *testing.T) {
+	defaultClass := &networkingv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "nginx",
+			Annotations: map[string]string{defaultIngressClassAnnotationKey: "true"},
+		},
+	}
+	otherClass := &networkingv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "internal"},
+	}
+
+	for _, ti := range []struct {
+		title             string
+		ingressClassNames []string
+		ingressClasses    []*networkingv1.IngressClass
+		expected          []string
+	}{
+		{
+			title:    "no class names",
+			expected: []string{"class-name.org", "annotation.org", "both.org", "none.org"},
+		},
+		{
+			title:             "class name and annotation",
+			ingressClassNames: []string{"internal"},
+			ingressClasses:    []*networkingv1.IngressClass{defaultClass, otherClass},
+			expected:          []string{"class-name.org", "annotation.org"},
+		},
+		{
+			title:             "default class",
+			ingressClassNames: []string{"nginx"},
+			ingressClasses:    []*networkingv1.IngressClass{defaultClass, otherClass},
+			expected:          []string{"both.org", "none.org"},
+		},
+		{
+			title:             "no default class",
+			ingressClassNames: []string{"nginx"},
+			ingressClasses:    []*networkingv1.IngressClass{otherClass},
+			expected:          []string{"both.org"},
+		},
+		{
+			title:             "several classes",
+			ingressClassNames: []string{"nginx", "internal"},
+			expected:          []string{"class-name.org", "annotation.org", "both.org"},
+		},
+	} {
+		t.Run(ti.title, func(t *testing.T) {
+			fakeClient := newIngressFakeClient()
+			for _, class := range ti.ingressClasses {
+				_, err := fakeClient.NetworkingV1().IngressClasses().Create(context.Background(), class, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+			for _, ing := range []fakeIngress{
+				{name: "class-name", namespace: "default", dnsnames: []string{"class-name.org"}, className: "internal"},
+				{name: "annotation", namespace: "default", dnsnames: []string{"annotation.org"}, annotations: map[string]string{ingressClassAnnotationKey: "internal"}},
+				{name: "both", namespace: "default", dnsnames: []string{"both.org"}, className: "nginx", annotations: map[string]string{ingressClassAnnotationKey: "internal"}},
+				{name: "none", namespace: "default", dnsnames: []string{"none.org"}},
+			} {
+				ingress := ing.Ingress()
+				ingress.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.2.3.4"}}
+				_, err := fakeClient.NetworkingV1().Ingresses(ingress.Namespace).Create(context.Background(), ingress, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			source, err := NewIngressSource(fakeClient, nil, "", "", "", false, false, ti.ingressClassNames, "")
+			require.NoError(t, err)
+
+			endpoints, err := source.Endpoints(context.Background())
+			require.NoError(t, err)
+			hostnames := []string{}
+			for _, ep := range endpoints {
+				hostnames = append(hostnames, ep.DNSName)
+			}
+			assert.ElementsMatch(t, ti.expected, hostnames)
+		})
+	}
+}
+
+func TestIngressSourceBetaAPI(t *testing.T) {
+	// the fake client only serves the beta networking API
+	fakeClient := fake.NewSimpleClientset()
+	fakeClient.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: networkingBetaGroupVersion,
+			APIResources: []metav1.APIResource{{Name: "ingresses", Namespaced: true}, {Name: "ingressclasses"}},
+		},
+	}
+	_, err := fakeClient.NetworkingV1beta1().IngressClasses().Create(context.Background(), &networkingv1beta1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Annotations: map[string]string{defaultIngressClassAnnotationKey: "true"}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	className := "internal"
+	for _, ingress := range []*networkingv1beta1.Ingress{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "internal"},
+			Spec: networkingv1beta1.IngressSpec{
+				IngressClassName: &className,
+				Rules:            []networkingv1beta1.IngressRule{{Host: "internal.org"}},
+			},
+			Status: networkingv1beta1.IngressStatus{
+				LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "1.2.3.4"}}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx"},
+			Spec: networkingv1beta1.IngressSpec{
+				Backend: &networkingv1beta1.IngressBackend{ServiceName: "nginx", ServicePort: intstr.FromInt(80)},
+				Rules:   []networkingv1beta1.IngressRule{{Host: "nginx.org"}},
+				TLS:     []networkingv1beta1.IngressTLS{{Hosts: []string{"tls.nginx.org"}}},
+			},
+			Status: networkingv1beta1.IngressStatus{
+				LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{Hostname: "lb.com"}}},
+			},
+		},
+	} {
+		_, err := fakeClient.NetworkingV1beta1().Ingresses(ingress.Namespace).Create(context.Background(), ingress, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	// the ingresses without class are selected by the default class
+	source, err := NewIngressSource(fakeClient, nil, "", "", "", false, false, []string{"nginx"}, "")
+	require.NoError(t, err)
+	assert.Nil(t, source.(*ingressSource).ingressInformer)
+	assert.Nil(t, source.(*ingressSource).ingressClassInformer)
+
+	endpoints, err := source.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "nginx.org", Targets: endpoint.Targets{"lb.com"}},
+		{DNSName: "tls.nginx.org", Targets: endpoint.Targets{"lb.com"}},
+	})
+	for _, ep := range endpoints {
+		assert.Equal(t, "ingress/default/nginx", ep.Labels[endpoint.ResourceLabelKey])
+	}
+}
+
+func TestIngressSourceLegacyAPI(t *testing.T) {
+	// the fake client doesn't serve the networking API
+	fakeClient := fake.NewSimpleClientset()
+	className := "internal"
+	for _, ingress := range []*extensionsv1beta1.Ingress{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "internal"},
+			Spec: extensionsv1beta1.IngressSpec{
+				IngressClassName: &className,
+				Rules:            []extensionsv1beta1.IngressRule{{Host: "internal.org"}},
+				TLS:              []extensionsv1beta1.IngressTLS{{Hosts: []string{"tls.internal.org"}}},
+			},
+			Status: extensionsv1beta1.IngressStatus{
+				LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "1.2.3.4"}}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx", Annotations: map[string]string{ingressClassAnnotationKey: "nginx"}},
+			Spec: extensionsv1beta1.IngressSpec{
+				Rules: []extensionsv1beta1.IngressRule{{Host: "nginx.org"}},
+			},
+			Status: extensionsv1beta1.IngressStatus{
+				LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{Hostname: "lb.com"}}},
+			},
+		},
+	} {
+		_, err := fakeClient.ExtensionsV1beta1().Ingresses(ingress.Namespace).Create(context.Background(), ingress, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	source, err := NewIngressSource(fakeClient, nil, "", "", "", false, false, []string{"internal"}, "")
+	require.NoError(t, err)
+	assert.Nil(t, source.(*ingressSource).ingressInformer)
+
+	endpoints, err := source.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "internal.org", Targets: endpoint.Targets{"1.2.3.4"}},
+		{DNSName: "tls.internal.org", Targets: endpoint.Targets{"1.2.3.4"}},
+	})
+	for _, ep := range endpoints {
+		assert.Equal(t, "ingress/default/internal", ep.Labels[endpoint.ResourceLabelKey])
+	}
+}
@@ -51,6 +51,7 @@ type ingressRouteSource struct {
 	kubeClient                 kubernetes.Interface
 	contourLoadBalancerService string
 	namespace                  string
+	namespaceFilter            *namespaceFilter
 	annotationFilter           string
 	fqdnTemplate               *template.Template
 	combineFQDNAnnotation      bool
@@ -64,11 +65,13 @@ func NewContourIngressRouteSource(
 	dynamicKubeClient dynamic.Interface,
 	kubeClient kubernetes.Interface,
 	contourLoadBalancerService string,
-	namespace string,
+	namespaces []string,
+	namespaceSelector string,
 	annotationFilter string,
 	fqdnTemplate string,
 	combineFqdnAnnotation bool,
 	ignoreHostnameAnnotation bool,
+	labelFilter string,
 ) (Source, error) {
 	var (
 		tmpl *template.Template
@@ -87,9 +90,20 @@ func NewContourIngressRouteSource(
 		return nil, err
 	}
 
+	labelFilterOptions, err := labelFilterListOptions(labelFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceFilter, err := newNamespaceFilter(kubeClient, namespaces, namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	namespace := namespaceFilter.informerNamespace()
+
 	// Use shared informer to listen for add/update/delete of ingressroutes in the specified namespace.
 	// Set resync period to 0, to prevent processing when nothing has changed.
-	informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicKubeClient, 0, namespace, nil)
+	informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicKubeClient, 0, namespace, labelFilterOptions)
 	ingressRouteInformer := informerFactory.ForResource(contourapi.IngressRouteGVR)
 
 	// Add default resource event handlers to properly initialize informer.
@@ -121,6 +135,7 @@ func NewContourIngressRouteSource(
 		kubeClient:                 kubeClient,
 		contourLoadBalancerService: contourLoadBalancerService,
 		namespace:                  namespace,
+		namespaceFilter:            namespaceFilter,
 		annotationFilter:           annotationFilter,
 		fqdnTemplate:               tmpl,
 		combineFQDNAnnotation:      combineFqdnAnnotation,
@@ -154,10 +169,17 @@ func (sc *ingressRouteSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoi
 		ingressRoutes = append(ingressRoutes, irConverted)
 	}
 
-	ingressRoutes, err = sc.filterByAnnotations(ingressRoutes)
+	matchesAnnotations, err := annotationFilterFunc(sc.annotationFilter)
 	if err != nil {
 		return nil, err
 	}
+	filtered := ingressRoutes[:0]
+	for _, ir := range ingressRoutes {
+		if sc.namespaceFilter.matches(ir.Namespace) && matchesAnnotations(ir.Annotations) {
+			filtered = append(filtered, ir)
+		}
+	}
+	ingressRoutes = filtered
 
 	endpoints := []*endpoint.Endpoint{}
 
@@ -245,41 +267,12 @@ func (sc *ingressRouteSource) endpointsFromTemplate(ctx context.Context, ingress
 	return endpoints, nil
 }
 
-// filterByAnnotations filters a list of configs by a given annotation selector.
-func (sc *ingressRouteSource) filterByAnnotations(ingressRoutes []*contourapi.IngressRoute) ([]*contourapi.IngressRoute, error) {
-	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
-	if err != nil {
-		return nil, err
-	}
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
-	if err != nil {
-		return nil, err
-	}
-
-	// empty filter returns original list
-	if selector.Empty() {
-		return ingressRoutes, nil
-	}
-
-	filteredList := []*contourapi.IngressRoute{}
-
-	for _, ingressRoute := range ingressRoutes {
-		// convert the ingressroute's annotations to an equivalent label selector
-		annotations := labels.Set(ingressRoute.Annotations)
-
-		// include ingressroute if its annotations match the selector
-		if selector.Matches(annotations) {
-			filteredList = append(filteredList, ingressRoute)
-		}
-	}
-
-	return filteredList, nil
-}
-
 func (sc *ingressRouteSource) setResourceLabel(ingressRoute *contourapi.IngressRoute, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("ingressroute/%s/%s", ingressRoute.Namespace, ingressRoute.Name)
 	}
+	setPriorityLabel(ingressRoute.Annotations, endpoints)
+	setClaimLabel(ingressRoute.Annotations, endpoints)
 }
 
 func (sc *ingressRouteSource) targetsFromContourLoadBalancer(ctx context.Context) (targets endpoint.Targets, err error) {
@@ -357,6 +350,7 @@ func parseContourLoadBalancerService(service string) (namespace, name string, er
 }
 
 func (sc *ingressRouteSource) AddEventHandler(ctx context.Context, handler func()) {
+	sc.namespaceFilter.AddEventHandler(handler)
 }
 
 // UnstructuredConverter handles conversions between unstructured.Unstructured and Contour types
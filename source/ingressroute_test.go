@@ -65,11 +65,13 @@ func (suite *IngressRouteSuite) SetupTest() {
 		fakeDynamicClient,
 		fakeKubernetesClient,
 		"heptio-contour/contour",
-		"default",
+		[]string{"default"},
+		"",
 		"",
 		"{{.Name}}",
 		false,
 		false,
+		"",
 	)
 	suite.NoError(err, "should initialize ingressroute source")
 
@@ -162,11 +164,13 @@ func TestNewContourIngressRouteSource(t *testing.T) {
 				fakeDynamicClient,
 				fakeKube.NewSimpleClientset(),
 				"heptio-contour/contour",
+				nil,
 				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
 				ti.combineFQDNAndAnnotation,
 				false,
+				"",
 			)
 			if ti.expectError {
 				assert.Error(t, err)
@@ -1023,11 +1027,13 @@ func testIngressRouteEndpoints(t *testing.T) {
 				fakeDynamicClient,
 				fakeKubernetesClient,
 				lbService.Namespace+"/"+lbService.Name,
-				ti.targetNamespace,
+				[]string{ti.targetNamespace},
+				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
 				ti.combineFQDNAndAnnotation,
 				ti.ignoreHostnameAnnotation,
+				"",
 			)
 			require.NoError(t, err)
 
@@ -1058,11 +1064,13 @@ func newTestIngressRouteSource(loadBalancer fakeLoadBalancerService) (*ingressRo
 		fakeDynamicClient,
 		fakeKubernetesClient,
 		lbService.Namespace+"/"+lbService.Name,
-		"default",
+		[]string{"default"},
+		"",
 		"",
 		"{{.Name}}",
 		false,
 		false,
+		"",
 	)
 	if err != nil {
 		return nil, err
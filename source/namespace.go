@@ -0,0 +1,176 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceFilter selects the namespaces whose objects are processed by a source, by their names
+// and by a label selector. The namespaces have to match both when both are given, and all the
+// namespaces are selected when none is, as they are by a nil namespaceFilter.
+type namespaceFilter struct {
+	names             map[string]struct{}
+	selector          labels.Selector
+	namespaceInformer coreinformers.NamespaceInformer
+}
+
+var (
+	// the namespace informers shared by the sources, by the Kubernetes client they use
+	namespaceInformersLock sync.Mutex
+	namespaceInformers     = map[kubernetes.Interface]coreinformers.NamespaceInformer{}
+)
+
+// sharedNamespaceInformer returns the informer of the namespaces shared by all the sources using the given client,
+// like the sources built with the same ClientGenerator, so that the namespaces are only watched once.
+// The informer is started, and its cache populated.
+func sharedNamespaceInformer(kubeClient kubernetes.Interface) (coreinformers.NamespaceInformer, error) {
+	namespaceInformersLock.Lock()
+	namespaceInformer, ok := namespaceInformers[kubeClient]
+	if !ok {
+		// Use shared informer to listen for add/update/delete of namespaces.
+		// Set resync period to 0, to prevent processing when nothing has changed.
+		informerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+		namespaceInformer = informerFactory.Core().V1().Namespaces()
+
+		// Add default resource event handler to properly initialize informer.
+		namespaceInformer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+
+		// TODO informer is not explicitly stopped since controller is not passing in its channel.
+		informerFactory.Start(wait.NeverStop)
+		namespaceInformers[kubeClient] = namespaceInformer
+	}
+	namespaceInformersLock.Unlock()
+
+	// wait for the local cache to be populated.
+	err := poll(time.Second, 60*time.Second, func() (bool, error) {
+		return namespaceInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+	return namespaceInformer, nil
+}
+
+// newNamespaceFilter creates a new namespaceFilter for the given namespace names and label selector.
+// Empty names are ignored, so that the empty namespace keeps selecting all the namespaces.
+// The labels of the namespaces are watched when a selector is given, so that the namespaces created
+// or relabeled at runtime are selected without restarting.
+func newNamespaceFilter(kubeClient kubernetes.Interface, namespaces []string, namespaceSelector string) (*namespaceFilter, error) {
+	nf := &namespaceFilter{
+		names: make(map[string]struct{}),
+	}
+	for _, namespace := range namespaces {
+		if namespace != v1.NamespaceAll {
+			nf.names[namespace] = struct{}{}
+		}
+	}
+
+	if namespaceSelector == "" {
+		return nf, nil
+	}
+
+	selector, err := labels.Parse(namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	nf.selector = selector
+
+	nf.namespaceInformer, err = sharedNamespaceInformer(kubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return nf, nil
+}
+
+// informerNamespace returns the namespace to be watched by the informers of the source. It's the only
+// selected namespace, or all the namespaces when several namespaces or a selector are given, in which
+// case the objects are filtered by their namespace afterwards.
+func (nf *namespaceFilter) informerNamespace() string {
+	if len(nf.names) == 1 && nf.selector == nil {
+		for namespace := range nf.names {
+			return namespace
+		}
+	}
+	return v1.NamespaceAll
+}
+
+// matches returns whether the namespace with the given name is selected.
+func (nf *namespaceFilter) matches(namespace string) bool {
+	if nf == nil {
+		return true
+	}
+	if len(nf.names) > 0 {
+		if _, ok := nf.names[namespace]; !ok {
+			return false
+		}
+	}
+	if nf.selector == nil {
+		return true
+	}
+
+	ns, err := nf.namespaceInformer.Lister().Get(namespace)
+	if err != nil {
+		// the namespace is being deleted, or isn't known to the informer yet
+		log.Debugf("Skipping namespace %s: %v", namespace, err)
+		return false
+	}
+	return nf.selector.Matches(labels.Set(ns.Labels))
+}
+
+// AddEventHandler adds an event handler that should be triggered if the labels of the namespaces change,
+// so that the objects of the namespaces starting or ceasing to match the selector are processed.
+func (nf *namespaceFilter) AddEventHandler(handler func()) {
+	if nf == nil || nf.namespaceInformer == nil {
+		return
+	}
+
+	// Right now there is no way to remove event handler from informer, see:
+	// https://github.com/kubernetes/kubernetes/issues/79610
+	nf.namespaceInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				handler()
+			},
+			UpdateFunc: func(old interface{}, new interface{}) {
+				if !labels.Equals(old.(*v1.Namespace).Labels, new.(*v1.Namespace).Labels) {
+					handler()
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				handler()
+			},
+		},
+	)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func fakeNamespace(name string, labels map[string]string) *v1.Namespace {
+	return &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+func TestNamespaceFilter(t *testing.T) {
+	namespaces := []*v1.Namespace{
+		fakeNamespace("team-a", map[string]string{"team": "dns"}),
+		fakeNamespace("team-b", map[string]string{"team": "dns", "environment": "test"}),
+		fakeNamespace("team-c", map[string]string{"team": "web"}),
+		fakeNamespace("default", nil),
+	}
+
+	for _, tc := range []struct {
+		title             string
+		namespaces        []string
+		namespaceSelector string
+		informerNamespace string
+		expected          []string
+		expectError       bool
+	}{
+		{
+			title:             "no namespace selects all the namespaces",
+			informerNamespace: "",
+			expected:          []string{"team-a", "team-b", "team-c", "default"},
+		},
+		{
+			title:             "empty namespace selects all the namespaces",
+			namespaces:        []string{""},
+			informerNamespace: "",
+			expected:          []string{"team-a", "team-b", "team-c", "default"},
+		},
+		{
+			title:             "single namespace is watched by the informers",
+			namespaces:        []string{"team-a"},
+			informerNamespace: "team-a",
+			expected:          []string{"team-a"},
+		},
+		{
+			title:             "several namespaces",
+			namespaces:        []string{"team-a", "default"},
+			informerNamespace: "",
+			expected:          []string{"team-a", "default"},
+		},
+		{
+			title:             "namespace selector",
+			namespaceSelector: "team=dns,environment!=test",
+			informerNamespace: "",
+			expected:          []string{"team-a"},
+		},
+		{
+			title:             "namespaces and namespace selector",
+			namespaces:        []string{"team-b", "team-c"},
+			namespaceSelector: "team=dns",
+			informerNamespace: "",
+			expected:          []string{"team-b"},
+		},
+		{
+			title:             "invalid namespace selector",
+			namespaceSelector: "team in dns",
+			expectError:       true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset()
+			for _, namespace := range namespaces {
+				_, err := kubeClient.CoreV1().Namespaces().Create(context.Background(), namespace, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			nf, err := newNamespaceFilter(kubeClient, tc.namespaces, tc.namespaceSelector)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.informerNamespace, nf.informerNamespace())
+
+			names := []string{}
+			for _, name := range []string{"team-a", "team-b", "team-c", "default"} {
+				if nf.matches(name) {
+					names = append(names, name)
+				}
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestNilNamespaceFilter(t *testing.T) {
+	var nf *namespaceFilter
+	assert.True(t, nf.matches("default"))
+	nf.AddEventHandler(func() {})
+}
+
+func TestNamespaceFilterRuntimeChanges(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(fakeNamespace("team-a", map[string]string{"team": "dns"}))
+
+	nf, err := newNamespaceFilter(kubeClient, nil, "team=dns")
+	require.NoError(t, err)
+
+	handled := make(chan struct{}, 10)
+	nf.AddEventHandler(func() { handled <- struct{}{} })
+	// the handler is triggered for the namespaces known to the informer when it's added
+	<-handled
+
+	assert.True(t, nf.matches("team-a"))
+	assert.False(t, nf.matches("team-b"))
+
+	// a namespace created with matching labels is selected
+	_, err = kubeClient.CoreV1().Namespaces().Create(context.Background(), fakeNamespace("team-b", map[string]string{"team": "dns"}), metav1.CreateOptions{})
+	require.NoError(t, err)
+	waitForHandler(t, handled)
+	assert.True(t, nf.matches("team-b"))
+
+	// a namespace relabeled not to match is no longer selected
+	_, err = kubeClient.CoreV1().Namespaces().Update(context.Background(), fakeNamespace("team-a", map[string]string{"team": "web"}), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	waitForHandler(t, handled)
+	assert.False(t, nf.matches("team-a"))
+
+	// a deleted namespace is no longer selected
+	err = kubeClient.CoreV1().Namespaces().Delete(context.Background(), "team-b", metav1.DeleteOptions{})
+	require.NoError(t, err)
+	waitForHandler(t, handled)
+	assert.False(t, nf.matches("team-b"))
+}
+
+func TestSharedNamespaceInformer(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(fakeNamespace("team-a", map[string]string{"team": "api"}))
+
+	// the sources using the same client share the informer of the namespaces
+	first, err := newNamespaceFilter(kubeClient, nil, "team=api")
+	require.NoError(t, err)
+	second, err := newNamespaceFilter(kubeClient, []string{"team-a"}, "team")
+	require.NoError(t, err)
+	assert.Same(t, first.namespaceInformer, second.namespaceInformer)
+	assert.True(t, second.matches("team-a"))
+
+	other, err := newNamespaceFilter(fake.NewSimpleClientset(), nil, "team=api")
+	require.NoError(t, err)
+	assert.NotSame(t, first.namespaceInformer, other.namespaceInformer)
+}
+
+func waitForHandler(t *testing.T, handled <-chan struct{}) {
+	select {
+	case <-handled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("event handler wasn't triggered")
+	}
+}
@@ -20,13 +20,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"text/template"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
@@ -45,7 +45,7 @@ type nodeSource struct {
 }
 
 // NewNodeSource creates a new nodeSource with the given config.
-func NewNodeSource(kubeClient kubernetes.Interface, annotationFilter, fqdnTemplate string) (Source, error) {
+func NewNodeSource(kubeClient kubernetes.Interface, annotationFilter, fqdnTemplate, labelFilter string) (Source, error) {
 	var (
 		tmpl *template.Template
 		err  error
@@ -60,9 +60,14 @@ func NewNodeSource(kubeClient kubernetes.Interface, annotationFilter, fqdnTempla
 		}
 	}
 
+	labelFilterOptions, err := labelFilterListOptions(labelFilter)
+	if err != nil {
+		return nil, err
+	}
+
 	// Use shared informers to listen for add/update/delete of nodes.
 	// Set resync period to 0, to prevent processing when nothing has changed
-	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0)
+	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithTweakListOptions(labelFilterOptions))
 	nodeInformer := informerFactory.Core().V1().Nodes()
 
 	// Add default resource event handler to properly initialize informer.
@@ -100,10 +105,17 @@ func (ns *nodeSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, erro
 		return nil, err
 	}
 
-	nodes, err = ns.filterByAnnotations(nodes)
+	matchesAnnotations, err := annotationFilterFunc(ns.annotationFilter)
 	if err != nil {
 		return nil, err
 	}
+	filtered := nodes[:0]
+	for _, node := range nodes {
+		if matchesAnnotations(node.Annotations) {
+			filtered = append(filtered, node)
+		}
+	}
+	nodes = filtered
 
 	endpoints := map[string]*endpoint.Endpoint{}
 
@@ -124,12 +136,7 @@ func (ns *nodeSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, erro
 			log.Warn(err)
 		}
 
-		// create new endpoint with the information we already have
-		ep := &endpoint.Endpoint{
-			RecordType: "A", // hardcoded DNS record type
-			RecordTTL:  ttl,
-		}
-
+		var dnsName string
 		if ns.fqdnTemplate != nil {
 			// Process the whole template string
 			var buf bytes.Buffer
@@ -138,10 +145,10 @@ func (ns *nodeSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, erro
 				return nil, fmt.Errorf("failed to apply template on node %s: %v", node.Name, err)
 			}
 
-			ep.DNSName = buf.String()
-			log.Debugf("applied template for %s, converting to %s", node.Name, ep.DNSName)
+			dnsName = buf.String()
+			log.Debugf("applied template for %s, converting to %s", node.Name, dnsName)
 		} else {
-			ep.DNSName = node.Name
+			dnsName = node.Name
 			log.Debugf("not applying template for %s", node.Name)
 		}
 
@@ -150,13 +157,22 @@ func (ns *nodeSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, erro
 			return nil, fmt.Errorf("failed to get node address from %s: %s", node.Name, err.Error())
 		}
 
-		ep.Targets = endpoint.Targets(addrs)
-
-		log.Debugf("adding endpoint %s", ep)
-		if _, ok := endpoints[ep.DNSName]; ok {
-			endpoints[ep.DNSName].Targets = append(endpoints[ep.DNSName].Targets, ep.Targets...)
-		} else {
-			endpoints[ep.DNSName] = ep
+		// create an A endpoint for the IPv4 and an AAAA endpoint for the IPv6 addresses of the node
+		for _, addr := range addrs {
+			recordType := suitableType(addr)
+			key := dnsName + "::" + recordType
+			if ep, ok := endpoints[key]; ok {
+				ep.Targets = append(ep.Targets, addr)
+				continue
+			}
+			ep := &endpoint.Endpoint{
+				DNSName:    dnsName,
+				RecordType: recordType,
+				RecordTTL:  ttl,
+				Targets:    endpoint.Targets{addr},
+			}
+			log.Debugf("adding endpoint %s", ep)
+			endpoints[key] = ep
 		}
 	}
 
@@ -169,6 +185,34 @@ func (ns *nodeSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, erro
 }
 
 func (ns *nodeSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for node")
+
+	// Right now there is no way to remove event handler from informer, see:
+	// https://github.com/kubernetes/kubernetes/issues/79610
+	ns.nodeInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				handler()
+			},
+			UpdateFunc: func(old interface{}, new interface{}) {
+				// the status of the nodes is updated periodically by the kubelets
+				if nodeChanged(old.(*v1.Node), new.(*v1.Node)) {
+					handler()
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				handler()
+			},
+		},
+	)
+}
+
+// nodeChanged returns whether the node changed in a way which may change its endpoints:
+// its addresses, or the labels and annotations used by the filters and templates.
+func nodeChanged(old, new *v1.Node) bool {
+	return !reflect.DeepEqual(old.Status.Addresses, new.Status.Addresses) ||
+		!labels.Equals(old.Labels, new.Labels) ||
+		!labels.Equals(old.Annotations, new.Annotations)
 }
 
 // nodeAddress returns node's externalIP and if that's not found, node's internalIP
@@ -193,34 +237,3 @@ func (ns *nodeSource) nodeAddresses(node *v1.Node) ([]string, error) {
 
 	return nil, fmt.Errorf("could not find node address for %s", node.Name)
 }
-
-// filterByAnnotations filters a list of nodes by a given annotation selector.
-func (ns *nodeSource) filterByAnnotations(nodes []*v1.Node) ([]*v1.Node, error) {
-	labelSelector, err := metav1.ParseToLabelSelector(ns.annotationFilter)
-	if err != nil {
-		return nil, err
-	}
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
-	if err != nil {
-		return nil, err
-	}
-
-	// empty filter returns original list
-	if selector.Empty() {
-		return nodes, nil
-	}
-
-	filteredList := []*v1.Node{}
-
-	for _, node := range nodes {
-		// convert the node's annotations to an equivalent label selector
-		annotations := labels.Set(node.Annotations)
-
-		// include node if its annotations match the selector
-		if selector.Matches(annotations) {
-			filteredList = append(filteredList, node)
-		}
-	}
-
-	return filteredList, nil
-}
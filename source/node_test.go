@@ -16,6 +16,8 @@ import (
 func TestNodeSource(t *testing.T) {
 	t.Run("NewNodeSource", testNodeSourceNewNodeSource)
 	t.Run("Endpoints", testNodeSourceEndpoints)
+	t.Run("LabelFilter", testNodeSourceLabelFilter)
+	t.Run("EventHandler", testNodeSourceEventHandler)
 }
 
 // testNodeSourceNewNodeSource tests that NewNodeService doesn't return an error.
@@ -24,6 +26,7 @@ func testNodeSourceNewNodeSource(t *testing.T) {
 		title            string
 		annotationFilter string
 		fqdnTemplate     string
+		labelFilter      string
 		expectError      bool
 	}{
 		{
@@ -45,12 +48,23 @@ func testNodeSourceNewNodeSource(t *testing.T) {
 			expectError:      false,
 			annotationFilter: "kubernetes.io/ingress.class=nginx",
 		},
+		{
+			title:       "non-empty label filter",
+			expectError: false,
+			labelFilter: "node-role.kubernetes.io/edge=true",
+		},
+		{
+			title:       "invalid label filter",
+			expectError: true,
+			labelFilter: "node-role.kubernetes.io/edge in true",
+		},
 	} {
 		t.Run(ti.title, func(t *testing.T) {
 			_, err := NewNodeSource(
 				fake.NewSimpleClientset(),
 				ti.annotationFilter,
 				ti.fqdnTemplate,
+				ti.labelFilter,
 			)
 
 			if ti.expectError {
@@ -140,6 +154,20 @@ func testNodeSourceEndpoints(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"dual-stack node returns an A and an AAAA endpoint",
+			"",
+			"",
+			"node1",
+			[]v1.NodeAddress{{Type: v1.NodeExternalIP, Address: "1.2.3.4"}, {Type: v1.NodeExternalIP, Address: "2001:db8::1"}},
+			map[string]string{},
+			map[string]string{},
+			[]*endpoint.Endpoint{
+				{RecordType: "A", DNSName: "node1", Targets: endpoint.Targets{"1.2.3.4"}},
+				{RecordType: "AAAA", DNSName: "node1", Targets: endpoint.Targets{"2001:db8::1"}},
+			},
+			false,
+		},
 		{
 			"node with both external and internal IP returns an endpoint with external IP",
 			"",
@@ -315,6 +343,7 @@ func testNodeSourceEndpoints(t *testing.T) {
 				kubernetes,
 				tc.annotationFilter,
 				tc.fqdnTemplate,
+				"",
 			)
 			require.NoError(t, err)
 
@@ -330,3 +359,79 @@ func testNodeSourceEndpoints(t *testing.T) {
 		})
 	}
 }
+
+// testNodeSourceLabelFilter tests that only the nodes matching the label filter generate endpoints.
+func testNodeSourceLabelFilter(t *testing.T) {
+	kubernetes := fake.NewSimpleClientset()
+
+	for _, node := range []*v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "edge1",
+				Labels: map[string]string{"node-role.kubernetes.io/edge": "true"},
+			},
+			Status: v1.NodeStatus{
+				Addresses: []v1.NodeAddress{{Type: v1.NodeExternalIP, Address: "1.2.3.4"}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "worker1",
+			},
+			Status: v1.NodeStatus{
+				Addresses: []v1.NodeAddress{{Type: v1.NodeExternalIP, Address: "2.3.4.5"}},
+			},
+		},
+	} {
+		_, err := kubernetes.CoreV1().Nodes().Create(context.Background(), node, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	client, err := NewNodeSource(kubernetes, "", "", "node-role.kubernetes.io/edge=true")
+	require.NoError(t, err)
+
+	endpoints, err := client.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{RecordType: "A", DNSName: "edge1", Targets: endpoint.Targets{"1.2.3.4"}},
+	})
+}
+
+// testNodeSourceEventHandler tests that the changes of the nodes trigger the event handler.
+func testNodeSourceEventHandler(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node1",
+		},
+		Status: v1.NodeStatus{
+			Addresses: []v1.NodeAddress{{Type: v1.NodeExternalIP, Address: "1.2.3.4"}},
+		},
+	}
+	kubernetes := fake.NewSimpleClientset(node)
+
+	client, err := NewNodeSource(kubernetes, "", "", "")
+	require.NoError(t, err)
+
+	handled := make(chan struct{}, 10)
+	client.AddEventHandler(context.Background(), func() { handled <- struct{}{} })
+	// the handler is triggered for the nodes known to the informer when it's added
+	waitForHandler(t, handled)
+
+	// an address change triggers the handler
+	node.Status.Addresses = []v1.NodeAddress{{Type: v1.NodeExternalIP, Address: "2.3.4.5"}}
+	_, err = kubernetes.CoreV1().Nodes().UpdateStatus(context.Background(), node, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	waitForHandler(t, handled)
+
+	// a status update keeping the addresses doesn't
+	node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+	_, err = kubernetes.CoreV1().Nodes().UpdateStatus(context.Background(), node, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	// a deletion triggers the handler, and is the only change handled since the address change
+	err = kubernetes.CoreV1().Nodes().Delete(context.Background(), node.Name, metav1.DeleteOptions{})
+	require.NoError(t, err)
+	waitForHandler(t, handled)
+	assert.Empty(t, handled)
+}
@@ -30,9 +30,9 @@ import (
 	extInformers "github.com/openshift/client-go/route/informers/externalversions"
 	routeInformer "github.com/openshift/client-go/route/informers/externalversions/route/v1"
 	log "github.com/sirupsen/logrus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
 	"sigs.k8s.io/external-dns/endpoint"
@@ -45,6 +45,7 @@ import (
 type ocpRouteSource struct {
 	client                   versioned.Interface
 	namespace                string
+	namespaceFilter          *namespaceFilter
 	annotationFilter         string
 	fqdnTemplate             *template.Template
 	combineFQDNAnnotation    bool
@@ -55,11 +56,14 @@ type ocpRouteSource struct {
 // NewOcpRouteSource creates a new ocpRouteSource with the given config.
 func NewOcpRouteSource(
 	ocpClient versioned.Interface,
-	namespace string,
+	kubeClient kubernetes.Interface,
+	namespaces []string,
+	namespaceSelector string,
 	annotationFilter string,
 	fqdnTemplate string,
 	combineFQDNAnnotation bool,
 	ignoreHostnameAnnotation bool,
+	labelFilter string,
 ) (Source, error) {
 	var (
 		tmpl *template.Template
@@ -74,9 +78,20 @@ func NewOcpRouteSource(
 		}
 	}
 
+	labelFilterOptions, err := labelFilterListOptions(labelFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceFilter, err := newNamespaceFilter(kubeClient, namespaces, namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	namespace := namespaceFilter.informerNamespace()
+
 	// Use shared informer to listen for add/update/delete of Routes in the specified namespace.
 	// Set resync period to 0, to prevent processing when nothing has changed.
-	informerFactory := extInformers.NewFilteredSharedInformerFactory(ocpClient, 0, namespace, nil)
+	informerFactory := extInformers.NewFilteredSharedInformerFactory(ocpClient, 0, namespace, labelFilterOptions)
 	routeInformer := informerFactory.Route().V1().Routes()
 
 	// Add default resource event handlers to properly initialize informer.
@@ -101,6 +116,7 @@ func NewOcpRouteSource(
 	return &ocpRouteSource{
 		client:                   ocpClient,
 		namespace:                namespace,
+		namespaceFilter:          namespaceFilter,
 		annotationFilter:         annotationFilter,
 		fqdnTemplate:             tmpl,
 		combineFQDNAnnotation:    combineFQDNAnnotation,
@@ -111,6 +127,7 @@ func NewOcpRouteSource(
 
 // TODO add a meaningful EventHandler
 func (ors *ocpRouteSource) AddEventHandler(ctx context.Context, handler func()) {
+	ors.namespaceFilter.AddEventHandler(handler)
 }
 
 // Endpoints returns endpoint objects for each host-target combination that should be processed.
@@ -120,11 +137,17 @@ func (ors *ocpRouteSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint,
 	if err != nil {
 		return nil, err
 	}
-
-	ocpRoutes, err = ors.filterByAnnotations(ocpRoutes)
+	matchesAnnotations, err := annotationFilterFunc(ors.annotationFilter)
 	if err != nil {
 		return nil, err
 	}
+	filtered := ocpRoutes[:0]
+	for _, ocpRoute := range ocpRoutes {
+		if ors.namespaceFilter.matches(ocpRoute.Namespace) && matchesAnnotations(ocpRoute.Annotations) {
+			filtered = append(filtered, ocpRoute)
+		}
+	}
+	ocpRoutes = filtered
 
 	endpoints := []*endpoint.Endpoint{}
 
@@ -203,40 +226,12 @@ func (ors *ocpRouteSource) endpointsFromTemplate(ocpRoute *routeapi.Route) ([]*e
 	return endpoints, nil
 }
 
-func (ors *ocpRouteSource) filterByAnnotations(ocpRoutes []*routeapi.Route) ([]*routeapi.Route, error) {
-	labelSelector, err := metav1.ParseToLabelSelector(ors.annotationFilter)
-	if err != nil {
-		return nil, err
-	}
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
-	if err != nil {
-		return nil, err
-	}
-
-	// empty filter returns original list
-	if selector.Empty() {
-		return ocpRoutes, nil
-	}
-
-	filteredList := []*routeapi.Route{}
-
-	for _, ocpRoute := range ocpRoutes {
-		// convert the Route's annotations to an equivalent label selector
-		annotations := labels.Set(ocpRoute.Annotations)
-
-		// include ocpRoute if its annotations match the selector
-		if selector.Matches(annotations) {
-			filteredList = append(filteredList, ocpRoute)
-		}
-	}
-
-	return filteredList, nil
-}
-
 func (ors *ocpRouteSource) setResourceLabel(ocpRoute *routeapi.Route, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("route/%s/%s", ocpRoute.Namespace, ocpRoute.Name)
 	}
+	setPriorityLabel(ocpRoute.Annotations, endpoints)
+	setClaimLabel(ocpRoute.Annotations, endpoints)
 }
 
 // endpointsFromOcpRoute extracts the endpoints from a OpenShift Route object
@@ -34,6 +34,8 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
@@ -49,11 +51,13 @@ type routeGroupSource struct {
 	cli                      routeGroupListClient
 	apiServer                string
 	namespace                string
+	namespaceFilter          *namespaceFilter
 	apiEndpoint              string
 	annotationFilter         string
 	fqdnTemplate             *template.Template
 	combineFQDNAnnotation    bool
 	ignoreHostnameAnnotation bool
+	pollInterval             time.Duration
 }
 
 // for testing
@@ -199,12 +203,22 @@ func parseTemplate(fqdnTemplate string) (tmpl *template.Template, err error) {
 }
 
 // NewRouteGroupSource creates a new routeGroupSource with the given config.
-func NewRouteGroupSource(timeout time.Duration, token, tokenPath, apiServerURL, namespace, annotationFilter, fqdnTemplate, routegroupVersion string, combineFqdnAnnotation, ignoreHostnameAnnotation bool) (Source, error) {
+func NewRouteGroupSource(kubeClient kubernetes.Interface, timeout time.Duration, token, tokenPath, apiServerURL string, namespaces []string, namespaceSelector, annotationFilter, fqdnTemplate, routegroupVersion string, combineFqdnAnnotation, ignoreHostnameAnnotation bool, labelFilter string, pollInterval time.Duration) (Source, error) {
 	tmpl, err := parseTemplate(fqdnTemplate)
 	if err != nil {
 		return nil, err
 	}
 
+	if _, err := labels.Parse(labelFilter); err != nil {
+		return nil, err
+	}
+
+	namespaceFilter, err := newNamespaceFilter(kubeClient, namespaces, namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	namespace := namespaceFilter.informerNamespace()
+
 	if routegroupVersion == "" {
 		routegroupVersion = DefaultRoutegroupVersion
 	}
@@ -225,22 +239,33 @@ func NewRouteGroupSource(timeout time.Duration, token, tokenPath, apiServerURL,
 		cli:                      cli,
 		apiServer:                apiServer,
 		namespace:                namespace,
+		namespaceFilter:          namespaceFilter,
 		apiEndpoint:              apiServer + fmt.Sprintf(routeGroupListResource, routegroupVersion),
 		annotationFilter:         annotationFilter,
 		fqdnTemplate:             tmpl,
 		combineFQDNAnnotation:    combineFqdnAnnotation,
 		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		pollInterval:             pollInterval,
 	}
 	if namespace != "" {
 		sc.apiEndpoint = apiServer + fmt.Sprintf(routeGroupNamespacedResource, routegroupVersion, namespace)
 	}
+	// the route groups are filtered by their labels by the API server
+	if labelFilter != "" {
+		sc.apiEndpoint += "?labelSelector=" + url.QueryEscape(labelFilter)
+	}
 
 	log.Infoln("Created route group source")
 	return sc, nil
 }
 
-// AddEventHandler for routegroup is currently a no op, because we do not implement caching, yet.
-func (sc *routeGroupSource) AddEventHandler(ctx context.Context, handler func()) {}
+// AddEventHandler for routegroup polls the routegroups for changes, because we do not implement caching, yet.
+func (sc *routeGroupSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for routegroup")
+
+	sc.namespaceFilter.AddEventHandler(handler)
+	pollForChanges(ctx, sc, sc.pollInterval, handler)
+}
 
 // Endpoints returns endpoint objects for each host-target combination that should be processed.
 // Retrieves all routeGroup resources on all namespaces.
@@ -251,10 +276,17 @@ func (sc *routeGroupSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint
 		log.Errorf("Failed to get RouteGroup list: %v", err)
 		return nil, err
 	}
-	rgList, err = sc.filterByAnnotations(rgList)
+	matchesAnnotations, err := annotationFilterFunc(sc.annotationFilter)
 	if err != nil {
 		return nil, err
 	}
+	filtered := rgList.Items[:0]
+	for _, rg := range rgList.Items {
+		if sc.namespaceFilter.matches(rg.Metadata.Namespace) && matchesAnnotations(rg.Metadata.Annotations) {
+			filtered = append(filtered, rg)
+		}
+	}
+	rgList.Items = filtered
 
 	endpoints := []*endpoint.Endpoint{}
 	for _, rg := range rgList.Items {
@@ -334,6 +366,8 @@ func (sc *routeGroupSource) setRouteGroupResourceLabel(rg *routeGroup, eps []*en
 	for _, ep := range eps {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("routegroup/%s/%s", rg.Metadata.Namespace, rg.Metadata.Name)
 	}
+	setPriorityLabel(rg.Metadata.Annotations, eps)
+	setClaimLabel(rg.Metadata.Annotations, eps)
 }
 
 func (sc *routeGroupSource) setRouteGroupDualstackLabel(rg *routeGroup, eps []*endpoint.Endpoint) {
@@ -385,30 +419,6 @@ func (sc *routeGroupSource) endpointsFromRouteGroup(rg *routeGroup) []*endpoint.
 	return endpoints
 }
 
-// filterByAnnotations filters a list of routeGroupList by a given annotation selector.
-func (sc *routeGroupSource) filterByAnnotations(rgs *routeGroupList) (*routeGroupList, error) {
-	selector, err := getLabelSelector(sc.annotationFilter)
-	if err != nil {
-		return nil, err
-	}
-
-	// empty filter returns original list
-	if selector.Empty() {
-		return rgs, nil
-	}
-
-	var filteredList []*routeGroup
-	for _, rg := range rgs.Items {
-		// include ingress if its annotations match the selector
-		if matchLabelSelector(selector, rg.Metadata.Annotations) {
-			filteredList = append(filteredList, rg)
-		}
-	}
-	rgs.Items = filteredList
-
-	return rgs, nil
-}
-
 func targetsFromRouteGroupStatus(status routeGroupStatus) endpoint.Targets {
 	var targets endpoint.Targets
 
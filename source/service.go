@@ -50,6 +50,7 @@ const (
 type serviceSource struct {
 	client           kubernetes.Interface
 	namespace        string
+	namespaceFilter  *namespaceFilter
 	annotationFilter string
 
 	// process Services with legacy annotations
@@ -68,7 +69,7 @@ type serviceSource struct {
 }
 
 // NewServiceSource creates a new serviceSource with the given config.
-func NewServiceSource(kubeClient kubernetes.Interface, namespace, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, compatibility string, publishInternal bool, publishHostIP bool, alwaysPublishNotReadyAddresses bool, serviceTypeFilter []string, ignoreHostnameAnnotation bool) (Source, error) {
+func NewServiceSource(kubeClient kubernetes.Interface, namespaces []string, namespaceSelector, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, compatibility string, publishInternal bool, publishHostIP bool, alwaysPublishNotReadyAddresses bool, serviceTypeFilter []string, ignoreHostnameAnnotation bool, labelFilter string) (Source, error) {
 	var (
 		tmpl *template.Template
 		err  error
@@ -82,10 +83,23 @@ func NewServiceSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 		}
 	}
 
+	labelFilterOptions, err := labelFilterListOptions(labelFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceFilter, err := newNamespaceFilter(kubeClient, namespaces, namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	namespace := namespaceFilter.informerNamespace()
+
 	// Use shared informers to listen for add/update/delete of services/pods/nodes in the specified namespace.
 	// Set resync period to 0, to prevent processing when nothing has changed
 	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
-	serviceInformer := informerFactory.Core().V1().Services()
+	// Only the services are filtered by their labels, the endpoints, pods and nodes they select aren't labeled alike.
+	serviceInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace), kubeinformers.WithTweakListOptions(labelFilterOptions))
+	serviceInformer := serviceInformerFactory.Core().V1().Services()
 	endpointsInformer := informerFactory.Core().V1().Endpoints()
 	podInformer := informerFactory.Core().V1().Pods()
 	nodeInformer := informerFactory.Core().V1().Nodes()
@@ -118,6 +132,7 @@ func NewServiceSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 
 	// TODO informer is not explicitly stopped since controller is not passing in its channel.
 	informerFactory.Start(wait.NeverStop)
+	serviceInformerFactory.Start(wait.NeverStop)
 
 	// wait for the local cache to be populated.
 	err = poll(time.Second, 60*time.Second, func() (bool, error) {
@@ -140,6 +155,7 @@ func NewServiceSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 	return &serviceSource{
 		client:                         kubeClient,
 		namespace:                      namespace,
+		namespaceFilter:                namespaceFilter,
 		annotationFilter:               annotationFilter,
 		compatibility:                  compatibility,
 		fqdnTemplate:                   tmpl,
@@ -162,10 +178,17 @@ func (sc *serviceSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, e
 	if err != nil {
 		return nil, err
 	}
-	services, err = sc.filterByAnnotations(services)
+	matchesAnnotations, err := annotationFilterFunc(sc.annotationFilter)
 	if err != nil {
 		return nil, err
 	}
+	filtered := services[:0]
+	for _, svc := range services {
+		if sc.namespaceFilter.matches(svc.Namespace) && matchesAnnotations(svc.Annotations) {
+			filtered = append(filtered, svc)
+		}
+	}
+	services = filtered
 
 	// filter on service types if at least one has been provided
 	if len(sc.serviceTypeFilter) > 0 {
@@ -310,11 +333,7 @@ func (sc *serviceSource) extractHeadlessEndpoints(svc *v1.Service, hostname stri
 			targets = append(targets, target)
 		}
 
-		if ttl.IsConfigured() {
-			endpoints = append(endpoints, endpoint.NewEndpointWithTTL(headlessDomain, endpoint.RecordTypeA, ttl, targets...))
-		} else {
-			endpoints = append(endpoints, endpoint.NewEndpoint(headlessDomain, endpoint.RecordTypeA, targets...))
-		}
+		endpoints = append(endpoints, endpointsForHostname(headlessDomain, targets, ttl, nil, "")...)
 	}
 
 	return endpoints
@@ -353,37 +372,6 @@ func (sc *serviceSource) endpoints(svc *v1.Service) []*endpoint.Endpoint {
 	return endpoints
 }
 
-// filterByAnnotations filters a list of services by a given annotation selector.
-func (sc *serviceSource) filterByAnnotations(services []*v1.Service) ([]*v1.Service, error) {
-	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
-	if err != nil {
-		return nil, err
-	}
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
-	if err != nil {
-		return nil, err
-	}
-
-	// empty filter returns original list
-	if selector.Empty() {
-		return services, nil
-	}
-
-	filteredList := []*v1.Service{}
-
-	for _, service := range services {
-		// convert the service's annotations to an equivalent label selector
-		annotations := labels.Set(service.Annotations)
-
-		// include service if its annotations match the selector
-		if selector.Matches(annotations) {
-			filteredList = append(filteredList, service)
-		}
-	}
-
-	return filteredList, nil
-}
-
 // filterByServiceType filters services according their types
 func (sc *serviceSource) filterByServiceType(services []*v1.Service) []*v1.Service {
 	filteredList := []*v1.Service{}
@@ -401,6 +389,8 @@ func (sc *serviceSource) setResourceLabel(service *v1.Service, endpoints []*endp
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("service/%s/%s", service.Namespace, service.Name)
 	}
+	setPriorityLabel(service.Annotations, endpoints)
+	setClaimLabel(service.Annotations, endpoints)
 }
 
 func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, providerSpecific endpoint.ProviderSpecific, setIdentifier string) []*endpoint.Endpoint {
@@ -418,6 +408,14 @@ func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, pro
 		DNSName:    hostname,
 	}
 
+	epAAAA := &endpoint.Endpoint{
+		RecordTTL:  ttl,
+		RecordType: endpoint.RecordTypeAAAA,
+		Labels:     endpoint.NewLabels(),
+		Targets:    make(endpoint.Targets, 0, defaultTargetsCapacity),
+		DNSName:    hostname,
+	}
+
 	epCNAME := &endpoint.Endpoint{
 		RecordTTL:  ttl,
 		RecordType: endpoint.RecordTypeCNAME,
@@ -452,10 +450,12 @@ func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, pro
 	}
 
 	for _, t := range targets {
-		if suitableType(t) == endpoint.RecordTypeA {
+		switch suitableType(t) {
+		case endpoint.RecordTypeA:
 			epA.Targets = append(epA.Targets, t)
-		}
-		if suitableType(t) == endpoint.RecordTypeCNAME {
+		case endpoint.RecordTypeAAAA:
+			epAAAA.Targets = append(epAAAA.Targets, t)
+		case endpoint.RecordTypeCNAME:
 			epCNAME.Targets = append(epCNAME.Targets, t)
 		}
 	}
@@ -463,6 +463,9 @@ func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, pro
 	if len(epA.Targets) > 0 {
 		endpoints = append(endpoints, epA)
 	}
+	if len(epAAAA.Targets) > 0 {
+		endpoints = append(endpoints, epAAAA)
+	}
 	if len(epCNAME.Targets) > 0 {
 		endpoints = append(endpoints, epCNAME)
 	}
@@ -598,6 +601,8 @@ func (sc *serviceSource) extractNodePortEndpoints(svc *v1.Service, nodeTargets e
 func (sc *serviceSource) AddEventHandler(ctx context.Context, handler func()) {
 	log.Debug("Adding event handler for service")
 
+	sc.namespaceFilter.AddEventHandler(handler)
+
 	// Right now there is no way to remove event handler from informer, see:
 	// https://github.com/kubernetes/kubernetes/issues/79610
 	sc.serviceInformer.Informer().AddEventHandler(
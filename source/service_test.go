@@ -44,6 +44,7 @@ func (suite *ServiceSuite) SetupTest() {
 
 	suite.sc, err = NewServiceSource(
 		fakeClient,
+		nil,
 		"",
 		"",
 		"{{.Name}}",
@@ -54,6 +55,7 @@ func (suite *ServiceSuite) SetupTest() {
 		false,
 		[]string{},
 		false,
+		"",
 	)
 	suite.fooWithTargets = &v1.Service{
 		Spec: v1.ServiceSpec{
@@ -137,6 +139,7 @@ func testServiceSourceNewServiceSource(t *testing.T) {
 		t.Run(ti.title, func(t *testing.T) {
 			_, err := NewServiceSource(
 				fake.NewSimpleClientset(),
+				nil,
 				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
@@ -147,6 +150,7 @@ func testServiceSourceNewServiceSource(t *testing.T) {
 				false,
 				ti.serviceTypesFilter,
 				false,
+				"",
 			)
 
 			if ti.expectError {
@@ -240,6 +244,30 @@ func testServiceSourceEndpoints(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"annotated dual-stack services return an A and an AAAA endpoint",
+			"",
+			"",
+			"testing",
+			"foo",
+			v1.ServiceTypeLoadBalancer,
+			"",
+			"",
+			false,
+			false,
+			map[string]string{},
+			map[string]string{
+				hostnameAnnotationKey: "foo.example.org.",
+			},
+			"",
+			[]string{"1.2.3.4", "2001:db8::1"},
+			[]string{},
+			[]*endpoint.Endpoint{
+				{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+			},
+			false,
+		},
 		{
 			"hostname annotation on services is ignored",
 			"",
@@ -1102,7 +1130,8 @@ func testServiceSourceEndpoints(t *testing.T) {
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
 				kubernetes,
-				tc.targetNamespace,
+				[]string{tc.targetNamespace},
+				"",
 				tc.annotationFilter,
 				tc.fqdnTemplate,
 				tc.combineFQDNAndAnnotation,
@@ -1112,6 +1141,7 @@ func testServiceSourceEndpoints(t *testing.T) {
 				false,
 				tc.serviceTypesFilter,
 				tc.ignoreHostnameAnnotation,
+				"",
 			)
 			require.NoError(t, err)
 
@@ -1273,7 +1303,8 @@ func TestClusterIpServices(t *testing.T) {
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
 				kubernetes,
-				tc.targetNamespace,
+				[]string{tc.targetNamespace},
+				"",
 				tc.annotationFilter,
 				tc.fqdnTemplate,
 				false,
@@ -1283,6 +1314,7 @@ func TestClusterIpServices(t *testing.T) {
 				false,
 				[]string{},
 				tc.ignoreHostnameAnnotation,
+				"",
 			)
 			require.NoError(t, err)
 
@@ -1605,7 +1637,8 @@ func TestNodePortServices(t *testing.T) {
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
 				kubernetes,
-				tc.targetNamespace,
+				[]string{tc.targetNamespace},
+				"",
 				tc.annotationFilter,
 				tc.fqdnTemplate,
 				false,
@@ -1615,6 +1648,7 @@ func TestNodePortServices(t *testing.T) {
 				false,
 				[]string{},
 				tc.ignoreHostnameAnnotation,
+				"",
 			)
 			require.NoError(t, err)
 
@@ -1829,6 +1863,35 @@ func TestHeadlessServices(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"annotated Headless services return A and AAAA endpoints for dual-stack pods",
+			"",
+			"testing",
+			"foo",
+			v1.ServiceTypeClusterIP,
+			"",
+			"",
+			false,
+			map[string]string{"component": "foo"},
+			map[string]string{
+				hostnameAnnotationKey: "service.example.org",
+			},
+			v1.ClusterIPNone,
+			[]string{"1.1.1.1", "2001:db8::1"},
+			map[string]string{
+				"component": "foo",
+			},
+			[]string{},
+			[]string{"foo-0", "foo-1"},
+			[]string{"", ""},
+			[]bool{true, true},
+			false,
+			[]*endpoint.Endpoint{
+				{DNSName: "service.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.1.1.1"}},
+				{DNSName: "service.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+			},
+			false,
+		},
 		{
 			"annotated Headless services return only a unique set of targets",
 			"",
@@ -1934,7 +1997,8 @@ func TestHeadlessServices(t *testing.T) {
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
 				kubernetes,
-				tc.targetNamespace,
+				[]string{tc.targetNamespace},
+				"",
 				"",
 				tc.fqdnTemplate,
 				false,
@@ -1944,6 +2008,7 @@ func TestHeadlessServices(t *testing.T) {
 				false,
 				[]string{},
 				tc.ignoreHostnameAnnotation,
+				"",
 			)
 			require.NoError(t, err)
 
@@ -2236,7 +2301,8 @@ func TestHeadlessServicesHostIP(t *testing.T) {
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
 				kubernetes,
-				tc.targetNamespace,
+				[]string{tc.targetNamespace},
+				"",
 				"",
 				tc.fqdnTemplate,
 				false,
@@ -2246,6 +2312,7 @@ func TestHeadlessServicesHostIP(t *testing.T) {
 				false,
 				[]string{},
 				tc.ignoreHostnameAnnotation,
+				"",
 			)
 			require.NoError(t, err)
 
@@ -2341,7 +2408,8 @@ func TestExternalServices(t *testing.T) {
 			// Create our object under test and get the endpoints.
 			client, _ := NewServiceSource(
 				kubernetes,
-				tc.targetNamespace,
+				[]string{tc.targetNamespace},
+				"",
 				"",
 				tc.fqdnTemplate,
 				false,
@@ -2351,6 +2419,7 @@ func TestExternalServices(t *testing.T) {
 				false,
 				[]string{},
 				tc.ignoreHostnameAnnotation,
+				"",
 			)
 			require.NoError(t, err)
 
@@ -2367,6 +2436,86 @@ func TestExternalServices(t *testing.T) {
 	}
 }
 
+// TestServiceSourceNamespaces tests that only the services of the selected namespaces generate endpoints.
+func TestServiceSourceNamespaces(t *testing.T) {
+	for _, tc := range []struct {
+		title             string
+		namespaces        []string
+		namespaceSelector string
+		expected          []*endpoint.Endpoint
+	}{
+		{
+			title:      "several namespaces",
+			namespaces: []string{"team-a", "team-c"},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "c.example.org", Targets: endpoint.Targets{"1.2.3.6"}},
+			},
+		},
+		{
+			title:             "namespace selector",
+			namespaceSelector: "team=dns",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "b.example.org", Targets: endpoint.Targets{"1.2.3.5"}},
+			},
+		},
+		{
+			title:             "namespaces and namespace selector",
+			namespaces:        []string{"team-b", "team-c"},
+			namespaceSelector: "team=dns",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "b.example.org", Targets: endpoint.Targets{"1.2.3.5"}},
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubernetes := fake.NewSimpleClientset()
+
+			for _, ns := range []struct {
+				name, team, hostname, ip string
+			}{
+				{"team-a", "dns", "a.example.org", "1.2.3.4"},
+				{"team-b", "dns", "b.example.org", "1.2.3.5"},
+				{"team-c", "web", "c.example.org", "1.2.3.6"},
+			} {
+				_, err := kubernetes.CoreV1().Namespaces().Create(context.Background(), &v1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   ns.name,
+						Labels: map[string]string{"team": ns.team},
+					},
+				}, metav1.CreateOptions{})
+				require.NoError(t, err)
+
+				_, err = kubernetes.CoreV1().Services(ns.name).Create(context.Background(), &v1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace:   ns.name,
+						Name:        "foo",
+						Annotations: map[string]string{hostnameAnnotationKey: ns.hostname},
+					},
+					Spec: v1.ServiceSpec{
+						Type: v1.ServiceTypeLoadBalancer,
+					},
+					Status: v1.ServiceStatus{
+						LoadBalancer: v1.LoadBalancerStatus{
+							Ingress: []v1.LoadBalancerIngress{{IP: ns.ip}},
+						},
+					},
+				}, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			client, err := NewServiceSource(kubernetes, tc.namespaces, tc.namespaceSelector, "", "", false, "", false, false, false, []string{}, false, "")
+			require.NoError(t, err)
+
+			endpoints, err := client.Endpoints(context.Background())
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
+
 func BenchmarkServiceEndpoints(b *testing.B) {
 	kubernetes := fake.NewSimpleClientset()
 
@@ -2391,7 +2540,7 @@ func BenchmarkServiceEndpoints(b *testing.B) {
 	_, err := kubernetes.CoreV1().Services(service.Namespace).Create(context.Background(), service, metav1.CreateOptions{})
 	require.NoError(b, err)
 
-	client, err := NewServiceSource(kubernetes, v1.NamespaceAll, "", "", false, "", false, false, false, []string{}, false)
+	client, err := NewServiceSource(kubernetes, nil, "", "", "", false, "", false, false, false, []string{}, false, "")
 	require.NoError(b, err)
 
 	for i := 0; i < b.N; i++ {
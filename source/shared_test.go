@@ -32,10 +32,10 @@ func validateEndpoints(t *testing.T, endpoints, expected []*endpoint.Endpoint) {
 	}
 	// Make sure endpoints are sorted - validateEndpoint() depends on it.
 	sort.SliceStable(endpoints, func(i, j int) bool {
-		return strings.Compare(endpoints[i].DNSName, endpoints[j].DNSName) < 0
+		return lessEndpoint(endpoints[i], endpoints[j])
 	})
 	sort.SliceStable(expected, func(i, j int) bool {
-		return strings.Compare(expected[i].DNSName, expected[j].DNSName) < 0
+		return lessEndpoint(expected[i], expected[j])
 	})
 
 	for i := range endpoints {
@@ -43,6 +43,14 @@ func validateEndpoints(t *testing.T, endpoints, expected []*endpoint.Endpoint) {
 	}
 }
 
+// lessEndpoint orders endpoints by DNS name and, where both specify one, by record type.
+func lessEndpoint(x, y *endpoint.Endpoint) bool {
+	if c := strings.Compare(x.DNSName, y.DNSName); c != 0 || x.RecordType == "" || y.RecordType == "" {
+		return c < 0
+	}
+	return x.RecordType < y.RecordType
+}
+
 func validateEndpoint(t *testing.T, endpoint, expected *endpoint.Endpoint) {
 	if endpoint.DNSName != expected.DNSName {
 		t.Errorf("expected %s, got %s", expected.DNSName, endpoint.DNSName)
@@ -21,10 +21,12 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -43,6 +45,10 @@ const (
 	ttlAnnotationKey = "external-dns.alpha.kubernetes.io/ttl"
 	// The annotation used for switching to the alias record types e. g. AWS Alias records instead of a normal CNAME
 	aliasAnnotationKey = "external-dns.alpha.kubernetes.io/alias"
+	// The annotation used for ranking resources which request the same DNS name, see the priority conflict resolver
+	priorityAnnotationKey = "external-dns.alpha.kubernetes.io/priority"
+	// The annotation used for claiming the ownership of pre-existing records which aren't owned by anyone
+	claimAnnotationKey = "external-dns.alpha.kubernetes.io/claim"
 	// The value of the controller annotation so that we feel responsible
 	controllerAnnotationValue = "dns-controller"
 )
@@ -106,6 +112,31 @@ func getHostnamesFromAnnotations(annotations map[string]string) []string {
 	return strings.Split(strings.Replace(hostnameAnnotation, " ", "", -1), ",")
 }
 
+// setPriorityLabel copies a valid priority annotation to the labels of the given endpoints.
+func setPriorityLabel(annotations map[string]string, endpoints []*endpoint.Endpoint) {
+	priority, exists := annotations[priorityAnnotationKey]
+	if !exists {
+		return
+	}
+	if _, err := strconv.Atoi(priority); err != nil {
+		log.Warnf("\"%v\" is not a valid priority value", priority)
+		return
+	}
+	for _, ep := range endpoints {
+		ep.Labels[endpoint.PriorityLabelKey] = priority
+	}
+}
+
+// setClaimLabel marks the given endpoints as claiming the ownership of unowned records if the claim annotation is set.
+func setClaimLabel(annotations map[string]string, endpoints []*endpoint.Endpoint) {
+	if annotations[claimAnnotationKey] != "true" {
+		return
+	}
+	for _, ep := range endpoints {
+		ep.Labels[endpoint.ClaimLabelKey] = "true"
+	}
+}
+
 func getAliasFromAnnotations(annotations map[string]string) bool {
 	aliasAnnotation, exists := annotations[aliasAnnotationKey]
 	return exists && aliasAnnotation == "true"
@@ -161,12 +192,17 @@ func getTargetsFromTargetAnnotation(annotations map[string]string) endpoint.Targ
 }
 
 // suitableType returns the DNS resource record type suitable for the target.
-// In this case type A for IPs and type CNAME for everything else.
+// In this case type A for IPv4 addresses, type AAAA for IPv6 addresses and type CNAME for everything else.
 func suitableType(target string) string {
-	if net.ParseIP(target) != nil {
+	ip := net.ParseIP(target)
+	switch {
+	case ip == nil:
+		return endpoint.RecordTypeCNAME
+	case ip.To4() == nil:
+		return endpoint.RecordTypeAAAA
+	default:
 		return endpoint.RecordTypeA
 	}
-	return endpoint.RecordTypeCNAME
 }
 
 // endpointsForHostname returns the endpoint objects for each host-target combination.
@@ -174,12 +210,15 @@ func endpointsForHostname(hostname string, targets endpoint.Targets, ttl endpoin
 	var endpoints []*endpoint.Endpoint
 
 	var aTargets endpoint.Targets
+	var aaaaTargets endpoint.Targets
 	var cnameTargets endpoint.Targets
 
 	for _, t := range targets {
 		switch suitableType(t) {
 		case endpoint.RecordTypeA:
 			aTargets = append(aTargets, t)
+		case endpoint.RecordTypeAAAA:
+			aaaaTargets = append(aaaaTargets, t)
 		default:
 			cnameTargets = append(cnameTargets, t)
 		}
@@ -198,6 +237,19 @@ func endpointsForHostname(hostname string, targets endpoint.Targets, ttl endpoin
 		endpoints = append(endpoints, epA)
 	}
 
+	if len(aaaaTargets) > 0 {
+		epAAAA := &endpoint.Endpoint{
+			DNSName:          strings.TrimSuffix(hostname, "."),
+			Targets:          aaaaTargets,
+			RecordTTL:        ttl,
+			RecordType:       endpoint.RecordTypeAAAA,
+			Labels:           endpoint.NewLabels(),
+			ProviderSpecific: providerSpecific,
+			SetIdentifier:    setIdentifier,
+		}
+		endpoints = append(endpoints, epAAAA)
+	}
+
 	if len(cnameTargets) > 0 {
 		epCNAME := &endpoint.Endpoint{
 			DNSName:          strings.TrimSuffix(hostname, "."),
@@ -247,3 +299,66 @@ func poll(interval time.Duration, timeout time.Duration, condition wait.Conditio
 
 	return wait.Poll(interval, timeout, condition)
 }
+
+// annotationFilterFunc returns the function returning whether the annotations of an object match
+// the annotation filter, an empty filter matches all objects.
+func annotationFilterFunc(annotationFilter string) (func(annotations map[string]string) bool, error) {
+	if annotationFilter == "" {
+		return func(map[string]string) bool { return true }, nil
+	}
+
+	selector, err := getLabelSelector(annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	return func(annotations map[string]string) bool {
+		return matchLabelSelector(selector, annotations)
+	}, nil
+}
+
+// labelFilterListOptions returns the function restricting the list and watch requests of the informers
+// to the objects matching the label filter, so that the others aren't sent by the API server at all.
+func labelFilterListOptions(labelFilter string) (func(*metav1.ListOptions), error) {
+	if _, err := labels.Parse(labelFilter); err != nil {
+		return nil, err
+	}
+	return func(options *metav1.ListOptions) {
+		options.LabelSelector = labelFilter
+	}, nil
+}
+
+// pollForChanges calls the event handler whenever the endpoints of the source differ from the ones
+// returned by its previous call to Endpoints, until the context is done. It's used by the sources
+// which can't watch their objects, so that their changes are processed before the next full interval.
+func pollForChanges(ctx context.Context, src Source, interval time.Duration, handler func()) {
+	var last []*endpoint.Endpoint
+	polled := false
+	check := func() {
+		endpoints, err := src.Endpoints(ctx)
+		if err != nil {
+			log.Debugf("Failed to poll for changes: %v", err)
+			return
+		}
+		// the first successful poll only records the endpoints the changes are compared to
+		if polled && !reflect.DeepEqual(last, endpoints) {
+			handler()
+		}
+		last = endpoints
+		polled = true
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		check()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				check()
+			}
+		}
+	}()
+}
@@ -17,10 +17,15 @@ limitations under the License.
 package source
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
@@ -89,11 +94,213 @@ func TestGetTTLFromAnnotations(t *testing.T) {
 	}
 }
 
+func TestSetPriorityLabel(t *testing.T) {
+	for _, tc := range []struct {
+		title            string
+		annotations      map[string]string
+		expectedPriority string
+		expectedExists   bool
+	}{
+		{
+			title:          "priority annotation not present",
+			annotations:    map[string]string{"foo": "bar"},
+			expectedExists: false,
+		},
+		{
+			title:          "priority annotation value is not a number",
+			annotations:    map[string]string{priorityAnnotationKey: "high"},
+			expectedExists: false,
+		},
+		{
+			title:            "priority annotation value is set correctly",
+			annotations:      map[string]string{priorityAnnotationKey: "10"},
+			expectedPriority: "10",
+			expectedExists:   true,
+		},
+		{
+			title:            "priority annotation value is negative",
+			annotations:      map[string]string{priorityAnnotationKey: "-1"},
+			expectedPriority: "-1",
+			expectedExists:   true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			ep := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")
+			setPriorityLabel(tc.annotations, []*endpoint.Endpoint{ep})
+			priority, exists := ep.Labels[endpoint.PriorityLabelKey]
+			assert.Equal(t, tc.expectedExists, exists)
+			assert.Equal(t, tc.expectedPriority, priority)
+		})
+	}
+}
+
+func TestSetClaimLabel(t *testing.T) {
+	for _, tc := range []struct {
+		title          string
+		annotations    map[string]string
+		expectedExists bool
+	}{
+		{
+			title:          "claim annotation not present",
+			annotations:    map[string]string{"foo": "bar"},
+			expectedExists: false,
+		},
+		{
+			title:          "claim annotation is not true",
+			annotations:    map[string]string{claimAnnotationKey: "false"},
+			expectedExists: false,
+		},
+		{
+			title:          "claim annotation is true",
+			annotations:    map[string]string{claimAnnotationKey: "true"},
+			expectedExists: true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			ep := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")
+			setClaimLabel(tc.annotations, []*endpoint.Endpoint{ep})
+			_, exists := ep.Labels[endpoint.ClaimLabelKey]
+			assert.Equal(t, tc.expectedExists, exists)
+		})
+	}
+}
+
+func TestAnnotationFilterFunc(t *testing.T) {
+	for _, tc := range []struct {
+		title            string
+		annotationFilter string
+		annotations      []map[string]string
+		expected         []int
+		expectError      bool
+	}{
+		{
+			title:       "empty filter keeps all objects",
+			annotations: []map[string]string{{"foo": "bar"}, nil},
+			expected:    []int{0, 1},
+		},
+		{
+			title:            "filter keeps the matching objects in order",
+			annotationFilter: "kubernetes.io/ingress.class=nginx",
+			annotations: []map[string]string{
+				{"kubernetes.io/ingress.class": "nginx"},
+				{"kubernetes.io/ingress.class": "alb"},
+				nil,
+				{"kubernetes.io/ingress.class": "nginx", "foo": "bar"},
+			},
+			expected: []int{0, 3},
+		},
+		{
+			title:            "set based filter",
+			annotationFilter: "kubernetes.io/ingress.class in (nginx, alb)",
+			annotations: []map[string]string{
+				{"kubernetes.io/ingress.class": "traefik"},
+				{"kubernetes.io/ingress.class": "alb"},
+				{"kubernetes.io/ingress.class": "nginx"},
+			},
+			expected: []int{1, 2},
+		},
+		{
+			title:            "no matching object",
+			annotationFilter: "kubernetes.io/ingress.class=nginx",
+			annotations:      []map[string]string{{"foo": "bar"}},
+			expected:         []int{},
+		},
+		{
+			title:            "invalid filter",
+			annotationFilter: "kubernetes.io/ingress.class in nginx",
+			annotations:      []map[string]string{{"foo": "bar"}},
+			expectError:      true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			matchesAnnotations, err := annotationFilterFunc(tc.annotationFilter)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			matched := []int{}
+			for i, annotations := range tc.annotations {
+				if matchesAnnotations(annotations) {
+					matched = append(matched, i)
+				}
+			}
+			assert.Equal(t, tc.expected, matched)
+		})
+	}
+}
+
+func TestLabelFilterListOptions(t *testing.T) {
+	tweak, err := labelFilterListOptions("app=external-dns,tier!=internal")
+	require.NoError(t, err)
+	options := metav1.ListOptions{}
+	tweak(&options)
+	assert.Equal(t, "app=external-dns,tier!=internal", options.LabelSelector)
+
+	tweak, err = labelFilterListOptions("")
+	require.NoError(t, err)
+	options = metav1.ListOptions{}
+	tweak(&options)
+	assert.Empty(t, options.LabelSelector)
+
+	_, err = labelFilterListOptions("app in external-dns")
+	assert.Error(t, err)
+}
+
+// pollingTestSource returns the endpoints sent to its results channel, one call to Endpoints at a time.
+type pollingTestSource struct {
+	results chan []*endpoint.Endpoint
+}
+
+func (s *pollingTestSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	select {
+	case endpoints := <-s.results:
+		if endpoints == nil {
+			return nil, errors.New("failed to list")
+		}
+		return endpoints, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *pollingTestSource) AddEventHandler(ctx context.Context, handler func()) {
+}
+
+func TestPollForChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := &pollingTestSource{results: make(chan []*endpoint.Endpoint)}
+	handled := make(chan struct{}, 10)
+	pollForChanges(ctx, src, time.Millisecond, func() { handled <- struct{}{} })
+
+	foo := []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")}
+	bar := []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "2.3.4.5")}
+
+	// the first poll doesn't trigger the handler, nor do the unchanged endpoints
+	src.results <- foo
+	src.results <- foo
+
+	// changed endpoints trigger the handler
+	src.results <- bar
+	waitForHandler(t, handled)
+
+	// a failed poll doesn't, nor do the endpoints unchanged since the last successful poll
+	src.results <- nil
+	src.results <- bar
+	// wait for the previous endpoints to be compared
+	src.results <- bar
+	assert.Empty(t, handled)
+}
+
 func TestSuitableType(t *testing.T) {
 	for _, tc := range []struct {
 		target, recordType, expected string
 	}{
 		{"8.8.8.8", "", "A"},
+		{"2001:db8::1", "", "AAAA"},
+		{"::ffff:8.8.8.8", "", "A"},
 		{"foo.example.org", "", "CNAME"},
 		{"bar.eu-central-1.elb.amazonaws.com", "", "CNAME"},
 	} {
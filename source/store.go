@@ -40,8 +40,12 @@ var ErrSourceNotFound = errors.New("source not found")
 
 // Config holds shared configuration options for all Sources.
 type Config struct {
+	// Deprecated: use Namespaces instead, Namespace is only used when Namespaces is empty.
 	Namespace                      string
+	Namespaces                     []string
+	NamespaceSelector              string
 	AnnotationFilter               string
+	LabelFilter                    string
 	FQDNTemplate                   string
 	CombineFQDNAndAnnotation       bool
 	IgnoreHostnameAnnotation       bool
@@ -55,12 +59,22 @@ type Config struct {
 	KubeConfig                     string
 	APIServerURL                   string
 	ServiceTypeFilter              []string
+	IngressClassNames              []string
 	CFAPIEndpoint                  string
 	CFUsername                     string
 	CFPassword                     string
 	ContourLoadBalancerService     string
 	SkipperRouteGroupVersion       string
 	RequestTimeout                 time.Duration
+	EventsPollInterval             time.Duration
+}
+
+// namespaces returns the namespaces to watch, including the deprecated Namespace.
+func (cfg *Config) namespaces() []string {
+	if len(cfg.Namespaces) == 0 && cfg.Namespace != "" {
+		return []string{cfg.Namespace}
+	}
+	return cfg.Namespaces
 }
 
 // ClientGenerator provides clients
@@ -172,19 +186,19 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 		if err != nil {
 			return nil, err
 		}
-		return NewNodeSource(client, cfg.AnnotationFilter, cfg.FQDNTemplate)
+		return NewNodeSource(client, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.LabelFilter)
 	case "service":
 		client, err := p.KubeClient()
 		if err != nil {
 			return nil, err
 		}
-		return NewServiceSource(client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.Compatibility, cfg.PublishInternal, cfg.PublishHostIP, cfg.AlwaysPublishNotReadyAddresses, cfg.ServiceTypeFilter, cfg.IgnoreHostnameAnnotation)
+		return NewServiceSource(client, cfg.namespaces(), cfg.NamespaceSelector, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.Compatibility, cfg.PublishInternal, cfg.PublishHostIP, cfg.AlwaysPublishNotReadyAddresses, cfg.ServiceTypeFilter, cfg.IgnoreHostnameAnnotation, cfg.LabelFilter)
 	case "ingress":
 		client, err := p.KubeClient()
 		if err != nil {
 			return nil, err
 		}
-		return NewIngressSource(client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+		return NewIngressSource(client, cfg.namespaces(), cfg.NamespaceSelector, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.IngressClassNames, cfg.LabelFilter)
 	case "istio-gateway":
 		kubernetesClient, err := p.KubeClient()
 		if err != nil {
@@ -194,7 +208,7 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 		if err != nil {
 			return nil, err
 		}
-		return NewIstioGatewaySource(kubernetesClient, istioClient, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+		return NewIstioGatewaySource(kubernetesClient, istioClient, cfg.namespaces(), cfg.NamespaceSelector, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.LabelFilter)
 	case "istio-virtualservice":
 		kubernetesClient, err := p.KubeClient()
 		if err != nil {
@@ -204,13 +218,13 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 		if err != nil {
 			return nil, err
 		}
-		return NewIstioVirtualServiceSource(kubernetesClient, istioClient, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+		return NewIstioVirtualServiceSource(kubernetesClient, istioClient, cfg.namespaces(), cfg.NamespaceSelector, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.LabelFilter)
 	case "cloudfoundry":
 		cfClient, err := p.CloudFoundryClient(cfg.CFAPIEndpoint, cfg.CFUsername, cfg.CFPassword)
 		if err != nil {
 			return nil, err
 		}
-		return NewCloudFoundrySource(cfClient)
+		return NewCloudFoundrySource(cfClient, cfg.EventsPollInterval)
 	case "contour-ingressroute":
 		kubernetesClient, err := p.KubeClient()
 		if err != nil {
@@ -220,13 +234,23 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 		if err != nil {
 			return nil, err
 		}
-		return NewContourIngressRouteSource(dynamicClient, kubernetesClient, cfg.ContourLoadBalancerService, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+		return NewContourIngressRouteSource(dynamicClient, kubernetesClient, cfg.ContourLoadBalancerService, cfg.namespaces(), cfg.NamespaceSelector, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.LabelFilter)
+	case "gateway-httproute":
+		return newGatewayRouteSource(p, cfg, GatewayHTTPRouteKind)
+	case "gateway-tlsroute":
+		return newGatewayRouteSource(p, cfg, GatewayTLSRouteKind)
+	case "gateway-tcproute":
+		return newGatewayRouteSource(p, cfg, GatewayTCPRouteKind)
 	case "openshift-route":
+		kubernetesClient, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
 		ocpClient, err := p.OpenShiftClient()
 		if err != nil {
 			return nil, err
 		}
-		return NewOcpRouteSource(ocpClient, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+		return NewOcpRouteSource(ocpClient, kubernetesClient, cfg.namespaces(), cfg.NamespaceSelector, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.LabelFilter)
 	case "fake":
 		return NewFakeSource(cfg.FQDNTemplate)
 	case "connector":
@@ -240,8 +264,12 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 		if err != nil {
 			return nil, err
 		}
-		return NewCRDSource(crdClient, cfg.Namespace, cfg.CRDSourceKind, cfg.AnnotationFilter, scheme)
+		return NewCRDSource(crdClient, client, cfg.namespaces(), cfg.NamespaceSelector, cfg.CRDSourceKind, cfg.AnnotationFilter, scheme, cfg.LabelFilter)
 	case "skipper-routegroup":
+		client, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
 		apiServerURL := cfg.APIServerURL
 		tokenPath := ""
 		token := ""
@@ -251,11 +279,24 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 			tokenPath = restConfig.BearerTokenFile
 			token = restConfig.BearerToken
 		}
-		return NewRouteGroupSource(cfg.RequestTimeout, token, tokenPath, apiServerURL, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.SkipperRouteGroupVersion, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+		return NewRouteGroupSource(client, cfg.RequestTimeout, token, tokenPath, apiServerURL, cfg.namespaces(), cfg.NamespaceSelector, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.SkipperRouteGroupVersion, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.LabelFilter, cfg.EventsPollInterval)
 	}
 	return nil, ErrSourceNotFound
 }
 
+// newGatewayRouteSource generates a Source for the Gateway API routes of the given kind.
+func newGatewayRouteSource(p ClientGenerator, cfg *Config, kind string) (Source, error) {
+	kubernetesClient, err := p.KubeClient()
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := p.DynamicKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewGatewayRouteSource(dynamicClient, kubernetesClient, kind, cfg.namespaces(), cfg.NamespaceSelector, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.LabelFilter)
+}
+
 // GetRestConfig returns the rest clients config to get automatically
 // data if you run inside a cluster or by passing flags.
 func GetRestConfig(kubeConfig, apiServerURL string) (*rest.Config, error) {
@@ -22,6 +22,7 @@ import (
 
 	cfclient "github.com/cloudfoundry-community/go-cfclient"
 	openshift "github.com/openshift/client-go/route/clientset/versioned"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	istioclient "istio.io/client-go/pkg/clientset/versioned"
@@ -135,6 +136,9 @@ func (suite *ByNamesTestSuite) TestKubeClientFails() {
 
 	_, err = ByNames(mockClientGenerator, []string{"contour-ingressroute"}, minimalConfig)
 	suite.Error(err, "should return an error if kubernetes client cannot be created")
+
+	_, err = ByNames(mockClientGenerator, []string{"gateway-httproute"}, minimalConfig)
+	suite.Error(err, "should return an error if kubernetes client cannot be created")
 }
 
 func (suite *ByNamesTestSuite) TestIstioClientFails() {
@@ -148,12 +152,46 @@ func (suite *ByNamesTestSuite) TestIstioClientFails() {
 
 	_, err = ByNames(mockClientGenerator, []string{"contour-ingressroute"}, minimalConfig)
 	suite.Error(err, "should return an error if contour client cannot be created")
+
+	_, err = ByNames(mockClientGenerator, []string{"gateway-tcproute"}, minimalConfig)
+	suite.Error(err, "should return an error if dynamic client cannot be created")
 }
 
 func TestByNames(t *testing.T) {
 	suite.Run(t, new(ByNamesTestSuite))
 }
 
+func TestConfigNamespaces(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		cfg      Config
+		expected []string
+	}{
+		{
+			title: "all namespaces",
+		},
+		{
+			title:    "namespaces",
+			cfg:      Config{Namespaces: []string{"foo", "bar"}},
+			expected: []string{"foo", "bar"},
+		},
+		{
+			title:    "deprecated namespace",
+			cfg:      Config{Namespace: "foo"},
+			expected: []string{"foo"},
+		},
+		{
+			title:    "namespaces take precedence over the deprecated namespace",
+			cfg:      Config{Namespace: "foo", Namespaces: []string{"bar"}},
+			expected: []string{"bar"},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.cfg.namespaces())
+		})
+	}
+}
+
 var minimalConfig = &Config{
 	ContourLoadBalancerService:  "heptio-contour/contour",
 }
@@ -50,10 +50,12 @@ type virtualServiceSource struct {
 	kubeClient               kubernetes.Interface
 	istioClient              istioclient.Interface
 	namespace                string
+	namespaceFilter          *namespaceFilter
 	annotationFilter         string
 	fqdnTemplate             *template.Template
 	combineFQDNAnnotation    bool
 	ignoreHostnameAnnotation bool
+	labelFilter              string
 	serviceInformer          coreinformers.ServiceInformer
 }
 
@@ -61,11 +63,13 @@ type virtualServiceSource struct {
 func NewIstioVirtualServiceSource(
 	kubeClient kubernetes.Interface,
 	istioClient istioclient.Interface,
-	namespace string,
+	namespaces []string,
+	namespaceSelector string,
 	annotationFilter string,
 	fqdnTemplate string,
 	combineFQDNAnnotation bool,
 	ignoreHostnameAnnotation bool,
+	labelFilter string,
 ) (Source, error) {
 	var (
 		tmpl *template.Template
@@ -81,6 +85,16 @@ func NewIstioVirtualServiceSource(
 		}
 	}
 
+	if _, err := labels.Parse(labelFilter); err != nil {
+		return nil, err
+	}
+
+	namespaceFilter, err := newNamespaceFilter(kubeClient, namespaces, namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	namespace := namespaceFilter.informerNamespace()
+
 	// Use shared informers to listen for add/update/delete of services/pods/nodes in the specified namespace.
 	// Set resync period to 0, to prevent processing when nothing has changed
 	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
@@ -110,10 +124,12 @@ func NewIstioVirtualServiceSource(
 		kubeClient:               kubeClient,
 		istioClient:              istioClient,
 		namespace:                namespace,
+		namespaceFilter:          namespaceFilter,
 		annotationFilter:         annotationFilter,
 		fqdnTemplate:             tmpl,
 		combineFQDNAnnotation:    combineFQDNAnnotation,
 		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		labelFilter:              labelFilter,
 		serviceInformer:          serviceInformer,
 	}, nil
 }
@@ -121,16 +137,23 @@ func NewIstioVirtualServiceSource(
 // Endpoints returns endpoint objects for each host-target combination that should be processed.
 // Retrieves all VirtualService resources in the source's namespace(s).
 func (sc *virtualServiceSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
-	virtualServiceList, err := sc.istioClient.NetworkingV1alpha3().VirtualServices(sc.namespace).List(ctx, metav1.ListOptions{})
+	virtualServiceList, err := sc.istioClient.NetworkingV1alpha3().VirtualServices(sc.namespace).List(ctx, metav1.ListOptions{LabelSelector: sc.labelFilter})
 	if err != nil {
 		return nil, err
 	}
 
 	virtualServices := virtualServiceList.Items
-	virtualServices, err = sc.filterByAnnotations(virtualServices)
+	matchesAnnotations, err := annotationFilterFunc(sc.annotationFilter)
 	if err != nil {
 		return nil, err
 	}
+	filtered := virtualServices[:0]
+	for _, vs := range virtualServices {
+		if sc.namespaceFilter.matches(vs.Namespace) && matchesAnnotations(vs.Annotations) {
+			filtered = append(filtered, vs)
+		}
+	}
+	virtualServices = filtered
 
 	var endpoints []*endpoint.Endpoint
 
@@ -182,6 +205,7 @@ func (sc *virtualServiceSource) Endpoints(ctx context.Context) ([]*endpoint.Endp
 // TODO(tariq1890): Implement this once we have evaluated and tested VirtualServiceInformers
 // AddEventHandler adds an event handler that should be triggered if the watched Istio VirtualService changes.
 func (sc *virtualServiceSource) AddEventHandler(ctx context.Context, handler func()) {
+	sc.namespaceFilter.AddEventHandler(handler)
 }
 
 func (sc *virtualServiceSource) getGateway(ctx context.Context, gatewayStr string, virtualService networkingv1alpha3.VirtualService) *networkingv1alpha3.Gateway {
@@ -244,41 +268,12 @@ func (sc *virtualServiceSource) endpointsFromTemplate(ctx context.Context, virtu
 	return endpoints, nil
 }
 
-// filterByAnnotations filters a list of configs by a given annotation selector.
-func (sc *virtualServiceSource) filterByAnnotations(virtualservices []networkingv1alpha3.VirtualService) ([]networkingv1alpha3.VirtualService, error) {
-	labelSelector, err := metav1.ParseToLabelSelector(sc.annotationFilter)
-	if err != nil {
-		return nil, err
-	}
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
-	if err != nil {
-		return nil, err
-	}
-
-	// empty filter returns original list
-	if selector.Empty() {
-		return virtualservices, nil
-	}
-
-	var filteredList []networkingv1alpha3.VirtualService
-
-	for _, virtualservice := range virtualservices {
-		// convert the annotations to an equivalent label selector
-		annotations := labels.Set(virtualservice.Annotations)
-
-		// include if the annotations match the selector
-		if selector.Matches(annotations) {
-			filteredList = append(filteredList, virtualservice)
-		}
-	}
-
-	return filteredList, nil
-}
-
 func (sc *virtualServiceSource) setResourceLabel(virtualservice networkingv1alpha3.VirtualService, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("virtualservice/%s/%s", virtualservice.Namespace, virtualservice.Name)
 	}
+	setPriorityLabel(virtualservice.Annotations, endpoints)
+	setClaimLabel(virtualservice.Annotations, endpoints)
 }
 
 func (sc *virtualServiceSource) targetsFromVirtualService(ctx context.Context, virtualService networkingv1alpha3.VirtualService, vsHost string) ([]string, error) {
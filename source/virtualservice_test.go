@@ -73,11 +73,13 @@ func (suite *VirtualServiceSuite) SetupTest() {
 	suite.source, err = NewIstioVirtualServiceSource(
 		fakeKubernetesClient,
 		fakeIstioClient,
+		nil,
 		"",
 		"",
 		"{{.Name}}",
 		false,
 		false,
+		"",
 	)
 	suite.NoError(err, "should initialize virtualservice source")
 
@@ -159,11 +161,13 @@ func TestNewIstioVirtualServiceSource(t *testing.T) {
 			_, err := NewIstioVirtualServiceSource(
 				fake.NewSimpleClientset(),
 				NewFakeConfigStore(),
+				nil,
 				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
 				ti.combineFQDNAndAnnotation,
 				false,
+				"",
 			)
 			if ti.expectError {
 				assert.Error(t, err)
@@ -1431,11 +1435,13 @@ func testVirtualServiceEndpoints(t *testing.T) {
 			virtualServiceSource, err := NewIstioVirtualServiceSource(
 				fakeKubernetesClient,
 				fakeIstioClient,
-				ti.targetNamespace,
+				[]string{ti.targetNamespace},
+				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
 				ti.combineFQDNAndAnnotation,
 				ti.ignoreHostnameAnnotation,
+				"",
 			)
 			require.NoError(t, err)
 
@@ -1506,11 +1512,13 @@ func newTestVirtualServiceSource(loadBalancerList []fakeIngressGatewayService, g
 	src, err := NewIstioVirtualServiceSource(
 		fakeKubernetesClient,
 		fakeIstioClient,
+		nil,
 		"",
 		"",
 		"{{.Name}}",
 		false,
 		false,
+		"",
 	)
 	if err != nil {
 		return nil, err